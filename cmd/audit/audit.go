@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"eke/internal/kubectlcmd/audit"
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/complete"
+	"eke/pkg/config"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+type CmdOpts config.CLIOptions
+
+// NewAuditCmd creates a new `eke audit` cobra command
+func NewAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the log of the kubectl commands run through eke",
+		Long: `eke kubectl records every command it runs in ~/.eke/audit, along with the user,
+the context, the server, the namespace and the version of kubectl. The values of
+secret flags are redacted. The log is configured by the ekeKubectlConfig.audit
+section of eke.cmd.yaml.`,
+	}
+	cmd.AddCommand(newShowCmd())
+	return cmd
+}
+
+func newShowCmd() *cobra.Command {
+	var since, context string
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the kubectl commands recorded in the audit log",
+		Example: `
+  Show the commands of the last day run against the prod contexts:
+  $ eke audit show --since 1d --context 'prod*'
+
+  Export the commands run since a date:
+  $ eke audit show --since 2022-03-01 -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			auditConfig := c.CmdConfig.EkeKubectlConfig.Audit
+
+			filter := audit.Filter{Context: context}
+			if since != "" {
+				t, err := audit.ParseSince(since, time.Now())
+				if err != nil {
+					return err
+				}
+				filter.Since = t
+			}
+
+			l := audit.NewLog(common.AuditDir(), auditConfig.MaxSize, auditConfig.MaxFiles)
+			records, err := l.Read(filter)
+			if err != nil {
+				return err
+			}
+
+			if records == nil {
+				records = []audit.Record{}
+			}
+			return printer.Print(cmd.OutOrStdout(), recordList(records))
+		},
+	}
+	cmd.Flags().StringVar(&since, "since", "", "only show the commands run after this time, a duration like 12h or 1d, a date or an RFC3339 timestamp")
+	cmd.Flags().StringVar(&context, "context", "", "only show the commands run against the contexts matching this glob pattern")
+	cmd.RegisterFlagCompletionFunc("context", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return complete.Contexts(toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+// recordList is printed as a table of the records
+type recordList []audit.Record
+
+func (l recordList) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, r := range l {
+		rows = append(rows, table.Row{
+			r.Time.Local().Format("2006-01-02 15:04:05"),
+			r.User,
+			r.Context,
+			r.Namespace,
+			r.KubectlVersion,
+			strings.Join(r.Args, " "),
+		})
+	}
+	return table.Row{"Time", "User", "Context", "Namespace", "Kubectl", "Command"}, rows
+}
@@ -16,11 +16,14 @@ limitations under the License.
 package ckc
 
 import (
-	"log"
+	"eke/internal/pkg/logging"
 
 	"github.com/spf13/cobra"
 )
 
+// logger writes the messages of the ews subsystem
+var logger = logging.For(logging.EWS)
+
 func NewCkcCmd() *cobra.Command {
 
 	// ckcCmd represents the ckc command
@@ -32,7 +35,7 @@ func NewCkcCmd() *cobra.Command {
 
 			if len(args) == 0 {
 				if err := cmd.Help(); err != nil {
-					log.Println(err)
+					logger.Warnln(err)
 				}
 			}
 		},
@@ -40,6 +43,7 @@ func NewCkcCmd() *cobra.Command {
 
 	ckcCmd.AddCommand(ckcGetCmd())
 	ckcCmd.AddCommand(ckcRenewCmd())
+	ckcCmd.AddCommand(ckcInspectCmd())
 	return ckcCmd
 
 }
@@ -17,6 +17,7 @@ package ckc
 
 import (
 	util "eke/internal/util/utilityFunctions"
+	"eke/pkg/config"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -30,8 +31,12 @@ func ckcGetCmd() *cobra.Command {
 		Long: `If user has client key/certificate cached in local already, it will just display that stored in local.
 	otherwise, it will fetch it from remote and user's signum and password will be asked.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			eke_cache := util.Get_eke_path()
-			userCert, userKey := util.GetCertAndKey(eke_cache)
+			profile, ews, err := util.ProfileEws(config.GetCmdOpts().CmdConfig, config.ProfileRequested())
+			if err != nil {
+				logger.Fatalln(err)
+			}
+			eke_cache := util.Get_profile_path(profile)
+			userCert, userKey := util.GetCertAndKey(ews, eke_cache)
 			fmt.Println(util.CreateOutput(userCert, userKey))
 		},
 	}
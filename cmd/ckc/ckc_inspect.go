@@ -0,0 +1,87 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ckc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"eke/internal/kubectlcmd/common"
+	util "eke/internal/util/utilityFunctions"
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// certResult is the cached client certificate of a profile
+type certResult struct {
+	Profile string `json:"profile"`
+	util.CertInfo
+}
+
+func ckcInspectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show the cached EWS client certificate",
+		Long: `Show the user, the groups and the validity of the client certificate cached
+for the selected profile, without contacting the EWS.`,
+		Example: `
+  Print the expiration date of the certificate:
+  $ eke ckc inspect -o jsonpath='{.notAfter}'`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			profile, _, err := util.ProfileEws(config.GetCmdOpts().CmdConfig, config.ProfileRequested())
+			if err != nil {
+				return err
+			}
+
+			path := filepath.Join(common.ProfileDir(profile), util.ClientCertFile)
+			info, err := util.InspectCert(path, time.Now())
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no client certificate cached in %s, see eke ckc get", filepath.Dir(path))
+			}
+			if err != nil {
+				return err
+			}
+			return printer.Print(cmd.OutOrStdout(), certResult{Profile: profile, CertInfo: info})
+		},
+	}
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+func (r certResult) WriteText(w io.Writer) error {
+	profile := r.Profile
+	if profile == "" {
+		profile = "none"
+	}
+	status := "valid"
+	if r.Expired {
+		status = "expired"
+	}
+	_, err := fmt.Fprintf(w, "Profile:     %s\nCertificate: %s\nUser:        %s\nGroups:      %v\nIssuer:      %s\nNot before:  %s\nNot after:   %s (%s)\n",
+		profile, r.Path, r.User, r.Groups, r.Issuer,
+		r.NotBefore.Local().Format(time.RFC3339), r.NotAfter.Local().Format(time.RFC3339), status)
+	return err
+}
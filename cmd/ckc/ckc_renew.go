@@ -17,7 +17,7 @@ package ckc
 
 import (
 	util "eke/internal/util/utilityFunctions"
-	"log"
+	"eke/pkg/config"
 
 	"github.com/spf13/cobra"
 )
@@ -34,26 +34,34 @@ func ckcRenewCmd() *cobra.Command {
 		Long:  `Renew EWS client key and certificate`,
 		Run: func(cmd *cobra.Command, args []string) {
 
-			// Get signum and password from the user if not already set by corresponding flags
-			var err error
+			profile, ews, err := util.ProfileEws(config.GetCmdOpts().CmdConfig, config.ProfileRequested())
+			if err != nil {
+				logger.Fatalln(err)
+			}
+
+			// Get signum and password from the user if not already set by corresponding flags,
+			// or by the profile
 			//signum, _ := cmd.Flags().GetString("userid")
+			if signum == "" {
+				signum = ews.User
+			}
 			if signum == "" {
 				signum, err = util.GetUserSignum()
 				if err != nil {
-					log.Fatalln("error occured while prompting for credentials:", err)
+					logger.Fatalln("error occured while prompting for credentials:", err)
 				}
 			}
 			//pass, _ := cmd.Flags().GetString("password")
 			if pass == "" {
 				pass, err = util.GetUserPassword()
 				if err != nil {
-					log.Fatalln("error occured while prompting for credentials:", err)
+					logger.Fatalln("error occured while prompting for credentials:", err)
 				}
 			}
 
-			eke_cache := util.Get_eke_path()
+			eke_cache := util.Get_profile_path(profile)
 
-			util.RequestCertAndKeyFromEWS(eke_cache, signum, pass, true)
+			util.RequestCertAndKeyFromEWS(ews, eke_cache, signum, pass, true)
 		},
 	}
 
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package clusters
+
+import (
+	"path/filepath"
+
+	"eke/internal/kubectlcmd/cache"
+	"eke/internal/kubectlcmd/common"
+	util "eke/internal/util/utilityFunctions"
+	"eke/pkg/config"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// clusterList is printed as a table of the clusters
+type clusterList []cache.NamedCluster
+
+// NewClustersCmd creates a new `eke clusters` cobra command
+func NewClustersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clusters",
+		Short: "List the EWS clusters looked up by eke kubeconfig init",
+		Long: `List the EWS clusters of the selected profile looked up by eke kubeconfig init,
+along with their API server and the last time they were looked up.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			profile, _, err := util.ProfileEws(config.GetCmdOpts().CmdConfig, config.ProfileRequested())
+			if err != nil {
+				return err
+			}
+
+			clusters, err := cache.NewClusters(filepath.Join(common.ProfileCacheDir(profile), cache.ClustersFile)).List()
+			if err != nil {
+				return err
+			}
+			return printer.Print(cmd.OutOrStdout(), clusterList(clusters))
+		},
+	}
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+func (l clusterList) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, c := range l {
+		rows = append(rows, table.Row{c.Name, c.Server, c.LastSeen.Local().Format("2006-01-02 15:04")})
+	}
+	return table.Row{"Name", "Server", "Last seen"}, rows
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"eke/internal/pkg/output"
+	cliconfig "eke/pkg/config"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// NewConfigCmd creates a new `eke config` cobra command
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and edit the configuration of the eke commands",
+		Long: `The configuration of the eke commands is merged, from the lowest to the highest
+precedence, from:
+  1. the built in defaults
+  2. the eke.cmd.yaml files of /usr/etc, /etc, ~/.eke and of the --cmd-config
+     directory, in this order
+  3. the .eke.cmd.yaml files of the current directory and of its parents, the
+     nearest last, so that a repository can pin kubectl versions or add
+     guardrails. They can only set ekeKubectlConfig.pins, policy and
+     guardrails, their guardrails are added after the ones already set
+  4. the EKE_ environment variables, e.g. EKE_KUBECTL_TIMEOUT overrides
+     ekeKubectlConfig.timeout, see eke config env
+Mappings are merged, other values are replaced.`,
+	}
+	cmd.AddCommand(newViewCmd())
+	cmd.AddCommand(newSetCmd())
+	cmd.AddCommand(newUnsetCmd())
+	cmd.AddCommand(newValidateCmd())
+	cmd.AddCommand(newEnvCmd())
+	return cmd
+}
+
+func newViewCmd() *cobra.Command {
+	var sources bool
+
+	cmd := &cobra.Command{
+		Use:   "view",
+		Short: "Show the merged configuration",
+		Example: `
+  Show where each value comes from, followed by the sources in order of precedence:
+  $ eke config view --sources`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := cliconfig.Printer()
+			if err != nil {
+				return err
+			}
+			layers, err := cliconfig.CmdConfigLoader().Layers()
+			if err != nil {
+				return err
+			}
+			merged, settings := cmdconfig.Merge(layers)
+
+			if !sources {
+				if printer.Format == output.Table {
+					enc := yaml.NewEncoder(cmd.OutOrStdout())
+					enc.SetIndent(2)
+					if err := enc.Encode(merged); err != nil {
+						return err
+					}
+					return enc.Close()
+				}
+				return printer.Print(cmd.OutOrStdout(), merged)
+			}
+
+			result := sourcesResult{Settings: settings, Sources: []string{}}
+			for _, l := range layers {
+				result.Sources = append(result.Sources, l.Source)
+			}
+			return printer.Print(cmd.OutOrStdout(), result)
+		},
+	}
+	cmd.Flags().BoolVar(&sources, "sources", false, "show the file each value comes from")
+	cliconfig.AddOutputFlag(cmd)
+	return cmd
+}
+
+// sourcesResult holds the merged settings along with the layers they come
+// from, from the lowest to the highest precedence
+type sourcesResult struct {
+	Settings []cmdconfig.Setting `json:"settings"`
+	Sources  []string            `json:"sources"`
+}
+
+func (r sourcesResult) WriteText(w io.Writer) error {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Key", "Value", "Source"})
+	for _, s := range r.Settings {
+		t.AppendRow([]interface{}{s.Key, formatValue(s.Value), s.Source})
+	}
+	t.Render()
+
+	fmt.Fprintln(w, "\nSources, from the lowest to the highest precedence:")
+	for i, source := range r.Sources {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, source)
+	}
+	return nil
+}
+
+// formatValue prints scalars as is and the other values as JSON
+func formatValue(value interface{}) string {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(value); err == nil {
+			return strings.TrimSpace(buf.String())
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func newSetCmd() *cobra.Command {
+	var scope string
+
+	cmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a value in the eke.cmd.yaml file of a scope",
+		Long: `Set a value in the eke.cmd.yaml file of the user, ~/.eke, or of the system, /etc.
+Keys are dotted paths, e.g. ekeKubectlConfig.timeout, values are parsed as YAML.`,
+		Example: `
+  Give up on unreachable API servers after 3 seconds:
+  $ eke config set ekeKubectlConfig.timeout 3
+
+  Share an alias with every user of the host:
+  $ eke config set aliases.podsof "get pods -l app=\$1 -o wide" --scope system
+
+  Set a list:
+  $ eke config set ekeKubectlConfig.searchPaths '[/opt/kubectl/bin]'`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cmdconfig.ScopeDir(scope)
+			if err != nil {
+				return err
+			}
+			return cmdconfig.SetValue(dir, args[0], args[1])
+		},
+	}
+	cmd.Flags().StringVar(&scope, "scope", cmdconfig.ScopeUser, "file to edit, user or system")
+	return cmd
+}
+
+func newUnsetCmd() *cobra.Command {
+	var scope string
+
+	cmd := &cobra.Command{
+		Use:          "unset <key>",
+		Short:        "Remove a value from the eke.cmd.yaml file of a scope",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cmdconfig.ScopeDir(scope)
+			if err != nil {
+				return err
+			}
+			return cmdconfig.UnsetValue(dir, args[0])
+		},
+	}
+	cmd.Flags().StringVar(&scope, "scope", cmdconfig.ScopeUser, "file to edit, user or system")
+	return cmd
+}
+
+func newValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "validate",
+		Short:        "Check the configuration files and the EKE_ environment variables against the configuration schema",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loader := cliconfig.CmdConfigLoader()
+			files, err := loader.Files()
+			if err != nil {
+				return err
+			}
+
+			invalid := 0
+			for _, path := range files {
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+
+				validate := cmdconfig.Validate
+				if cmdconfig.IsProjectFile(path) {
+					validate = cmdconfig.ValidateProject
+				}
+				errs, err := validate(data)
+				if err != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %v\n", path, err)
+					invalid++
+					continue
+				}
+				for _, e := range errs {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %v\n", path, e)
+				}
+				if len(errs) > 0 {
+					invalid++
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: valid\n", path)
+				}
+			}
+
+			if _, err := cmdconfig.EnvOverrides(loader.Environ); err != nil {
+				fmt.Fprintln(cmd.OutOrStdout(), err)
+				invalid++
+			}
+
+			if invalid > 0 {
+				return errors.New("the configuration is invalid")
+			}
+			return nil
+		},
+	}
+}
+
+func newEnvCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "List the EKE_ environment variables overriding the configuration",
+		Long: `List the EKE_ environment variables overriding the configuration, along with
+the key they override and their current value. Values are parsed as YAML, except
+for the ones of string keys, e.g. EKE_KUBECTL_SEARCH_PATHS='[/opt/bin, /usr/local/bin]'.
+Empty variables are ignored.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := cliconfig.Printer()
+			if err != nil {
+				return err
+			}
+			keys := cmdconfig.EnvKeys()
+			names := make([]string, 0, len(keys))
+			for name := range keys {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			variables := envVariables{}
+			for _, name := range names {
+				variables = append(variables, envVariable{Name: name, Key: keys[name], Value: os.Getenv(name)})
+			}
+			return printer.Print(cmd.OutOrStdout(), variables)
+		},
+	}
+	cliconfig.AddOutputFlag(cmd)
+	return cmd
+}
+
+// envVariable is an EKE_ environment variable and the key it overrides
+type envVariable struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type envVariables []envVariable
+
+func (l envVariables) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, v := range l {
+		rows = append(rows, table.Row{v.Name, v.Key, v.Value})
+	}
+	return table.Row{"Variable", "Key", "Value"}, rows
+}
@@ -16,11 +16,14 @@ limitations under the License.
 package kubeconfig
 
 import (
-	"log"
+	"eke/internal/pkg/logging"
 
 	"github.com/spf13/cobra"
 )
 
+// logger writes the messages of the ews subsystem
+var logger = logging.For(logging.EWS)
+
 func NewKubeconfigCmd() *cobra.Command {
 
 	// kubeconfigCmd represents the kubeconfig command
@@ -31,7 +34,7 @@ func NewKubeconfigCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				if err := cmd.Help(); err != nil {
-					log.Println(err)
+					logger.Warnln(err)
 				}
 			}
 		},
@@ -41,6 +44,7 @@ func NewKubeconfigCmd() *cobra.Command {
 	kubeconfigCmd.AddCommand(kubeconfigGetCmd())
 	kubeconfigCmd.AddCommand(kubeconfigInitCmd())
 	kubeconfigCmd.AddCommand(kubeconfigResetCmd())
+	kubeconfigCmd.AddCommand(kubeconfigListCmd())
 
 	return kubeconfigCmd
 }
@@ -16,7 +16,9 @@ limitations under the License.
 package kubeconfig
 
 import (
+	"eke/internal/pkg/logging"
 	util "eke/internal/util/utilityFunctions"
+	"eke/pkg/config"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -28,9 +30,16 @@ func kubeconfigAuthCmd() *cobra.Command {
 		Use:   "auth",
 		Short: "Gets user .crt and .key from EWS",
 		Long:  `Checks for cached .crt and .key files, if not it will request them from EWS`,
+		// kubectl runs it as the exec credential plugin of the contexts
+		// set up by eke kubeconfig init
+		Annotations: map[string]string{logging.ExecPluginAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
-			eke_cache := util.Get_eke_path()
-			userCert, userKey := util.GetCertAndKey(eke_cache)
+			profile, ews, err := util.ProfileEws(config.GetCmdOpts().CmdConfig, config.ProfileRequested())
+			if err != nil {
+				logger.Fatalln(err)
+			}
+			eke_cache := util.Get_profile_path(profile)
+			userCert, userKey := util.GetCertAndKey(ews, eke_cache)
 			fmt.Println(util.CreateOutput(userCert, userKey))
 		},
 	}
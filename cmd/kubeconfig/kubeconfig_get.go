@@ -18,8 +18,8 @@ package kubeconfig
 import (
 	"bufio"
 	util "eke/internal/util/utilityFunctions"
+	"eke/pkg/config"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -36,9 +36,9 @@ func kubeconfigGetCmd() *cobra.Command {
 
 			// the name of the kubeconfig file is set via the flag,
 			// or KUBECONFIG env variable, or the default path
-			// 1. Read the flag
+			// 1. Read the flag, the profile sets its default
 			var kubeconfig_path string
-			kubeconfig_path, _ = cmd.Flags().GetString("kubeconfig")
+			kubeconfig_path = profileKubeconfig(cmd, config.GetCmdOpts().CmdConfig)
 			if kubeconfig_path == "" {
 				// 2. Read the env variable
 				kubeconfig_path = os.Getenv("KUBECONFIG")
@@ -50,7 +50,7 @@ func kubeconfigGetCmd() *cobra.Command {
 			// display the contents of the kubeconfig file
 			kubeconfig, err := os.Open(kubeconfig_path)
 			if err != nil {
-				log.Print("could not get the kubeconfig file:", err)
+				logger.Warn("could not get the kubeconfig file:", err)
 			}
 
 			scanner := bufio.NewScanner(kubeconfig)
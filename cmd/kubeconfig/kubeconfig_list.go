@@ -0,0 +1,72 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeconfig
+
+import (
+	"eke/internal/kubectlcmd/kubehelper"
+	"eke/pkg/config"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// contextList is printed as a table of the contexts, the current one
+// marked with a star
+type contextList []kubehelper.ContextInfo
+
+func kubeconfigListCmd() *cobra.Command {
+	var kubeconfig string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the contexts of the kubeconfig file",
+		Long: `List the contexts of the kubeconfig file along with their cluster, API server,
+user and namespace. The file is the one of the --kubeconfig flag, of the selected
+profile, of the KUBECONFIG variable or ~/.kube/config, in this order.`,
+		Example: `
+  List the API servers set up by eke kubeconfig init:
+  $ eke kubeconfig list -o jsonpath='{range [?(@.eke)]}{.server}{"\n"}{end}'`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			flags := &kubehelper.ConnectionFlags{KubeConfig: kubeconfig}
+			contexts, err := flags.ContextInfos()
+			if err != nil {
+				return err
+			}
+			return printer.Print(cmd.OutOrStdout(), contextList(contexts))
+		},
+	}
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+func (l contextList) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, c := range l {
+		current := ""
+		if c.Current {
+			current = "*"
+		}
+		rows = append(rows, table.Row{current, c.Name, c.Cluster, c.Server, c.User, c.Namespace})
+	}
+	return table.Row{"Current", "Name", "Cluster", "Server", "User", "Namespace"}, rows
+}
@@ -18,8 +18,8 @@ package kubeconfig
 import (
 	//"fmt"
 	util "eke/internal/util/utilityFunctions"
+	"eke/pkg/config"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 
@@ -31,11 +31,12 @@ func kubeconfigResetCmd() *cobra.Command {
 	var resetCmd = &cobra.Command{
 		Use:   "reset",
 		Short: "Clean up kubeconfig file and ews config dir ~/.eke/",
-		Long:  `Clean up kubeconfig file and ews config dir ~/.eke/`,
+		Long: `Clean up kubeconfig file and ews config dir ~/.eke/, or ~/.eke/profiles/<profile>/
+when a profile is selected`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := RunReset(cmd)
 			if err != nil {
-				log.Fatal(err)
+				logger.Fatal(err)
 			}
 			return nil
 		},
@@ -50,9 +51,14 @@ func RunReset(cmd *cobra.Command) error {
 
 	// the name of the kubeconfig file is set via the flag,
 	// or KUBECONFIG env variable, or the default path
-	// 1. Read the flag
+	// 1. Read the flag, the profile sets its default
+	c := config.GetCmdOpts()
+	profile, _, err := util.ProfileEws(c.CmdConfig, config.ProfileRequested())
+	if err != nil {
+		return err
+	}
 	var kubeconfig_path string
-	kubeconfig_path, _ = cmd.Flags().GetString("kubeconfig")
+	kubeconfig_path = profileKubeconfig(cmd, c.CmdConfig)
 	if kubeconfig_path == "" {
 		// 2. Read the env variable
 		kubeconfig_path = os.Getenv("KUBECONFIG")
@@ -62,14 +68,14 @@ func RunReset(cmd *cobra.Command) error {
 	}
 
 	// remove the kubeconfig file
-	err := os.Remove(kubeconfig_path)
+	err = os.Remove(kubeconfig_path)
 	if err != nil {
 		// This error should not be fatal as kubeconfig file might not exist yet
-		log.Println("error while clearing kubeconfig:", err)
+		logger.Warnln("error while clearing kubeconfig:", err)
 	}
 
-	// clear the eke cache
-	eke_cache := util.Get_eke_path()
+	// clear the eke cache, only the one of the profile when selected
+	eke_cache := util.Get_profile_path(profile)
 	d, err := os.Open(eke_cache)
 	if err != nil {
 		//log.Fatal("error while clearing the eke cache:", err)
@@ -0,0 +1,59 @@
+package kubectl
+
+import (
+	"errors"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"eke/internal/kubectlcmd/alias"
+	"eke/pkg/config"
+)
+
+// NewAliasesCmd creates a new `eke kubectl aliases` cobra command
+func NewAliasesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          ALIASES_CMD,
+		Short:        "List the kubectl aliases defined in eke.cmd.yaml",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			aliases, err := alias.NewSet(c.CmdConfig.Aliases)
+			if err != nil {
+				return err
+			}
+
+			list := aliasList{}
+			for _, a := range aliases.Sorted() {
+				list = append(list, aliasInfo{Name: a.Name, Expansion: a.Template})
+			}
+			return printer.Print(cmd.OutOrStdout(), list)
+		},
+	}
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+// aliasInfo is the json representation of an alias
+type aliasInfo struct {
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+}
+
+type aliasList []aliasInfo
+
+func (l aliasList) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, a := range l {
+		rows = append(rows, table.Row{a.Name, a.Expansion})
+	}
+	return table.Row{"Alias", "Expands to"}, rows
+}
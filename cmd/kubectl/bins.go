@@ -1,43 +1,127 @@
 package kubectl
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"time"
 
+	"github.com/blang/semver/v4"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
 
 	"eke/internal/kubectlcmd/finder"
+	"eke/internal/pkg/output"
+	"eke/pkg/config"
 )
 
+// binInfo is the json representation of a kubectl binary
+type binInfo struct {
+	Kind     string `json:"kind"`
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Size     int64  `json:"size"`
+	LastUsed string `json:"lastUsed,omitempty"`
+}
+
+// binsResult holds the kubectl binaries found, it is printed as a list of
+// binInfo by the formats other than table
+type binsResult struct {
+	system, local       []finder.InstalledKubectl
+	systemErr, localErr error
+	defaultVersion      *semver.Version
+}
+
 // NewBinsCmd creates a new `kuberlr bins` cobra command
 func NewBinsCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "bins",
-		Short: "Print information about the kubectl binaries found",
-		Run: func(cmd *cobra.Command, args []string) {
-			kFinder := finder.NewKubectlFinder("", "")
-			systemBins, err := kFinder.SystemKubectlBinaries()
-
-			fmt.Printf("%s\n", text.FgGreen.Sprint("system-wide kubectl binaries"))
+	cmd := &cobra.Command{
+		Use:          "bins",
+		Short:        "Print information about the kubectl binaries found",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
 			if err != nil {
-				fmt.Printf("Error retrieving binaries: %v\n", err)
-			} else if len(systemBins) == 0 {
-				fmt.Println("No binaries found.")
-			} else {
-				printBinTable(systemBins)
+				return err
 			}
 
-			fmt.Printf("\n\n")
-			localBins, err := kFinder.LocalKubectlBinaries()
-
-			fmt.Printf("%s\n", text.FgGreen.Sprint("local kubectl binaries"))
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			kFinder := newKubectlFinder(c.CmdConfig.EkeKubectlConfig)
+			state, err := kubectlStateFile().Load()
 			if err != nil {
-				fmt.Printf("Error retrieving binaries: %v\n", err)
-			} else if len(localBins) == 0 {
-				fmt.Println("No binaries found.")
-			} else {
-				printBinTable(localBins)
+				logger.Warnf("Cannot read the last use of the binaries: %v", err)
 			}
+
+			systemBins, systemErr := kFinder.SystemKubectlBinaries()
+			localBins, localErr := kFinder.LocalKubectlBinaries()
+			result := binsResult{
+				system:         finder.DescribeKubectlBinaries(systemBins, state),
+				local:          finder.DescribeKubectlBinaries(localBins, state),
+				systemErr:      systemErr,
+				localErr:       localErr,
+				defaultVersion: state.Default,
+			}
+			// a partial list would mislead the scripts
+			if printer.Format != output.Table {
+				if systemErr != nil {
+					return systemErr
+				}
+				if localErr != nil {
+					return localErr
+				}
+			}
+			return printer.Print(cmd.OutOrStdout(), result)
 		},
 	}
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+// WriteText prints the system-wide and the local binaries in two tables
+func (r binsResult) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s\n", text.FgGreen.Sprint("system-wide kubectl binaries"))
+	printBinSection(w, r.system, r.systemErr)
+
+	fmt.Fprintf(w, "\n\n")
+	fmt.Fprintf(w, "%s\n", text.FgGreen.Sprint("local kubectl binaries"))
+	printBinSection(w, r.local, r.localErr)
+	if r.defaultVersion != nil {
+		fmt.Fprintf(w, "\nDefault version: %s\n", r.defaultVersion)
+	}
+	return nil
+}
+
+// MarshalJSON lists the system-wide binaries, followed by the local ones
+func (r binsResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(append(binInfos("system", r.system), binInfos("local", r.local)...))
+}
+
+func printBinSection(w io.Writer, bins []finder.InstalledKubectl, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "Error retrieving binaries: %v\n", err)
+	} else if len(bins) == 0 {
+		fmt.Fprintln(w, "No binaries found.")
+	} else {
+		printBinTable(w, bins)
+	}
+}
+
+func binInfos(kind string, bins []finder.InstalledKubectl) []binInfo {
+	infos := []binInfo{}
+	for _, b := range bins {
+		info := binInfo{
+			Kind:    kind,
+			Path:    b.Path,
+			Version: b.Version.String(),
+			Size:    b.Size,
+		}
+		if !b.LastUsed.IsZero() {
+			info.LastUsed = b.LastUsed.Format(time.RFC3339)
+		}
+		infos = append(infos, info)
+	}
+	return infos
 }
@@ -0,0 +1,136 @@
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eke/internal/kubectlcmd/bundle"
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/complete"
+	"eke/internal/kubectlcmd/downloader"
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// NewBundleCmd creates a new `eke kubectl bundle` cobra command
+func NewBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   BUNDLE_CMD,
+		Short: "Move kubectl binaries to hosts that cannot reach any mirror",
+	}
+	cmd.AddCommand(newBundleCreateCmd())
+	cmd.AddCommand(newBundleImportCmd())
+	return cmd
+}
+
+func newBundleCreateCmd() *cobra.Command {
+	var versions, platforms []string
+	var output string
+
+	cmd := &cobra.Command{
+		Use:          "create",
+		Short:        "Download kubectl binaries into a bundle",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Bundle the latest patch release of some minor versions for the current platform:
+  $ eke kubectl bundle create --versions 1.22,1.23,1.24 -o bundle.tar.gz
+
+  Bundle binaries for other platforms:
+  $ eke kubectl bundle create --versions 1.23.4 --platforms linux/amd64,linux/arm64 -o bundle.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ps []common.Platform
+			for _, arg := range platforms {
+				p, err := common.ParsePlatform(arg)
+				if err != nil {
+					return err
+				}
+				ps = append(ps, p)
+			}
+
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			d := downloader.NewDownloderFromConfig(c.CmdConfig.EkeKubectlConfig)
+			vs, err := bundle.ParseVersions(versions, d.LatestPatchVersion)
+			if err != nil {
+				return err
+			}
+
+			// write next to the output first, a failed run must not leave
+			// a truncated bundle behind
+			tmp, err := ioutil.TempFile(filepath.Dir(output), filepath.Base(output)+".*.tmp")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp.Name())
+
+			bins, err := bundle.Create(d, vs, ps, tmp)
+			if err != nil {
+				tmp.Close()
+				return err
+			}
+			if err := tmp.Close(); err != nil {
+				return err
+			}
+			if err := os.Rename(tmp.Name(), output); err != nil {
+				return err
+			}
+
+			for _, b := range bins {
+				fmt.Fprintf(cmd.OutOrStdout(), "Added kubectl %s for %s\n", b.Version, b.Platform)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Bundle written to %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&versions, "versions", nil, "Comma separated list of the kubectl versions to bundle, a minor version like 1.22 stands for its latest patch release")
+	cmd.Flags().StringSliceVar(&platforms, "platforms", []string{common.HostPlatform().String()}, "Comma separated list of the os/arch platforms to bundle")
+	cmd.Flags().StringVarP(&output, "output", "o", "kubectl-bundle.tar.gz", "Path of the bundle to create")
+	cmd.RegisterFlagCompletionFunc("versions", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		c := CmdOpts(config.GetCmdOpts())
+		if c.CmdConfig == nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return complete.CommaSeparated(installedVersions(c.CmdConfig.EkeKubectlConfig), toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.MarkFlagRequired("versions")
+	return cmd
+}
+
+func newBundleImportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "import <bundle>",
+		Short:        "Install the kubectl binaries of a bundle",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `
+  Install the binaries bundled on another host, every binary is checked against the bundle manifest first:
+  $ eke kubectl bundle import bundle.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			bins, err := bundle.Import(f, common.LocalDownloadDirFor)
+			if err != nil {
+				return fmt.Errorf("cannot import %s: %v", args[0], err)
+			}
+
+			var installed []string
+			for _, b := range bins {
+				installed = append(installed, fmt.Sprintf("  %s (%s)", b.Path, b.Platform))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed %d kubectl binaries:\n%s\n", len(bins), strings.Join(installed, "\n"))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,206 @@
+package kubectl
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"eke/internal/kubectlcmd/alias"
+	"eke/internal/kubectlcmd/complete"
+	"eke/pkg/config"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// completionTimeout bounds the time kubectl takes to complete a command
+const completionTimeout = 10 * time.Second
+
+// CompleteKubectl answers the shell completion requests of kubectl
+// commands, they are forwarded to the kubectl binary matching the cluster
+// so that resources complete like with kubectl. args are the arguments of
+// eke, it returns false when the request is left to cobra, which cannot
+// parse the kubectl flags
+func CompleteKubectl(root *cobra.Command, args []string, w io.Writer) bool {
+	if len(args) == 0 || (args[0] != cobra.ShellCompRequestCmd && args[0] != cobra.ShellCompNoDescRequestCmd) {
+		return false
+	}
+	request, args := args[0], args[1:]
+
+	n, pending := leadingFlags(root.PersistentFlags(), args)
+	if pending != nil || len(args) < n+2 || args[n] != "kubectl" {
+		return false
+	}
+	kubectlCmd, _, err := root.Find([]string{"kubectl"})
+	if err != nil {
+		return false
+	}
+
+	// the flags of eke kubectl, along with the inherited ones, are parsed
+	// since they select the configuration
+	flags := pflag.NewFlagSet("kubectl", pflag.ContinueOnError)
+	flags.AddFlagSet(kubectlCmd.Flags())
+	flags.AddFlagSet(kubectlCmd.InheritedFlags())
+	ekeArgs := args[:n]
+	args = args[n+1:]
+	n, pending = leadingFlags(flags, args)
+	parsed := n
+	if pending != nil {
+		// the flag whose value is under completion
+		parsed--
+	}
+	if err := flags.Parse(append(ekeArgs, args[:parsed]...)); err != nil {
+		return false
+	}
+	if pending != nil {
+		if pending.Name != "contexts" {
+			return false
+		}
+		complete.Write(w, complete.CommaSeparated(complete.Contexts(""), args[n]), cobra.ShellCompDirectiveNoFileComp)
+		return true
+	}
+
+	words := args[n:]
+	separated := len(words) > 1 && words[0] == "--"
+	if separated {
+		words = words[1:]
+	}
+	kubectlArgs, toComplete := words[:len(words)-1], words[len(words)-1]
+	if len(kubectlArgs) == 0 && !separated && strings.HasPrefix(toComplete, "-") {
+		// the flags of eke kubectl
+		return false
+	}
+	if len(kubectlArgs) > 0 && isManagementCmd(kubectlArgs[0]) {
+		return false
+	}
+
+	c := CmdOpts(config.GetCmdOpts())
+	if c.CmdConfig == nil || UseProfileKubeconfig(c.CmdConfig) != nil {
+		complete.Write(w, nil, cobra.ShellCompDirectiveError)
+		return true
+	}
+	aliases, err := alias.NewSet(c.CmdConfig.Aliases)
+	if err != nil {
+		complete.Write(w, nil, cobra.ShellCompDirectiveError)
+		return true
+	}
+	if len(kubectlArgs) > 0 {
+		if _, ok := aliases[strings.ToLower(kubectlArgs[0])]; ok {
+			// the arguments of aliases do not map to kubectl ones
+			complete.Write(w, nil, cobra.ShellCompDirectiveNoFileComp)
+			return true
+		}
+	}
+
+	completions, directive, err := completeWithKubectl(c.CmdConfig.EkeKubectlConfig, request, kubectlArgs, toComplete)
+	if err != nil {
+		logger.Warnln(err)
+	}
+	if len(kubectlArgs) == 0 {
+		extra := []string{LIST_BINS_CMD, GET_BIN_CMD, WHICH_CMD, USE_CMD, REMOVE_CMD, PRUNE_CMD, VERIFY_CMD, BUNDLE_CMD, ALIASES_CMD}
+		for _, a := range aliases.Sorted() {
+			extra = append(extra, a.Name)
+		}
+		completions = append(completions, complete.Matching(extra, toComplete)...)
+		if err != nil {
+			directive = cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	complete.Write(w, completions, directive)
+	return true
+}
+
+// completeWithKubectl asks the kubectl binary kubectlArgs would run with to
+// complete them, nothing is downloaded
+func completeWithKubectl(config cmdconfig.EkeKubectlConfig, request string, kubectlArgs []string, toComplete string) ([]string, cobra.ShellCompDirective, error) {
+	versioner, err := newVersioner(config, kubectlArgs)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError, err
+	}
+	versioner.RefreshInBackground = func() {
+		refreshServerVersionInBackground(kubectlArgs)
+	}
+	selection, err := versioner.ExplainKubectlToUse(int64(config.Timeout), false)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError, err
+	}
+	if _, err := os.Stat(selection.Path); err != nil {
+		return nil, cobra.ShellCompDirectiveError, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	return complete.Forward(ctx, selection.Path, request, append(kubectlArgs, toComplete))
+}
+
+// leadingFlags returns the number of arguments, from the first one, that
+// are flags of fs or their values. The last argument, which is under
+// completion, is never counted unless it is the value of a flag, which is
+// returned then
+func leadingFlags(fs *pflag.FlagSet, args []string) (int, *pflag.Flag) {
+	n := 0
+	for n < len(args)-1 {
+		arg := args[n]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		hasValue := strings.Contains(name, "=")
+		name = strings.SplitN(name, "=", 2)[0]
+		var f *pflag.Flag
+		if strings.HasPrefix(arg, "--") {
+			f = fs.Lookup(name)
+		} else if len(name) == 1 {
+			f = fs.ShorthandLookup(name)
+		}
+		if f == nil {
+			break
+		}
+
+		if hasValue || f.NoOptDefVal != "" {
+			n++
+			continue
+		}
+		if n+1 == len(args)-1 {
+			return n + 1, f
+		}
+		n += 2
+	}
+	return n, nil
+}
+
+// completeVersions completes the versions of the kubectl binaries found
+func completeVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c := CmdOpts(config.GetCmdOpts())
+	if c.CmdConfig == nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return complete.Matching(installedVersions(c.CmdConfig.EkeKubectlConfig), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeVersion completes the version of the commands taking a single one
+func completeVersion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeVersions(cmd, args, toComplete)
+}
+
+// installedVersions returns the versions of the kubectl binaries found,
+// from the newest to the oldest
+func installedVersions(config cmdconfig.EkeKubectlConfig) []string {
+	seen := map[string]bool{}
+	var versions []string
+	for _, b := range newKubectlFinder(config).AllKubectlBinaries(true) {
+		v := b.Version.String()
+		if !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
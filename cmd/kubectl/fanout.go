@@ -0,0 +1,114 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"eke/internal/kubectlcmd/fanout"
+	"eke/internal/kubectlcmd/guard"
+	"eke/internal/kubectlcmd/kubehelper"
+	"eke/pkg/config/cmdconfig"
+)
+
+// fanOutOptions holds the flags running kubectl against several contexts
+type fanOutOptions struct {
+	contexts       []string
+	allEkeContexts bool
+	parallel       int
+	group          bool
+}
+
+func (o *fanOutOptions) enabled() bool {
+	return len(o.contexts) > 0 || o.allEkeContexts
+}
+
+// fanOutMode runs kubectl against every selected context, each one with
+// the kubectl binary matching its server, and returns the combined exit
+// status. acknowledged tells whether the --i-know flag has been given
+func fanOutMode(config cmdconfig.EkeKubectlConfig, profile string, opts fanOutOptions, args []string, acknowledged bool) int {
+	acknowledged = acknowledged || guard.Acknowledged(args)
+	args = guard.StripAcknowledgement(args)
+	contexts, err := fanOutContexts(opts, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// every context is checked before running anything, confirmations
+	// cannot be asked while the commands run
+	for _, kubeContext := range contexts {
+		if err := checkGuardrails(config, append([]string{"--context", kubeContext}, args...), acknowledged); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := fanout.Run(ctx, contexts, opts.parallel, func(ctx context.Context, kubeContext string) fanout.Result {
+		contextArgs := append([]string{"--context", kubeContext}, args...)
+		versioner, err := newVersioner(config, contextArgs)
+		if err != nil {
+			return fanout.Result{Err: err}
+		}
+		selection, err := versioner.KubectlToUse(int64(config.Timeout), config.AllowDownload)
+		if err != nil {
+			return fanout.Result{Err: err}
+		}
+		auditCommand(config, profile, contextArgs, selection.Version.String())
+		return fanout.Command(ctx, selection.Path, contextArgs, os.Environ())
+	})
+
+	if fanout.WantsJSON(args) {
+		if err := fanout.WriteJSON(os.Stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else if opts.group {
+		fanout.WriteGrouped(os.Stdout, os.Stderr, results)
+	} else {
+		fanout.WritePrefixed(os.Stdout, os.Stderr, results)
+	}
+
+	var failed []string
+	for _, r := range results {
+		if r.Failed() {
+			failed = append(failed, r.Context)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d contexts failed: %s\n", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return fanout.ExitCode(results)
+}
+
+// fanOutContexts returns the contexts selected by the flags, the kubectl
+// arguments cannot select a context on their own
+func fanOutContexts(opts fanOutOptions, args []string) ([]string, error) {
+	flags, err := kubehelper.ParseConnectionFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if flags.Overrides.CurrentContext != "" {
+		return nil, errors.New("--context cannot be combined with --contexts or --all-eke-contexts")
+	}
+
+	contexts := opts.contexts
+	if opts.allEkeContexts {
+		if len(contexts) > 0 {
+			return nil, errors.New("--contexts and --all-eke-contexts are mutually exclusive")
+		}
+		if contexts, err = flags.EkeContexts(); err != nil {
+			return nil, err
+		}
+		if len(contexts) == 0 {
+			return nil, errors.New("no context of the kubeconfig authenticates with eke, use `eke kubeconfig init` to add one")
+		}
+	}
+	return contexts, nil
+}
@@ -1,43 +1,194 @@
 package kubectl
 
 import (
+	"eke/internal/kubectlcmd/audit"
+	"eke/internal/kubectlcmd/cache"
+	"eke/internal/kubectlcmd/common"
 	"eke/internal/kubectlcmd/finder"
+	"eke/internal/kubectlcmd/guard"
 	"eke/internal/kubectlcmd/osexec"
+	"eke/internal/pkg/debug"
+	util "eke/internal/util/utilityFunctions"
 	"eke/pkg/config/cmdconfig"
-	"log"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
-func printBinTable(bins finder.KubectlBinaries) {
+func printBinTable(w io.Writer, bins []finder.InstalledKubectl) {
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"#", "Version", "Binary"})
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"#", "Version", "Binary", "Size", "Last used"})
 	for i, b := range bins {
-		t.AppendRow([]interface{}{i + 1, b.Version, b.Path})
+		lastUsed := "never"
+		if !b.LastUsed.IsZero() {
+			lastUsed = b.LastUsed.Format("2006-01-02 15:04")
+		}
+		t.AppendRow([]interface{}{i + 1, b.Version, b.Path, humanSize(b.Size), lastUsed})
 	}
 	t.Render()
 }
 
-func kubectlWrapperMode(config cmdconfig.EkeKubectlConfig, args []string) {
+// humanSize formats a number of bytes using binary units
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// kubectlStateFile returns the store of the default kubectl version and
+// of the last use of the binaries
+func kubectlStateFile() *cache.KubectlStateFile {
+	return cache.NewKubectlStateFile(filepath.Join(common.CacheDir(), finder.KubectlStateFile))
+}
 
+// newKubectlFinder returns a KubectlFinder searching the configured
+// directories
+func newKubectlFinder(config cmdconfig.EkeKubectlConfig) *finder.KubectlFinder {
 	kFinder := finder.NewKubectlFinder("", config.SystemPath)
-	versioner := finder.NewVersioner(kFinder)
-	version, err := versioner.KubectlVersionToUse(int64(config.Timeout))
+	kFinder.SearchPaths = config.SearchPaths
+	kFinder.SearchPATH = config.SearchPATH
+	kFinder.Prober = finder.NewKubectlProber(
+		filepath.Join(common.CacheDir(), finder.KubectlVersionCacheFile))
+	return kFinder
+}
+
+// newVersioner returns a Versioner picking the kubectl binaries according
+// to the configured policy
+func newVersioner(config cmdconfig.EkeKubectlConfig, args []string) (*finder.Versioner, error) {
+	policy, err := finder.NewVersionPolicy(config.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	kFinder := newKubectlFinder(config)
+	kFinder.Policy = policy
+	versioner := finder.NewVersioner(kFinder, config, args)
+	versioner.Policy = policy
+	return versioner, nil
+}
+
+// ExecKubectl runs the kubectl binary matching the cluster with the given
+// arguments, it is used by `eke tool kubectl`
+func ExecKubectl(config cmdconfig.EkeKubectlConfig, profile string, args []string) {
+	kubectlWrapperMode(config, profile, args, false)
+}
+
+// InstalledKubectl returns the available kubectl binary matching the last
+// known version of the cluster of the current context. Neither the cluster
+// nor the mirrors are contacted
+func InstalledKubectl(config cmdconfig.EkeKubectlConfig) (finder.Selection, error) {
+	versioner, err := newVersioner(config, nil)
+	if err != nil {
+		return finder.Selection{}, err
+	}
+	return versioner.InstalledKubectl()
+}
+
+// UseProfileKubeconfig points KUBECONFIG to the kubeconfig file of the
+// selected profile, unless KUBECONFIG is already set
+func UseProfileKubeconfig(cmdConfig *cmdconfig.EkeCmdConfig) error {
+	profile, err := cmdConfig.ActiveProfile()
+	if err != nil || profile == nil || profile.Kubeconfig == "" {
+		return err
+	}
+	if os.Getenv("KUBECONFIG") != "" {
+		return nil
+	}
+	return os.Setenv("KUBECONFIG", common.ExpandHome(profile.Kubeconfig))
+}
+
+// checkGuardrails returns an error when the kubectl command is stopped by
+// one of the configured guardrails. acknowledged tells whether the
+// --i-know flag has been given
+func checkGuardrails(config cmdconfig.EkeKubectlConfig, args []string, acknowledged bool) error {
+	rules, err := guard.NewRules(config.Guardrails)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	target, err := guard.TargetOf(args)
+	if err != nil {
+		return err
+	}
+
+	g := &guard.Guard{
+		Rules:  rules,
+		Prompt: guard.TerminalPrompt(),
+		Record: guard.FileRecorder(filepath.Join(common.CacheDir(), guard.DecisionLogFile)),
+	}
+	return g.Check(target, args, acknowledged)
+}
+
+// auditCommand appends the kubectl command to the audit log, failures are
+// reported without stopping the command
+func auditCommand(config cmdconfig.EkeKubectlConfig, profile string, args []string, kubectlVersion string) {
+	if config.Audit.Disabled {
+		return
+	}
+
+	target, err := guard.TargetOf(args)
 	if err != nil {
-		log.Fatal(err)
+		logger.Warnf("Cannot find out where kubectl runs: %v", err)
+	}
+	// the identity is unknown until `eke kubeconfig init` is run
+	user, _ := audit.CertificateUser(filepath.Join(common.ProfileDir(profile), util.ClientCertFile))
+
+	redact := append([]string{}, audit.DefaultRedactedFlags...)
+	record := audit.Record{
+		Time:           time.Now(),
+		User:           user,
+		Context:        target.Context,
+		Server:         target.Server,
+		Namespace:      target.Namespace,
+		KubectlVersion: kubectlVersion,
+		Args:           audit.Redact(args, append(redact, config.Audit.Redact...)),
+	}
+	l := audit.NewLog(common.AuditDir(), config.Audit.MaxSize, config.Audit.MaxFiles)
+	if err := l.Append(record); err != nil {
+		logger.Warnf("Cannot write the audit log: %v", err)
+	}
+}
+
+func kubectlWrapperMode(config cmdconfig.EkeKubectlConfig, profile string, args []string, acknowledged bool) {
+	acknowledged = acknowledged || guard.Acknowledged(args)
+	args = guard.StripAcknowledgement(args)
+	if err := checkGuardrails(config, args, acknowledged); err != nil {
+		logger.Fatal(err)
 	}
 
-	kubectlBin, err := versioner.EnsureCompatibleKubectlAvailable(
-		version,
+	versioner, err := newVersioner(config, args)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	versioner.RefreshInBackground = func() {
+		refreshServerVersionInBackground(args)
+	}
+	selection, err := versioner.KubectlToUse(
+		int64(config.Timeout),
 		config.AllowDownload)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
+	kubectlBin := selection.Path
+	auditCommand(config, profile, args, selection.Version.String())
 
-	log.Println(os.Args)
+	logger.Debugln(os.Args)
+	// kubectl replaces eke, the step never ends and the traces have to be
+	// exported beforehand
+	debug.StartStep(debug.StepExec, kubectlBin)
+	debug.Flush()
 	childArgs := append([]string{kubectlBin}, args...)
 	err = osexec.Exec(kubectlBin, childArgs, os.Environ())
-	log.Fatal(err)
+	logger.Fatal(err)
 }
@@ -1,12 +1,14 @@
 package kubectl
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
 	"eke/internal/kubectlcmd/common"
 
 	"eke/internal/kubectlcmd/downloader"
+	"eke/pkg/config"
 
 	"github.com/blang/semver/v4"
 	"github.com/spf13/cobra"
@@ -35,7 +37,11 @@ func NewGetbinCmd() *cobra.Command {
 				common.LocalDownloadDir(),
 				common.BuildKubectlNameForLocalBin(version))
 
-			d := downloader.Downloder{}
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			d := downloader.NewDownloderFromConfig(c.CmdConfig.EkeKubectlConfig)
 			return d.GetKubectlBinary(version, destination)
 		},
 	}
@@ -16,34 +16,84 @@ limitations under the License.
 package kubectl
 
 import (
+	"eke/internal/kubectlcmd/alias"
+	"eke/internal/pkg/logging"
 	"eke/pkg/config"
+	"os"
 
 	"github.com/spf13/cobra"
 )
 
+// logger writes the messages of the kubectl subsystem
+var logger = logging.For(logging.Kubectl)
+
 type CmdOpts config.CLIOptions
 
 const LIST_BINS_CMD = "bins"
 
 const GET_BIN_CMD = "get-bin"
 
+const WHICH_CMD = "which"
+
+const USE_CMD = "use"
+
+const REMOVE_CMD = "remove"
+
+const PRUNE_CMD = "prune"
+
+const VERIFY_CMD = "verify"
+
+const BUNDLE_CMD = "bundle"
+
+const ALIASES_CMD = "aliases"
+
+const REFRESH_SERVER_VERSION_CMD = "__refresh-server-version"
+
 func NewKubectlCmd() *cobra.Command {
+	var fanOut fanOutOptions
+	var acknowledged bool
 
 	cmd := &cobra.Command{
-		Use:   "kubectl [original kubectl commands | get-bin or bins]",
+		Use:   "kubectl [original kubectl commands | alias | get-bin, bins, which, use, remove, prune, verify, bundle or aliases]",
 		Short: "eke kubectl",
-		Long: `eke kubectl has two types of commands:
-		1, "get-bin" and "bins" used to manage different versions of kubectl
-		2, Other normal kubectl commands`,
+		Long: `eke kubectl has three types of commands:
+		1, "get-bin", "bins", "which", "use", "remove", "prune", "verify", "bundle" and "aliases" used to manage different versions of kubectl
+		2, Aliases defined in eke.cmd.yaml, expanded to the kubectl commands they stand for
+		3, Other normal kubectl commands`,
+		Example: `
+  Run a command against several contexts, each one with the kubectl version matching its cluster:
+  $ eke kubectl --contexts dev,staging,prod -- get nodes
+
+  Run a command against every context set up by eke kubeconfig init, merging the JSON outputs:
+  $ eke kubectl --all-eke-contexts --parallel 8 -- get pods -A -o json
+
+  Run the alias podsof, defined as "get pods -l app=$1 -o wide":
+  $ eke kubectl podsof web`,
 
 		Run: func(cmd *cobra.Command, args []string) {
 			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				logger.Fatal("cannot load eke.cmd.yaml")
+			}
+
+			// aliases are expanded before anything looks at the arguments
+			aliases, err := alias.NewSet(c.CmdConfig.Aliases)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			if args, err = aliases.Expand(args); err != nil {
+				logger.Fatal(err)
+			}
+
+			if len(args) > 0 && fanOut.enabled() {
+				os.Exit(fanOutMode(c.CmdConfig.EkeKubectlConfig, c.CmdConfig.Profile, fanOut, args, acknowledged))
+			}
 
 			if len(args) > 0 {
 				subcmd := args[0]
 
-				if subcmd != LIST_BINS_CMD && subcmd != GET_BIN_CMD {
-					kubectlWrapperMode(c.CmdConfig.EkeKubectlConfig, args)
+				if !isManagementCmd(subcmd) {
+					kubectlWrapperMode(c.CmdConfig.EkeKubectlConfig, c.CmdConfig.Profile, args, acknowledged)
 				} else {
 					return
 				}
@@ -53,7 +103,30 @@ func NewKubectlCmd() *cobra.Command {
 		},
 	}
 	cmd.SetUsageTemplate(USAGE_TEMPLATE)
+	cmd.Flags().StringSliceVar(&fanOut.contexts, "contexts", nil, "Run the kubectl command against each of these comma separated contexts, e.g. eke kubectl --contexts a,b -- get nodes")
+	cmd.Flags().BoolVar(&fanOut.allEkeContexts, "all-eke-contexts", false, "Run the kubectl command against every context authenticating with eke")
+	cmd.Flags().IntVar(&fanOut.parallel, "parallel", 4, "Number of contexts the kubectl command runs against at the same time")
+	cmd.Flags().BoolVar(&acknowledged, "i-know", false, "Run a command protected by a guardrail with the flag action")
+	cmd.Flags().BoolVar(&fanOut.group, "group", false, "Group the output by context instead of prefixing each line with its context")
 	cmd.AddCommand(NewBinsCmd())
 	cmd.AddCommand(NewGetbinCmd())
+	cmd.AddCommand(NewWhichCmd())
+	cmd.AddCommand(NewUseCmd())
+	cmd.AddCommand(NewRemoveCmd())
+	cmd.AddCommand(NewPruneCmd())
+	cmd.AddCommand(NewVerifyCmd())
+	cmd.AddCommand(NewBundleCmd())
+	cmd.AddCommand(NewAliasesCmd())
+	cmd.AddCommand(NewRefreshServerVersionCmd())
 	return cmd
 }
+
+// isManagementCmd returns true when subcmd manages the kubectl binaries
+// instead of being forwarded to kubectl
+func isManagementCmd(subcmd string) bool {
+	switch subcmd {
+	case LIST_BINS_CMD, GET_BIN_CMD, WHICH_CMD, USE_CMD, REMOVE_CMD, PRUNE_CMD, VERIFY_CMD, BUNDLE_CMD, ALIASES_CMD:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package kubectl
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"eke/internal/pkg/output"
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func TestUnknownProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	profileName, outputFormat := config.ProfileName, config.OutputFormat
+	config.ProfileName, config.OutputFormat = "nope", output.Table
+	defer func() { config.ProfileName, config.OutputFormat = profileName, outputFormat }()
+
+	tests := []struct {
+		cmd  *cobra.Command
+		args []string
+	}{
+		{NewBinsCmd(), nil},
+		{NewBundleCmd(), []string{"create"}},
+		{NewGetbinCmd(), []string{"1.22.0"}},
+		{NewPruneCmd(), []string{"--keep", "1"}},
+		{NewRemoveCmd(), []string{"1.22.0"}},
+		{NewUseCmd(), []string{"1.22.0"}},
+		{NewVerifyCmd(), nil},
+		{NewWhichCmd(), nil},
+	}
+	for _, tt := range tests {
+		tt.cmd.SetArgs(tt.args)
+		tt.cmd.SetOut(ioutil.Discard)
+		tt.cmd.SetErr(ioutil.Discard)
+		if err := tt.cmd.Execute(); err == nil {
+			t.Errorf("%s: expected an error for an unknown profile", tt.cmd.Name())
+		}
+	}
+	// refresh-server-version runs in the background and only logs
+	refresh := NewRefreshServerVersionCmd()
+	refresh.SetArgs(nil)
+	if err := refresh.Execute(); err != nil {
+		t.Errorf("%s: %v", refresh.Name(), err)
+	}
+}
+
+func TestPruneRequiresAFlag(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	prune := NewPruneCmd()
+	prune.SetArgs(nil)
+	prune.SetOut(ioutil.Discard)
+	prune.SetErr(ioutil.Discard)
+	if err := prune.Execute(); err == nil || !strings.Contains(err.Error(), "--keep") {
+		t.Errorf("expected an error without --keep or --unused-for, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"eke/internal/kubectlcmd/finder"
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// NewPruneCmd creates a new `eke kubectl prune` cobra command
+func NewPruneCmd() *cobra.Command {
+	var keep int
+	var unusedFor string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:          PRUNE_CMD,
+		Short:        "Remove the downloaded kubectl binaries that are no longer used",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Keep the 3 most recently used binaries and the ones used in the last 90 days:
+  $ eke kubectl prune --keep 3 --unused-for 90d
+
+  Show what would be removed:
+  $ eke kubectl prune --unused-for 30d --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// a bare prune would remove every binary but the default one
+			if !cmd.Flags().Changed("keep") && !cmd.Flags().Changed("unused-for") {
+				return errors.New("at least one of --keep and --unused-for is required, --keep 0 removes every binary but the default one")
+			}
+			if keep < 0 {
+				return fmt.Errorf("--keep must be positive")
+			}
+			age, err := parseAge(unusedFor)
+			if err != nil {
+				return fmt.Errorf("invalid --unused-for value: %v", err)
+			}
+
+			state := kubectlStateFile()
+			current, err := state.Load()
+			if err != nil {
+				return err
+			}
+
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			kFinder := newKubectlFinder(c.CmdConfig.EkeKubectlConfig)
+			localBins, err := kFinder.LocalKubectlBinaries()
+			if err != nil {
+				return err
+			}
+
+			installed := finder.DescribeKubectlBinaries(localBins, current)
+			for _, b := range finder.PruneCandidates(installed, keep, age, time.Now(), current.Default) {
+				if dryRun {
+					fmt.Fprintf(cmd.OutOrStdout(), "Would remove %s (%s)\n", b.Path, humanSize(b.Size))
+					continue
+				}
+				if err := os.Remove(b.Path); err != nil {
+					return err
+				}
+				if err := state.Forget(b.Path); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %s (%s)\n", b.Path, humanSize(b.Size))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&keep, "keep", 0, "number of most recently used binaries to keep")
+	cmd.Flags().StringVar(&unusedFor, "unused-for", "0", "only remove the binaries not used for this long, e.g. 90d or 12h")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the binaries that would be removed")
+	return cmd
+}
+
+// parseAge parses a duration, on top of the units known by
+// time.ParseDuration it accepts a number of days, e.g. 90d
+func parseAge(s string) (time.Duration, error) {
+	if s == "0" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,62 @@
+package kubectl
+
+import (
+	"os"
+	"os/exec"
+
+	"eke/internal/kubectlcmd/finder"
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// NewRefreshServerVersionCmd creates the hidden command used to update the
+// cached API server version without slowing down the kubectl invocation
+func NewRefreshServerVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    REFRESH_SERVER_VERSION_CMD + " -- [kubectl args]",
+		Short:  "Refresh the cached version of the API server",
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				logger.Warnln("cannot load eke.cmd.yaml")
+				return
+			}
+			kubectlConfig := c.CmdConfig.EkeKubectlConfig
+
+			kFinder := finder.NewKubectlFinder("", kubectlConfig.SystemPath)
+			versioner := finder.NewVersioner(kFinder, kubectlConfig, args)
+			if err := versioner.RefreshServerVersion(int64(kubectlConfig.Timeout)); err != nil {
+				logger.Warnln(err)
+			}
+		},
+	}
+}
+
+// refreshServerVersionInBackground starts a detached eke process refreshing
+// the version of the API server targeted by the given kubectl args. The
+// process outlives the current one, which is replaced by kubectl
+func refreshServerVersionInBackground(args []string) {
+	self, err := os.Executable()
+	if err != nil {
+		logger.Warnln("cannot refresh the API server version:", err)
+		return
+	}
+
+	childArgs := []string{"kubectl", REFRESH_SERVER_VERSION_CMD}
+	if config.CmdCfgFile != "" {
+		childArgs = append(childArgs, "--cmd-config", config.CmdCfgFile)
+	}
+	childArgs = append(childArgs, "--")
+	childArgs = append(childArgs, args...)
+
+	child := exec.Command(self, childArgs...)
+	if err := child.Start(); err != nil {
+		logger.Warnln("cannot refresh the API server version:", err)
+		return
+	}
+	if err := child.Process.Release(); err != nil {
+		logger.Warnln(err)
+	}
+}
@@ -0,0 +1,70 @@
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"eke/pkg/config"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+)
+
+// NewRemoveCmd creates a new `eke kubectl remove` cobra command
+func NewRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               REMOVE_CMD + " [version]",
+		Short:             "Remove a downloaded kubectl binary",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeVersion,
+		SilenceUsage:      true,
+		Example: `
+  Remove the kubectl 1.20.4 binary downloaded by eke:
+  $ eke kubectl remove 1.20.4`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := semver.ParseTolerant(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid version: %v", err)
+			}
+
+			state := kubectlStateFile()
+			current, err := state.Load()
+			if err != nil {
+				return err
+			}
+			if current.Default != nil && current.Default.Equals(version) {
+				return fmt.Errorf("kubectl %s is the default version, run `eke kubectl use --unset` first", version)
+			}
+
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			kFinder := newKubectlFinder(c.CmdConfig.EkeKubectlConfig)
+			localBins, err := kFinder.LocalKubectlBinaries()
+			if err != nil {
+				return err
+			}
+
+			removed := 0
+			for _, b := range localBins {
+				if !b.Version.Equals(version) {
+					continue
+				}
+				if err := os.Remove(b.Path); err != nil {
+					return err
+				}
+				if err := state.Forget(b.Path); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", b.Path)
+				removed++
+			}
+			if removed == 0 {
+				return fmt.Errorf("kubectl %s has not been downloaded to %s", version, kFinder.LocalBinaryPath)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+
+	"eke/pkg/config"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+)
+
+// NewUseCmd creates a new `eke kubectl use` cobra command
+func NewUseCmd() *cobra.Command {
+	var unset bool
+
+	cmd := &cobra.Command{
+		Use:               USE_CMD + " [version]",
+		Short:             "Set the kubectl version used when the version of the server is unknown",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeVersion,
+		SilenceUsage:      true,
+		Example: `
+  Fall back to kubectl 1.22.3 when the API server cannot be reached:
+  $ eke kubectl use 1.22.3
+
+  Show the default version:
+  $ eke kubectl use
+
+  Go back to the most recent kubectl available:
+  $ eke kubectl use --unset`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state := kubectlStateFile()
+
+			if unset {
+				if len(args) > 0 {
+					return errors.New("--unset does not take a version")
+				}
+				return state.SetDefault(nil)
+			}
+
+			if len(args) == 0 {
+				current, err := state.Load()
+				if err != nil {
+					return err
+				}
+				if current.Default == nil {
+					fmt.Fprintln(cmd.OutOrStdout(), "No default version, the most recent kubectl available is used")
+				} else {
+					fmt.Fprintln(cmd.OutOrStdout(), current.Default)
+				}
+				return nil
+			}
+
+			version, err := semver.ParseTolerant(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid version: %v", err)
+			}
+
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			kFinder := newKubectlFinder(c.CmdConfig.EkeKubectlConfig)
+			for _, b := range kFinder.AllKubectlBinaries(true) {
+				if b.Version.Equals(version) {
+					return state.SetDefault(&version)
+				}
+			}
+			return fmt.Errorf("kubectl %s is not available, use `eke kubectl get-bin %s` to download it", version, version)
+		},
+	}
+	cmd.Flags().BoolVar(&unset, "unset", false, "remove the default version")
+	return cmd
+}
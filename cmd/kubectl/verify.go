@@ -0,0 +1,112 @@
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"eke/internal/kubectlcmd/downloader"
+	"eke/internal/kubectlcmd/manifest"
+	"eke/pkg/config"
+
+	"github.com/blang/semver/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// NewVerifyCmd creates a new `eke kubectl verify` cobra command
+func NewVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               VERIFY_CMD + " [version...]",
+		Short:             "Check the downloaded kubectl binaries against the checksums published by the mirrors",
+		SilenceUsage:      true,
+		ValidArgsFunction: completeVersions,
+		Example: `
+  Verify all the downloaded binaries:
+  $ eke kubectl verify
+
+  Verify only some versions:
+  $ eke kubectl verify 1.22.3 1.23.1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var versions []semver.Version
+			for _, arg := range args {
+				v, err := semver.ParseTolerant(arg)
+				if err != nil {
+					return fmt.Errorf("invalid version %q: %v", arg, err)
+				}
+				versions = append(versions, v)
+			}
+
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			kFinder := newKubectlFinder(c.CmdConfig.EkeKubectlConfig)
+			localBins, err := kFinder.LocalKubectlBinaries()
+			if err != nil {
+				return err
+			}
+			d := downloader.NewDownloderFromConfig(c.CmdConfig.EkeKubectlConfig)
+
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"Version", "Binary", "Result", "Checksum source"})
+			checked, failed := 0, 0
+			for _, b := range localBins {
+				if !containsVersion(versions, b.Version) {
+					continue
+				}
+				checked++
+
+				result, source := verifyBinary(d, b.Path, b.Version)
+				if result != "ok" {
+					failed++
+				}
+				t.AppendRow([]interface{}{b.Version, b.Path, result, source})
+			}
+			if checked == 0 {
+				return fmt.Errorf("no downloaded kubectl binary to verify in %s", kFinder.LocalBinaryPath)
+			}
+			t.Render()
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d binaries failed the verification", failed, checked)
+			}
+			return nil
+		},
+	}
+}
+
+// verifyBinary compares the checksum of the binary at path with the one
+// published by the mirrors
+func verifyBinary(d *downloader.Downloder, path string, version semver.Version) (result, source string) {
+	expected, signed, err := d.KubectlChecksum(version)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), "-"
+	}
+	source = "checksum file"
+	if signed {
+		source = "signed manifest"
+	}
+
+	actual, err := manifest.FileChecksum(path)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), source
+	}
+	if actual != expected {
+		return "MISMATCH", source
+	}
+	return "ok", source
+}
+
+func containsVersion(versions []semver.Version, v semver.Version) bool {
+	if len(versions) == 0 {
+		return true
+	}
+	for _, candidate := range versions {
+		if candidate.Equals(v) {
+			return true
+		}
+	}
+	return false
+}
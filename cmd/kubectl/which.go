@@ -0,0 +1,71 @@
+package kubectl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// NewWhichCmd creates a new `eke kubectl which` cobra command
+func NewWhichCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "which -- [kubectl args]",
+		Short:        "Print the kubectl binary that would be executed and why it was picked",
+		SilenceUsage: true,
+		Example: `
+  Show the kubectl used with the current context:
+  $ eke kubectl which
+
+  kubectl arguments selecting the cluster are taken into account:
+  $ eke kubectl which -- --context prod get pods`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			kubectlConfig := c.CmdConfig.EkeKubectlConfig
+
+			versioner, err := newVersioner(kubectlConfig, args)
+			if err != nil {
+				return err
+			}
+			selection, err := versioner.ExplainKubectlToUse(
+				int64(kubectlConfig.Timeout),
+				kubectlConfig.AllowDownload)
+			if err != nil {
+				return err
+			}
+
+			return printer.Print(cmd.OutOrStdout(), whichResult{
+				Path:    selection.Path,
+				Version: selection.Version.String(),
+				Reasons: selection.Reasons,
+			})
+		},
+	}
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+// whichResult is the kubectl binary picked and the reasons why
+type whichResult struct {
+	Path    string   `json:"path"`
+	Version string   `json:"version"`
+	Reasons []string `json:"reasons"`
+}
+
+func (r whichResult) WriteText(w io.Writer) error {
+	fmt.Fprintln(w, r.Path)
+	for _, reason := range r.Reasons {
+		fmt.Fprintf(w, "  - %s\n", reason)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eke/cmd/kubectl"
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/osexec"
+	"eke/internal/pkg/debug"
+	"eke/internal/pkg/logging"
+	"eke/internal/pkg/plugin"
+	"eke/pkg/config"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// logger writes the messages of the kubectl subsystem
+var logger = logging.For(logging.Kubectl)
+
+type CmdOpts config.CLIOptions
+
+// NewPluginCmd creates a new `eke plugin` cobra command
+func NewPluginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Inspect the plugins run as eke subcommands",
+		Long: `An executable called eke-<name>, found in ~/.eke/plugins or in a directory of
+PATH, runs as eke <name>, with the arguments following the name. The first one
+found wins, the built-in commands of eke cannot be replaced.
+
+The plugins are given the context of eke through the environment:
+  EKE_PLUGIN_PROFILE         the active profile, empty when none is
+  EKE_PLUGIN_IDENTITY_CACHE  the directory holding the EWS credentials of the profile
+  EKE_PLUGIN_KUBECONFIG      the kubeconfig files kubectl uses
+  EKE_PLUGIN_KUBECTL         the installed kubectl matching the last known version of the
+                             cluster, the cluster is not contacted, empty when none is
+  EKE_PLUGIN_EKE             the eke binary
+KUBECONFIG points to the kubeconfig file of the profile, unless already set.
+
+The flags of eke are not parsed before the name of a plugin, EKE_PROFILE selects
+the profile.`,
+	}
+	cmd.AddCommand(newListCmd())
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins found and their conflicts",
+		Example: `
+  List the plugins:
+  $ eke plugin list
+
+  List the plugins shadowed by another one or by a built-in command:
+  $ eke plugin list -o json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+			root := cmd.Root()
+			plugins := plugin.Discover(plugin.Dirs(), func(name string) bool {
+				return builtin(root, name)
+			})
+			if plugins == nil {
+				plugins = []plugin.Plugin{}
+			}
+			return printer.Print(cmd.OutOrStdout(), pluginList(plugins))
+		},
+	}
+	config.AddOutputFlag(cmd)
+	return cmd
+}
+
+// pluginList is printed as a table of the plugins
+type pluginList []plugin.Plugin
+
+func (l pluginList) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, p := range l {
+		rows = append(rows, table.Row{p.Name, p.Path, strings.Join(p.Warnings, ", ")})
+	}
+	return table.Row{"Name", "Path", "Warnings"}, rows
+}
+
+// Run runs the plugin named by the first of args, the arguments of eke,
+// with the following ones. It returns false, leaving args to cobra, when
+// they start with a flag or with a command of eke, or when no plugin has
+// this name. On success, the plugin replaces eke and Run never returns
+func Run(root *cobra.Command, args []string) (bool, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || builtin(root, args[0]) {
+		return false, nil
+	}
+	path, ok := plugin.Find(plugin.Dirs(), args[0])
+	if !ok {
+		return false, nil
+	}
+
+	env := pluginContext(CmdOpts(config.GetCmdOpts())).Env(os.Environ())
+	debug.StartStep(debug.StepExec, path)
+	debug.Flush()
+	childArgs := append([]string{path}, args[1:]...)
+	return true, osexec.Exec(path, childArgs, env)
+}
+
+// builtin tells whether name is a command of eke, including the ones
+// cobra adds when it executes the root command
+func builtin(root *cobra.Command, name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	cmd, _, err := root.Find([]string{name})
+	return err == nil && cmd != root
+}
+
+// pluginContext returns the context of eke given to the plugins, it
+// points KUBECONFIG to the kubeconfig of the profile like eke kubectl
+func pluginContext(c CmdOpts) plugin.Context {
+	ctx := plugin.Context{IdentityCache: common.ProfileDir("")}
+	if eke, err := os.Executable(); err == nil {
+		ctx.Eke = eke
+	}
+
+	if c.CmdConfig != nil {
+		ctx.Profile = c.CmdConfig.Profile
+		ctx.IdentityCache = common.ProfileDir(ctx.Profile)
+		if err := kubectl.UseProfileKubeconfig(c.CmdConfig); err != nil {
+			logger.Warn(err)
+		}
+		if selection, err := kubectl.InstalledKubectl(c.CmdConfig.EkeKubectlConfig); err != nil {
+			logger.Infof("no kubectl binary for the plugin: %v", err)
+		} else {
+			ctx.Kubectl = selection.Path
+		}
+	}
+
+	ctx.Kubeconfig = os.Getenv("KUBECONFIG")
+	if ctx.Kubeconfig == "" {
+		ctx.Kubeconfig = filepath.Join(common.HomeDir(), ".kube", "config")
+	}
+	return ctx
+}
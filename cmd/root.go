@@ -17,15 +17,24 @@ package cmd
 
 import ( //"eke/cmd/auth"
 	// "log"
+	"eke/cmd/audit"
 	"eke/cmd/ckc"
+	"eke/cmd/clusters"
+	ekeconfig "eke/cmd/config"
 	"eke/cmd/kubeconfig"
 	"eke/cmd/kubectl"
+	"eke/cmd/plugin"
+	"eke/cmd/selfupdate"
 	"eke/cmd/showconfig"
+	"eke/cmd/tool"
 	"eke/cmd/version"
+	"eke/internal/kubectlcmd/complete"
+	ekedebug "eke/internal/pkg/debug"
+	"eke/internal/pkg/logging"
+	"eke/internal/pkg/telemetry"
 	"eke/pkg/build"
 	"eke/pkg/config"
-	"log"
-	"net/http"
+	"os"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -40,28 +49,54 @@ func newRootCmd() *cobra.Command {
 		Short: "EWS Kubernetes Engine",
 		Long:  `EWS Kubernetes Engine`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// the flags of the command under completion are not parsed yet
+			if cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+				return
+			}
 			c := cliOpts(config.GetCmdOpts())
 
-			if c.Verbose {
-				logrus.SetLevel(logrus.InfoLevel)
+			// set DEBUG from env, or from command flag
+			debug := viper.GetString("debug") != "" || c.Debug
+			// kubectl shows the messages of its exec plugins to its own user
+			_, quiet := cmd.Annotations[logging.ExecPluginAnnotation]
+			logOpts, err := config.LoggingOptions(c.CmdConfig, debug, c.Verbose, quiet)
+			if err == nil {
+				err = logging.Setup(logOpts)
+			}
+			if err != nil {
+				logrus.Fatal(err)
+			}
+
+			if tracing := config.TracingOptions(c.CmdConfig); tracing.Enabled() {
+				if err := telemetry.Setup(tracing, cmd.CommandPath()); err != nil {
+					logrus.Warn(err)
+				}
 			}
 
-			// set DEBUG from env, or from command flag
-			if viper.GetString("debug") != "" || c.Debug {
-				logrus.SetLevel(logrus.DebugLevel)
-				go func() {
-					log.Println("starting debug server under", c.DebugListenOn)
-					log.Println(http.ListenAndServe(c.DebugListenOn, nil))
-				}()
+			// the commands run against the clusters of the selected profile
+			if c.CmdConfig != nil {
+				if err := kubectl.UseProfileKubeconfig(c.CmdConfig); err != nil {
+					logrus.Fatal(err)
+				}
+			}
+
+			if debug {
+				server := ekedebug.Server{Addr: c.DebugListenOn, Token: c.DebugToken}
+				if !server.Loopback() && server.Token == "" {
+					logrus.Warnf("the debug server listens on %s without token, set --debug-token to protect it", c.DebugListenOn)
+				}
+				addr, err := server.Start()
+				if err != nil {
+					logrus.Warn(err)
+				} else {
+					logrus.Infof("debug server listening on %s, see /debug/pprof/, /debug/vars and /trace", addr)
+				}
 			}
 		},
 	}
 	// This is to remove the help command from list of Available commands
 	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
 
-	// Disable auto completion
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
-
 	rootCmd.DisableAutoGenTag = true
 
 	longDesc := "EWS Kubernetes Engine"
@@ -73,12 +108,25 @@ func newRootCmd() *cobra.Command {
 	// workaround for the data-dir location input for the kubectl command
 	rootCmd.PersistentFlags().AddFlagSet(config.GetKubeCtlFlagSet())
 	rootCmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	rootCmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cmdConfig, err := config.CmdConfigLoader().Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return complete.Matching(cmdConfig.ProfileNames(), toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
 
 	rootCmd.AddCommand(ckc.NewCkcCmd())
 	rootCmd.AddCommand(kubeconfig.NewKubeconfigCmd())
 	rootCmd.AddCommand(version.NewVersionCmd())
 	rootCmd.AddCommand(showconfig.NewShowconfigCmd())
 	rootCmd.AddCommand(kubectl.NewKubectlCmd())
+	rootCmd.AddCommand(tool.NewToolCmd())
+	rootCmd.AddCommand(audit.NewAuditCmd())
+	rootCmd.AddCommand(ekeconfig.NewConfigCmd())
+	rootCmd.AddCommand(clusters.NewClustersCmd())
+	rootCmd.AddCommand(plugin.NewPluginCmd())
+	rootCmd.AddCommand(selfupdate.NewSelfUpdateCmd())
 
 	return rootCmd
 }
@@ -86,5 +134,17 @@ func newRootCmd() *cobra.Command {
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cobra.CheckErr(newRootCmd().Execute())
+	rootCmd := newRootCmd()
+	// cobra cannot parse the kubectl flags, kubectl completes its own commands
+	if kubectl.CompleteKubectl(rootCmd, os.Args[1:], os.Stdout) {
+		return
+	}
+	// eke-<name> executables run as eke <name>
+	if ok, err := plugin.Run(rootCmd, os.Args[1:]); ok {
+		cobra.CheckErr(err)
+		return
+	}
+	err := rootCmd.Execute()
+	ekedebug.Flush()
+	cobra.CheckErr(err)
 }
@@ -0,0 +1,112 @@
+package selfupdate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"eke/internal/pkg/selfupdate"
+	"eke/pkg/build"
+	"eke/pkg/config"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+)
+
+type CmdOpts config.CLIOptions
+
+// NewSelfUpdateCmd creates a new `eke self-update` cobra command
+func NewSelfUpdateCmd() *cobra.Command {
+	var version string
+	var rollback bool
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Replace eke with the latest release, or a given version",
+		Long: `eke self-update downloads the build of eke for this os and architecture from the
+release index set by releases.indexURL in eke.cmd.yaml. The build is checked
+against the checksum manifest of the release, which has to be signed with one of
+releases.publicKeys, then replaces the running executable. The replaced binary
+is kept next to it, with the .old suffix, --rollback puts it back.`,
+		Example: `
+  Update eke to the latest release:
+  $ eke self-update
+
+  Install a given version:
+  $ eke self-update --version 1.3.0
+
+  Put back the binary replaced by the last update:
+  $ eke self-update --rollback`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exe, err := selfupdate.Executable()
+			if err != nil {
+				return fmt.Errorf("cannot find the eke executable: %v", err)
+			}
+			if rollback {
+				if err := selfupdate.Rollback(exe); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s has been rolled back\n", exe)
+				return nil
+			}
+
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+			client, err := selfupdate.NewClient(c.CmdConfig.Releases.IndexURL, c.CmdConfig.Releases.PublicKeys)
+			if err != nil {
+				return err
+			}
+			index, err := client.Index()
+			if err != nil {
+				return err
+			}
+
+			current, currentErr := semver.ParseTolerant(build.Version)
+			var release selfupdate.Release
+			var target semver.Version
+			if version != "" {
+				if target, err = semver.ParseTolerant(version); err != nil {
+					return fmt.Errorf("invalid version %q: %v", version, err)
+				}
+				if release, err = index.Find(target); err != nil {
+					return err
+				}
+			} else {
+				release, target, err = index.Latest(currentErr == nil && len(current.Pre) > 0)
+				if err != nil {
+					return err
+				}
+				if currentErr == nil && !target.GT(current) {
+					fmt.Fprintf(cmd.OutOrStdout(), "eke %s is up to date\n", build.Version)
+					return nil
+				}
+			}
+			if currentErr == nil && target.Equals(current) {
+				fmt.Fprintf(cmd.OutOrStdout(), "eke %s is already installed\n", build.Version)
+				return nil
+			}
+
+			// the update is renamed over the executable, it has to be on
+			// the same file system
+			update := filepath.Join(filepath.Dir(exe), "."+filepath.Base(exe)+".update")
+			defer os.Remove(update)
+			if err := client.Download(release, runtime.GOOS, runtime.GOARCH, update); err != nil {
+				return err
+			}
+			if err := selfupdate.Replace(exe, update); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "eke updated to %s, the previous binary is kept at %s\n", release.Version, exe+selfupdate.RollbackSuffix)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&version, "version", "", "version to install instead of the latest release")
+	cmd.Flags().BoolVar(&rollback, "rollback", false, "put back the binary replaced by the last update")
+	return cmd
+}
@@ -2,10 +2,8 @@ package showconfig
 
 import (
 	"eke/pkg/config"
-	"fmt"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 type CmdOpts config.CLIOptions
@@ -13,15 +11,22 @@ type CmdOpts config.CLIOptions
 func NewShowconfigCmd() *cobra.Command {
 
 	cmd := &cobra.Command{
-		Use:   "showconfig",
-		Short: "Show Cmd Config",
+		Use:        "showconfig",
+		Short:      "Show Cmd Config",
+		Deprecated: "use eke config view instead",
 
-		Run: func(cmd *cobra.Command, args []string) {
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
 			c := CmdOpts(config.GetCmdOpts())
-			configdata, _ := yaml.Marshal(c.CmdConfig)
-			fmt.Println(string(configdata))
+			return printer.Print(cmd.OutOrStdout(), c.CmdConfig)
 		},
 	}
+	config.AddOutputFlag(cmd)
 	return cmd
 
 }
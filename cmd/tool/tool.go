@@ -0,0 +1,111 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"eke/cmd/kubectl"
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/complete"
+	"eke/internal/kubectlcmd/finder"
+	"eke/internal/kubectlcmd/osexec"
+	"eke/internal/kubectlcmd/tool"
+	"eke/internal/pkg/debug"
+	"eke/internal/pkg/logging"
+	"eke/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+// logger writes the messages of the kubectl subsystem
+var logger = logging.For(logging.Kubectl)
+
+type CmdOpts config.CLIOptions
+
+// NewToolCmd creates a new `eke tool` cobra command
+func NewToolCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tool <name> [tool arguments]",
+		Short: "Run a cluster tool, like helm or kustomize, with the version matching the cluster",
+		Long: `eke tool runs the binary of a cluster tool compatible with the version of the
+API server, downloading it when needed. Tools are described in the tools section
+of eke.cmd.yaml, kubectl is built in.`,
+		Example: `
+  List the configured tools:
+  $ eke tool
+
+  Run helm against the current context:
+  $ eke tool helm list -A
+
+  Run kubectl, like eke kubectl does:
+  $ eke tool kubectl get pods -n kube-system`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if c.CmdConfig == nil {
+				return errors.New("cannot load eke.cmd.yaml")
+			}
+
+			if len(args) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), tool.KubectlName+" (built in)")
+				for _, t := range c.CmdConfig.Tools {
+					fmt.Fprintln(cmd.OutOrStdout(), t.Name)
+				}
+				return nil
+			}
+
+			t, err := tool.Lookup(*c.CmdConfig, args[0])
+			if err != nil {
+				return err
+			}
+			if t.Builtin {
+				kubectl.ExecKubectl(c.CmdConfig.EkeKubectlConfig, c.CmdConfig.Profile, args[1:])
+				return nil
+			}
+			return execTool(c, t, args[1:])
+		},
+	}
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		c := CmdOpts(config.GetCmdOpts())
+		if len(args) > 0 || c.CmdConfig == nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		names := []string{tool.KubectlName}
+		for _, t := range c.CmdConfig.Tools {
+			names = append(names, t.Name)
+		}
+		return complete.Matching(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	// everything after the name of the tool belongs to the tool
+	cmd.Flags().SetInterspersed(false)
+	return cmd
+}
+
+func execTool(c CmdOpts, t *tool.Descriptor, args []string) error {
+	kubectlConfig := c.CmdConfig.EkeKubectlConfig
+
+	f := tool.NewFinder(t)
+	f.SearchPATH = kubectlConfig.SearchPATH
+	f.Prober = tool.NewProber(filepath.Join(common.CacheDir(), tool.VersionCacheFile))
+
+	// the server version is found out like kubectl does, including its
+	// cache and its fallbacks when the server cannot be reached
+	server := finder.NewVersioner(
+		finder.NewKubectlFinder("", kubectlConfig.SystemPath),
+		kubectlConfig,
+		t.ServerArgs(args))
+
+	bin, err := tool.NewSelector(t, f, server, kubectlConfig.Verification).
+		BinaryToUse(int64(kubectlConfig.Timeout), kubectlConfig.AllowDownload)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Running %s %s", bin.Path, bin.Version)
+	debug.StartStep(debug.StepExec, bin.Path)
+	debug.Flush()
+	childArgs := append([]string{bin.Path}, args...)
+	return osexec.Exec(bin.Path, childArgs, os.Environ())
+}
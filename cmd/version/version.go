@@ -16,25 +16,56 @@ limitations under the License.
 package version
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 
+	"eke/internal/pkg/selfupdate"
 	"eke/pkg/build"
+	"eke/pkg/config"
+	"eke/pkg/config/cmdconfig"
 
+	"github.com/blang/semver/v4"
 	"github.com/spf13/cobra"
 )
 
 var (
 	all   bool
 	isJsn bool
+	check bool
 )
 
 func NewVersionCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print the eke version",
+		Example: `
+  Print the version of eke:
+  $ eke version
+
+  Compare it to the latest release of the index set in eke.cmd.yaml:
+  $ eke version --check`,
+		SilenceUsage: true,
+		// the error is printed once by Execute
+		SilenceErrors: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if isJsn {
+				config.OutputFormat = "json"
+			}
+			printer, err := config.Printer()
+			if err != nil {
+				return err
+			}
+
+			if check {
+				result, err := checkVersion(config.GetCmdOpts().CmdConfig)
+				if err != nil {
+					return err
+				}
+				return printer.Print(cmd.OutOrStdout(), result)
+			}
 
-		Run: func(cmd *cobra.Command, args []string) {
 			info := versionInfo{
 				Version:      build.Version,
 				Runc:         build.RuncVersion,
@@ -44,14 +75,16 @@ func NewVersionCmd() *cobra.Command {
 				Etcd:         build.EtcdVersion,
 				Konnectivity: build.KonnectivityVersion,
 			}
-
-			info.String()
+			return printer.Print(cmd.OutOrStdout(), info)
 		},
 	}
 
 	// append flags
 	cmd.PersistentFlags().BoolVarP(&all, "all", "a", false, "use to print all eke version info")
 	cmd.PersistentFlags().BoolVarP(&isJsn, "json", "j", false, "use to print all eke version info in json")
+	cmd.PersistentFlags().MarkDeprecated("json", "use -o json instead")
+	cmd.Flags().BoolVar(&check, "check", false, "compare the version of eke to the latest one of the release index set in eke.cmd.yaml")
+	config.AddOutputFlag(cmd)
 	return cmd
 }
 
@@ -65,19 +98,69 @@ type versionInfo struct {
 	Konnectivity string `json:"konnectivity,omitempty"`
 }
 
-func (v versionInfo) String() {
-	if all {
-		fmt.Println("eke :", v.Version)
-		fmt.Println("runc :", v.Runc)
-		fmt.Println("containerd :", v.Containerd)
-		fmt.Println("kubernetes :", v.Kubernetes)
-		fmt.Println("kine :", v.Kine)
-		fmt.Println("etcd :", v.Etcd)
-		fmt.Println("konnectivity :", v.Konnectivity)
-	} else if isJsn {
-		jsn, _ := json.MarshalIndent(v, "", "   ")
-		fmt.Println(string(jsn))
-	} else {
-		fmt.Println(v.Version)
+// WriteText prints the eke version, or all the versions with --all
+func (v versionInfo) WriteText(w io.Writer) error {
+	if !all {
+		_, err := fmt.Fprintln(w, v.Version)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "eke : %s\nrunc : %s\ncontainerd : %s\nkubernetes : %s\nkine : %s\netcd : %s\nkonnectivity : %s\n",
+		v.Version, v.Runc, v.Containerd, v.Kubernetes, v.Kine, v.Etcd, v.Konnectivity)
+	return err
+}
+
+// checkResult compares the version of eke to the latest release
+type checkResult struct {
+	Current         string `json:"current"`
+	Latest          string `json:"latest"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+	Notes           string `json:"notes,omitempty"`
+	// versioned tells whether the version of eke is a semantic version,
+	// development builds are not compared
+	versioned bool
+}
+
+func (r checkResult) WriteText(w io.Writer) error {
+	var err error
+	switch {
+	case r.UpdateAvailable:
+		_, err = fmt.Fprintf(w, "eke %s is available, this is eke %s, run eke self-update to update\n", r.Latest, r.Current)
+	case !r.versioned:
+		_, err = fmt.Fprintf(w, "the latest release is eke %s, this build of eke has no release version\n", r.Latest)
+	default:
+		_, err = fmt.Fprintf(w, "eke %s is up to date\n", r.Current)
+	}
+	if err == nil && r.UpdateAvailable && r.Notes != "" {
+		_, err = fmt.Fprintln(w, r.Notes)
+	}
+	return err
+}
+
+// checkVersion compares the version of eke to the latest release of the
+// index, the pre-releases are only considered by the pre-release builds
+func checkVersion(cmdConfig *cmdconfig.EkeCmdConfig) (checkResult, error) {
+	if cmdConfig == nil {
+		return checkResult{}, errors.New("cannot load eke.cmd.yaml")
+	}
+	client, err := selfupdate.NewClient(cmdConfig.Releases.IndexURL, cmdConfig.Releases.PublicKeys)
+	if err != nil {
+		return checkResult{}, err
+	}
+	index, err := client.Index()
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	current, currentErr := semver.ParseTolerant(build.Version)
+	release, latest, err := index.Latest(currentErr == nil && len(current.Pre) > 0)
+	if err != nil {
+		return checkResult{}, err
 	}
+	return checkResult{
+		Current:         build.Version,
+		Latest:          release.Version,
+		UpdateAvailable: currentErr == nil && latest.GT(current),
+		Notes:           release.Notes,
+		versioned:       currentErr == nil,
+	}, nil
 }
@@ -0,0 +1,187 @@
+package alias
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parameter matches the references to the arguments of an alias: $1, ${1},
+// ${1:-default} and $@
+var parameter = regexp.MustCompile(`\$(?:(\d+)|\{(\d+)(?::-([^}]*))?\}|@)`)
+
+// Alias is a name standing for kubectl arguments. The arguments given to
+// the alias are referred to with $1, $2... or ${1:-default} to provide a
+// default. $@, which must be a whole word, stands for the arguments that
+// are not referred to, they are appended when it is missing
+type Alias struct {
+	Name     string
+	Template string
+	words    []string
+	// required is the number of arguments without default
+	required int
+	// used is the highest argument referred to
+	used int
+}
+
+// New parses the template of the alias name
+func New(name, template string) (*Alias, error) {
+	words, err := split(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alias %s: %v", name, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("invalid alias %s: it expands to nothing", name)
+	}
+
+	a := &Alias{Name: name, Template: template, words: words}
+	for _, word := range words {
+		for _, m := range parameter.FindAllStringSubmatch(word, -1) {
+			if m[0] == "$@" {
+				if word != "$@" {
+					return nil, fmt.Errorf("invalid alias %s: $@ must be a whole word", name)
+				}
+				continue
+			}
+			n, hasDefault := position(m)
+			if n == 0 {
+				return nil, fmt.Errorf("invalid alias %s: arguments are numbered from $1", name)
+			}
+			if n > a.used {
+				a.used = n
+			}
+			if !hasDefault && n > a.required {
+				a.required = n
+			}
+		}
+	}
+	return a, nil
+}
+
+// position returns the argument a parameter refers to and whether it has
+// a default
+func position(m []string) (int, bool) {
+	if m[1] != "" {
+		n, _ := strconv.Atoi(m[1])
+		return n, false
+	}
+	n, _ := strconv.Atoi(m[2])
+	return n, strings.Contains(m[0], ":-")
+}
+
+// Expand returns the kubectl arguments the alias stands for when invoked
+// with args. Substituted values are never split into several arguments
+func (a *Alias) Expand(args []string) ([]string, error) {
+	if len(args) < a.required {
+		return nil, fmt.Errorf("alias %s expects at least %d argument(s): %s", a.Name, a.required, a.Template)
+	}
+
+	var rest []string
+	if len(args) > a.used {
+		rest = args[a.used:]
+	}
+
+	var expanded []string
+	restUsed := false
+	for _, word := range a.words {
+		if word == "$@" {
+			expanded = append(expanded, rest...)
+			restUsed = true
+			continue
+		}
+		expanded = append(expanded, parameter.ReplaceAllStringFunc(word, func(ref string) string {
+			n, _ := position(parameter.FindStringSubmatch(ref))
+			if n <= len(args) {
+				return args[n-1]
+			}
+			return parameter.FindStringSubmatch(ref)[3]
+		}))
+	}
+	if !restUsed {
+		expanded = append(expanded, rest...)
+	}
+	return expanded, nil
+}
+
+// Set holds the aliases by name
+type Set map[string]*Alias
+
+// NewSet parses the aliases of the configuration. Names are case
+// insensitive, like the keys of the configuration
+func NewSet(aliases map[string]string) (Set, error) {
+	s := Set{}
+	for name, template := range aliases {
+		a, err := New(strings.ToLower(name), template)
+		if err != nil {
+			return nil, err
+		}
+		s[a.Name] = a
+	}
+	return s, nil
+}
+
+// Expand replaces the alias args start with, if any, by the arguments it
+// stands for. Expanded arguments are not expanded again
+func (s Set) Expand(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+	a, ok := s[strings.ToLower(args[0])]
+	if !ok {
+		return args, nil
+	}
+	return a.Expand(args[1:])
+}
+
+// Sorted returns the aliases sorted by name
+func (s Set) Sorted() []*Alias {
+	aliases := make([]*Alias, 0, len(s))
+	for _, a := range s {
+		aliases = append(aliases, a)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Name < aliases[j].Name
+	})
+	return aliases
+}
+
+// split breaks the template into words separated by spaces, single and
+// double quotes group words
+func split(template string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	for _, r := range template {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
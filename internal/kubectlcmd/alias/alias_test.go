@@ -0,0 +1,151 @@
+package alias
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		args     []string
+		expected []string
+		errMsg   string
+	}{
+		{
+			name:     "positional",
+			template: "get pods -l app=$1 -o wide",
+			args:     []string{"web"},
+			expected: []string{"get", "pods", "-l", "app=web", "-o", "wide"},
+		},
+		{
+			name:     "extra arguments are appended",
+			template: "get pods -l app=$1",
+			args:     []string{"web", "-n", "shop"},
+			expected: []string{"get", "pods", "-l", "app=web", "-n", "shop"},
+		},
+		{
+			name:     "remaining arguments placed",
+			template: "logs -f $@ --tail ${1:-10}",
+			args:     []string{"20", "deploy/web"},
+			expected: []string{"logs", "-f", "deploy/web", "--tail", "20"},
+		},
+		{
+			name:     "default",
+			template: "get pods -n ${2:-default} -l app=$1",
+			args:     []string{"web"},
+			expected: []string{"get", "pods", "-n", "default", "-l", "app=web"},
+		},
+		{
+			name:     "default overridden",
+			template: "get pods -n ${2:-default} -l app=${1}",
+			args:     []string{"web", "shop"},
+			expected: []string{"get", "pods", "-n", "shop", "-l", "app=web"},
+		},
+		{
+			name:     "values are not split",
+			template: "annotate $1 note=$2",
+			args:     []string{"pod/web", "hello world"},
+			expected: []string{"annotate", "pod/web", "note=hello world"},
+		},
+		{
+			name:     "quotes",
+			template: `get pods -o 'custom-columns=NAME:.metadata.name,NODE:.spec.nodeName' -o "jsonpath={.items[*]}"`,
+			expected: []string{"get", "pods", "-o", "custom-columns=NAME:.metadata.name,NODE:.spec.nodeName", "-o", "jsonpath={.items[*]}"},
+		},
+		{
+			name:     "go templates are kept",
+			template: "get pods -o go-template={{$x := 1}}",
+			expected: []string{"get", "pods", "-o", "go-template={{$x", ":=", "1}}"},
+		},
+		{
+			name:     "missing argument",
+			template: "get pods -l app=$1 -n $2",
+			args:     []string{"web"},
+			errMsg:   "expects at least 2 argument(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New("test", tt.template)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			actual, err := a.Expand(tt.args)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(actual, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Got %q instead of %q", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		template string
+		errMsg   string
+	}{
+		{"", "expands to nothing"},
+		{"get pods -l 'app=$1", "unterminated"},
+		{"get pods $0", "numbered from $1"},
+		{"get pods -l app=$@", "whole word"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := New("test", tt.template)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	s, err := NewSet(map[string]string{
+		"podsOf": "get pods -l app=$1 -o wide",
+		"get":    "get -o wide",
+		"nodes":  "get nodes",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"podsof", "web"}, []string{"get", "pods", "-l", "app=web", "-o", "wide"}},
+		{[]string{"PodsOf", "web"}, []string{"get", "pods", "-l", "app=web", "-o", "wide"}},
+		// expansions are not expanded again
+		{[]string{"get", "nodes"}, []string{"get", "-o", "wide", "nodes"}},
+		{[]string{"describe", "podsof"}, []string{"describe", "podsof"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		actual, err := s.Expand(tt.args)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Join(actual, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Got %q instead of %q", actual, tt.expected)
+		}
+	}
+
+	var names []string
+	for _, a := range s.Sorted() {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, ",") != "get,nodes,podsof" {
+		t.Errorf("Got aliases %v", names)
+	}
+}
@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"eke/internal/pkg/logging"
+
+	"bufio"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// logger writes the messages of the kubectl subsystem
+var logger = logging.For(logging.Kubectl)
+
+// LogFile is the name of the file, inside of the audit directory, the
+// commands are appended to. Rotated files get a numeric suffix, the
+// higher the older
+const LogFile = "audit.log"
+
+// Defaults of NewLog
+const (
+	DefaultMaxSize  = 10
+	DefaultMaxFiles = 5
+)
+
+// Record describes a kubectl command run through the wrapper
+type Record struct {
+	Time           time.Time `json:"time"`
+	User           string    `json:"user,omitempty"`
+	Context        string    `json:"context,omitempty"`
+	Server         string    `json:"server,omitempty"`
+	Namespace      string    `json:"namespace,omitempty"`
+	KubectlVersion string    `json:"kubectlVersion,omitempty"`
+	Args           []string  `json:"args"`
+}
+
+// Log appends the records to a JSON-lines file which is rotated by size
+type Log struct {
+	Dir string
+	// MaxSize is the size, in bytes, above which the file is rotated. The
+	// file is never rotated when zero
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int
+}
+
+// NewLog returns a Log writing to dir. maxSize is expressed in MiB, the
+// defaults are used for the values lower than one
+func NewLog(dir string, maxSize, maxFiles int) *Log {
+	if maxSize < 1 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles < 1 {
+		maxFiles = DefaultMaxFiles
+	}
+	return &Log{
+		Dir:      dir,
+		MaxSize:  int64(maxSize) * 1024 * 1024,
+		MaxFiles: maxFiles,
+	}
+}
+
+// writer returns the rotating file the records are appended to
+func (l *Log) writer() *logging.File {
+	return &logging.File{
+		Path:     filepath.Join(l.Dir, LogFile),
+		MaxSize:  l.MaxSize,
+		MaxFiles: l.MaxFiles,
+	}
+}
+
+func (l *Log) file(n int) string {
+	return l.writer().Name(n)
+}
+
+// Append writes r at the end of the log, rotating the log first when it
+// is too big
+func (l *Log) Append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	// a single write keeps the lines of concurrent processes apart
+	_, err = l.writer().Write(append(data, '\n'))
+	return err
+}
+
+// Filter selects the records shown by Read
+type Filter struct {
+	// Since drops the older records, when not zero
+	Since time.Time
+	// Context is a glob pattern matched against the context name, it
+	// matches everything when empty
+	Context string
+}
+
+func (f Filter) matches(r Record) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if f.Context == "" {
+		return true
+	}
+	ok, _ := path.Match(f.Context, r.Context)
+	return ok
+}
+
+// Read returns the records of the log, the rotated ones included, matching
+// the filter from the oldest to the newest. Malformed lines are skipped
+func (l *Log) Read(f Filter) ([]Record, error) {
+	if _, err := path.Match(f.Context, ""); err != nil {
+		return nil, fmt.Errorf("invalid context pattern %q: %v", f.Context, err)
+	}
+
+	var records []Record
+	for n := l.rotatedFiles(); n >= 0; n-- {
+		file, err := os.Open(l.file(n))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				logger.Warnf("Skipping line %d of %s: %v", line, l.file(n), err)
+				continue
+			}
+			if f.matches(r) {
+				records = append(records, r)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// rotatedFiles returns the highest suffix of the rotated files, they can
+// outnumber MaxFiles when it has been lowered
+func (l *Log) rotatedFiles() int {
+	n := l.MaxFiles
+	for {
+		if _, err := os.Stat(l.file(n + 1)); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+// CertificateUser returns the common name of the PEM certificate at path
+func CertificateUser(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", errors.New("no certificate found in " + path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	return cert.Subject.CommonName, nil
+}
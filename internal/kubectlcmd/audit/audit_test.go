@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	util "eke/internal/util/utilityFunctions"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	l := &Log{Dir: filepath.Join(t.TempDir(), "audit"), MaxFiles: 2}
+	start := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	for i, context := range []string{"dev", "prod-eu", "prod-us", "dev"} {
+		r := Record{Time: start.Add(time.Duration(i) * time.Hour), Context: context, Args: []string{"get", "pods"}}
+		if err := l.Append(r); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"everything", Filter{}, []string{"dev", "prod-eu", "prod-us", "dev"}},
+		{"context", Filter{Context: "prod-*"}, []string{"prod-eu", "prod-us"}},
+		{"since", Filter{Since: start.Add(90 * time.Minute)}, []string{"prod-us", "dev"}},
+		{"since and context", Filter{Since: start.Add(90 * time.Minute), Context: "prod-*"}, []string{"prod-us"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := l.Read(tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var contexts []string
+			for _, r := range records {
+				contexts = append(contexts, r.Context)
+			}
+			if strings.Join(contexts, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Got %v instead of %v", contexts, tt.expected)
+			}
+		})
+	}
+
+	info, err := os.Stat(l.file(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Got mode %v instead of 0600", info.Mode().Perm())
+	}
+}
+
+func TestRotation(t *testing.T) {
+	l := &Log{Dir: t.TempDir(), MaxSize: 1, MaxFiles: 2}
+	for i := 0; i < 5; i++ {
+		r := Record{Time: time.Unix(int64(i), 0).UTC(), Args: []string{"get", "pods"}}
+		if err := l.Append(r); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// every record goes to its own file, the oldest ones are dropped
+	for n := 0; n <= 2; n++ {
+		if _, err := os.Stat(l.file(n)); err != nil {
+			t.Errorf("Expected %s to exist: %v", l.file(n), err)
+		}
+	}
+	if _, err := os.Stat(l.file(3)); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", l.file(3))
+	}
+
+	records, err := l.Read(Filter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 3 || records[0].Time.Unix() != 2 || records[2].Time.Unix() != 4 {
+		t.Errorf("Expected the last 3 records in order, got %+v", records)
+	}
+}
+
+func TestReadSkipsMalformedLines(t *testing.T) {
+	l := &Log{Dir: t.TempDir()}
+	content := "{\"time\":\"2022-03-04T05:06:07Z\",\"args\":[\"get\"]}\nnot json\n"
+	if err := ioutil.WriteFile(l.file(0), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := l.Read(Filter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("Expected 1 record, got %+v", records)
+	}
+
+	if _, err := l.Read(Filter{Context: "prod-["}); err == nil {
+		t.Error("Expected an error with an invalid pattern")
+	}
+}
+
+func TestCertificateUser(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "alice", Organization: []string{"developers"}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), util.ClientCertFile)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	user, err := CertificateUser(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user != "alice" {
+		t.Errorf("Got %s instead of alice", user)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CertificateUser(path); err == nil {
+		t.Error("Expected an error without certificate")
+	}
+}
+
+func TestNewLogDefaults(t *testing.T) {
+	l := NewLog("/tmp/audit", 0, -1)
+	if l.MaxSize != DefaultMaxSize*1024*1024 || l.MaxFiles != DefaultMaxFiles {
+		t.Errorf("Unexpected defaults %+v", l)
+	}
+
+	l = NewLog("/tmp/audit", 2, 3)
+	if l.MaxSize != 2*1024*1024 || l.MaxFiles != 3 {
+		t.Errorf("Unexpected settings %+v", l)
+	}
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the values removed from the records
+const Redacted = "REDACTED"
+
+// DefaultRedactedFlags are the flags whose values are never recorded
+var DefaultRedactedFlags = []string{
+	"--token",
+	"--password",
+	"--*-password",
+	"--client-key",
+	"--from-literal",
+}
+
+// Redact returns a copy of args where the values of the flags matching
+// one of the glob patterns are replaced by Redacted. The value is either
+// after an equal sign or the next argument, unless that one is a flag:
+// the flag is then used without value, like a boolean, and the next flag
+// is left to its own pattern. Arguments after "--" are kept as is
+func Redact(args []string, patterns []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		arg := redacted[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name := arg
+		eq := strings.Index(arg, "=")
+		if eq >= 0 {
+			name = arg[:eq]
+		}
+		if !redactedFlag(name, patterns) {
+			continue
+		}
+		if eq >= 0 {
+			redacted[i] = name + "=" + Redacted
+		} else if i+1 < len(redacted) && !isFlag(redacted[i+1]) {
+			i++
+			redacted[i] = Redacted
+		}
+	}
+	return redacted
+}
+
+// isFlag tells whether arg is a flag rather than a value, "-" alone
+// usually stands for stdin
+func isFlag(arg string) bool {
+	return len(arg) > 1 && strings.HasPrefix(arg, "-")
+}
+
+func redactedFlag(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSince returns the time described by value, either a duration
+// before now, like 90m, 12h, 1d or 2w, a date (2006-01-02) or an RFC3339
+// timestamp
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); strings.HasSuffix(value, suffix) && err == nil {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a duration like 12h or 1d, a date or an RFC3339 timestamp", value)
+}
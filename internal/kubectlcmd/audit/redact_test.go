@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		patterns []string
+		expected string
+	}{
+		{
+			name:     "separate value",
+			args:     []string{"get", "pods", "--token", "secret"},
+			patterns: DefaultRedactedFlags,
+			expected: "get pods --token REDACTED",
+		},
+		{
+			name:     "equal sign",
+			args:     []string{"--token=secret", "get", "pods"},
+			patterns: DefaultRedactedFlags,
+			expected: "--token=REDACTED get pods",
+		},
+		{
+			name:     "glob pattern",
+			args:     []string{"create", "secret", "docker-registry", "reg", "--docker-password=secret", "--docker-username", "bob"},
+			patterns: DefaultRedactedFlags,
+			expected: "create secret docker-registry reg --docker-password=REDACTED --docker-username bob",
+		},
+		{
+			name:     "repeated flag",
+			args:     []string{"create", "secret", "generic", "s", "--from-literal", "a=1", "--from-literal=b=2"},
+			patterns: DefaultRedactedFlags,
+			expected: "create secret generic s --from-literal REDACTED --from-literal=REDACTED",
+		},
+		{
+			name:     "configured flag",
+			args:     []string{"get", "pods", "-l", "team=red", "--as", "admin"},
+			patterns: []string{"--as", "-l"},
+			expected: "get pods -l REDACTED --as REDACTED",
+		},
+		{
+			name:     "after double dash",
+			args:     []string{"exec", "pod", "--", "login", "--password", "secret"},
+			patterns: DefaultRedactedFlags,
+			expected: "exec pod -- login --password secret",
+		},
+		{
+			name:     "boolean form",
+			args:     []string{"get", "pods", "--token", "--as", "admin", "-n", "prod"},
+			patterns: []string{"--token", "--as"},
+			expected: "get pods --token --as REDACTED -n prod",
+		},
+		{
+			name:     "boolean form before double dash",
+			args:     []string{"exec", "pod", "--password", "--", "login", "secret"},
+			patterns: DefaultRedactedFlags,
+			expected: "exec pod --password -- login secret",
+		},
+		{
+			name:     "missing value",
+			args:     []string{"get", "pods", "--token"},
+			patterns: DefaultRedactedFlags,
+			expected: "get pods --token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := strings.Join(tt.args, " ")
+			actual := strings.Join(Redact(tt.args, tt.patterns), " ")
+			if actual != tt.expected {
+				t.Errorf("Got %q instead of %q", actual, tt.expected)
+			}
+			if strings.Join(tt.args, " ") != original {
+				t.Error("The arguments should not be modified")
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2022, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+		errMsg   string
+	}{
+		{value: "90m", expected: now.Add(-90 * time.Minute)},
+		{value: "1d", expected: now.Add(-24 * time.Hour)},
+		{value: "2w", expected: now.Add(-14 * 24 * time.Hour)},
+		{value: "2022-03-01", expected: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2022-03-01T08:00:00Z", expected: time.Date(2022, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{value: "yesterday", errMsg: "invalid time"},
+		{value: "d", errMsg: "invalid time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			actual, err := ParseSince(tt.value, now)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !actual.Equal(tt.expected) {
+				t.Errorf("Got %s instead of %s", actual, tt.expected)
+			}
+		})
+	}
+}
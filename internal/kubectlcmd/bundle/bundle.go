@@ -0,0 +1,241 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/manifest"
+	"eke/internal/pkg/archive"
+
+	"github.com/blang/semver/v4"
+)
+
+// ManifestFile is the name of the checksum manifest stored at the root of
+// a bundle
+const ManifestFile = "SHA256SUMS"
+
+type kubectlDownloader interface {
+	GetKubectlBinaryFor(version semver.Version, platform common.Platform, destination string) error
+}
+
+// Binary is a kubectl binary stored inside of a bundle
+type Binary struct {
+	Version  semver.Version
+	Platform common.Platform
+	// Path is where the binary has been installed, it is empty for the
+	// binaries that have only been added to a bundle
+	Path string
+}
+
+// ParseVersions parses the versions to bundle. A minor version, like 1.22,
+// stands for its latest patch release, given by latestPatch
+func ParseVersions(args []string, latestPatch func(major, minor uint64) (semver.Version, error)) ([]semver.Version, error) {
+	var versions []semver.Version
+	for _, arg := range args {
+		v, err := semver.ParseTolerant(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: %v", arg, err)
+		}
+		if strings.Count(strings.TrimPrefix(strings.TrimSpace(arg), "v"), ".") == 1 {
+			if v, err = latestPatch(v.Major, v.Minor); err != nil {
+				return nil, fmt.Errorf("cannot find the latest patch release of kubectl %s, give the full version: %v", arg, err)
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Create downloads the kubectl binaries of the given versions and platforms
+// and writes them to output as a tar.gz archive. The binaries are laid out
+// like in the checksum manifests, e.g. v1.22.3/linux/amd64/kubectl, the
+// manifest itself is stored as SHA256SUMS
+func Create(d kubectlDownloader, versions []semver.Version, platforms []common.Platform, output io.Writer) ([]Binary, error) {
+	if len(versions) == 0 || len(platforms) == 0 {
+		return nil, fmt.Errorf("at least one version and one platform are required to create a bundle")
+	}
+
+	dir, err := ioutil.TempDir("", "eke-bundle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	m := manifest.Manifest{}
+	var bins []Binary
+	for _, v := range versions {
+		for _, p := range platforms {
+			entry := manifest.KubectlEntry(v, p.OS, p.Arch)
+			destination := filepath.Join(dir, filepath.FromSlash(entry))
+			if err := d.GetKubectlBinaryFor(v, p, destination); err != nil {
+				return nil, fmt.Errorf("cannot download kubectl %s for %s: %v", v, p, err)
+			}
+
+			checksum, err := manifest.FileChecksum(destination)
+			if err != nil {
+				return nil, err
+			}
+			m[entry] = checksum
+			bins = append(bins, Binary{Version: v, Platform: p})
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, ManifestFile), m.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	return bins, archive.Create(output, dir)
+}
+
+// Import unpacks the bundle read from input and installs its binaries
+// inside of the directory returned by installDir for their platform.
+// Nothing is installed unless every file of the bundle matches the
+// checksum manifest
+func Import(input io.Reader, installDir func(common.Platform) string) ([]Binary, error) {
+	dir, err := ioutil.TempDir("", "eke-bundle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := archive.Extract(input, dir); err != nil {
+		return nil, fmt.Errorf("cannot unpack the bundle: %v", err)
+	}
+
+	bins, err := verify(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, b := range bins {
+		entry := manifest.KubectlEntry(b.Version, b.Platform.OS, b.Platform.Arch)
+		destination := filepath.Join(
+			installDir(b.Platform),
+			common.BuildKubectlNameForPlatformLocalBin(b.Version, b.Platform))
+		if err := install(filepath.Join(dir, filepath.FromSlash(entry)), destination); err != nil {
+			return nil, fmt.Errorf("cannot install kubectl %s for %s: %v", b.Version, b.Platform, err)
+		}
+		bins[i].Path = destination
+	}
+	return bins, nil
+}
+
+// verify checks the unpacked bundle in dir against its manifest, every
+// file must be listed and every listed file must be present
+func verify(dir string) ([]Binary, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("the bundle has no %s manifest: %v", ManifestFile, err)
+	}
+	m, err := manifest.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if name == ManifestFile {
+			return nil
+		}
+
+		expected, found := m[name]
+		if !found {
+			return fmt.Errorf("%s is not listed in the bundle manifest", name)
+		}
+		actual, err := manifest.FileChecksum(path)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return &common.ShaMismatchError{URL: name, ShaExpected: expected, ShaActual: actual}
+		}
+		seen[name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bins []Binary
+	for name := range m {
+		if !seen[name] {
+			return nil, fmt.Errorf("%s is listed in the bundle manifest but missing from the bundle", name)
+		}
+		b, err := parseEntry(name)
+		if err != nil {
+			return nil, err
+		}
+		bins = append(bins, b)
+	}
+	sort.Slice(bins, func(i, j int) bool {
+		if !bins[i].Version.Equals(bins[j].Version) {
+			return bins[i].Version.LT(bins[j].Version)
+		}
+		return bins[i].Platform.String() < bins[j].Platform.String()
+	})
+	return bins, nil
+}
+
+// parseEntry parses manifest entries like v1.22.3/linux/amd64/kubectl
+func parseEntry(name string) (Binary, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 {
+		return Binary{}, fmt.Errorf("unexpected file %s in the bundle", name)
+	}
+	v, err := semver.ParseTolerant(parts[0])
+	if err != nil {
+		return Binary{}, fmt.Errorf("unexpected file %s in the bundle: %v", name, err)
+	}
+	b := Binary{Version: v, Platform: common.Platform{OS: parts[1], Arch: parts[2]}}
+	if manifest.KubectlEntry(b.Version, b.Platform.OS, b.Platform.Arch) != name {
+		return Binary{}, fmt.Errorf("unexpected file %s in the bundle", name)
+	}
+	return b, nil
+}
+
+// install copies the binary next to its destination and renames it, so
+// that a partially written binary is never picked up
+func install(src, destination string) error {
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := destination + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, destination); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/manifest"
+
+	"github.com/blang/semver/v4"
+)
+
+type mockDownloader struct {
+	getKubectlBinaryFor func(version semver.Version, platform common.Platform, destination string) error
+}
+
+func (m *mockDownloader) GetKubectlBinaryFor(version semver.Version, platform common.Platform, destination string) error {
+	return m.getKubectlBinaryFor(version, platform, destination)
+}
+
+func fakeBinary(version semver.Version, platform common.Platform) string {
+	return fmt.Sprintf("#!/bin/sh\necho kubectl %s %s\n", version, platform)
+}
+
+var fakeDownloader = &mockDownloader{
+	getKubectlBinaryFor: func(version semver.Version, platform common.Platform, destination string) error {
+		if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(destination, []byte(fakeBinary(version, platform)), 0755)
+	},
+}
+
+type tarEntry struct {
+	name    string
+	content string
+}
+
+// writeBundle builds a bundle by hand, entries are stored as they are
+func writeBundle(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	var b bytes.Buffer
+	gzw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gzw)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+		if strings.HasSuffix(e.name, "/") {
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &b
+}
+
+func installDirIn(root string) func(common.Platform) string {
+	return func(p common.Platform) string {
+		return filepath.Join(root, p.OS+"-"+p.Arch)
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	latestPatch := func(major, minor uint64) (semver.Version, error) {
+		if minor == 21 {
+			return semver.Version{}, fmt.Errorf("no stable-%d.%d.txt", major, minor)
+		}
+		return semver.Version{Major: major, Minor: minor, Patch: 9}, nil
+	}
+
+	versions, err := ParseVersions([]string{"1.22", "v1.23", "1.24.1", "v1.20.0"}, latestPatch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"1.22.9", "1.23.9", "1.24.1", "1.20.0"}
+	if len(versions) != len(expected) {
+		t.Fatalf("Got versions %v instead of %v", versions, expected)
+	}
+	for i, v := range versions {
+		if v.String() != expected[i] {
+			t.Errorf("Got %s instead of %s", v, expected[i])
+		}
+	}
+
+	for _, args := range [][]string{{"1.21"}, {"latest"}} {
+		if _, err := ParseVersions(args, latestPatch); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestCreateAndImport(t *testing.T) {
+	versions := []semver.Version{semver.MustParse("1.22.3"), semver.MustParse("1.23.0")}
+	platforms := []common.Platform{{OS: "linux", Arch: "amd64"}, {OS: "windows", Arch: "amd64"}}
+
+	var b bytes.Buffer
+	created, err := Create(fakeDownloader, versions, platforms, &b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(created) != 4 {
+		t.Fatalf("Expected 4 binaries in the bundle, got %d", len(created))
+	}
+
+	root := t.TempDir()
+	imported, err := Import(&b, installDirIn(root))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(imported) != 4 {
+		t.Fatalf("Expected 4 binaries to be imported, got %d", len(imported))
+	}
+
+	expected := []string{
+		"linux-amd64/kubectl1.22.3",
+		"windows-amd64/kubectl1.22.3.exe",
+		"linux-amd64/kubectl1.23.0",
+		"windows-amd64/kubectl1.23.0.exe",
+	}
+	for i, b := range imported {
+		path := filepath.Join(root, filepath.FromSlash(expected[i]))
+		if b.Path != path {
+			t.Errorf("Got %s instead of %s", b.Path, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected %s to be installed: %v", path, err)
+		}
+		if string(data) != fakeBinary(b.Version, b.Platform) {
+			t.Errorf("Got unexpected content %q for %s", string(data), path)
+		}
+	}
+}
+
+func TestCreateDownloadError(t *testing.T) {
+	d := &mockDownloader{
+		getKubectlBinaryFor: func(version semver.Version, platform common.Platform, destination string) error {
+			return fmt.Errorf("boom")
+		},
+	}
+	var b bytes.Buffer
+	_, err := Create(d, []semver.Version{semver.MustParse("1.22.3")}, []common.Platform{common.HostPlatform()}, &b)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the download error to be reported, got %v", err)
+	}
+}
+
+func TestImportRejectsInvalidBundles(t *testing.T) {
+	good := "#!/bin/sh\necho kubectl\n"
+	sum := func(content string) string {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "f")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		checksum, err := manifest.FileChecksum(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checksum
+	}
+	entry := "v1.22.3/linux/amd64/kubectl"
+	dirs := []tarEntry{{name: "v1.22.3/"}, {name: "v1.22.3/linux/"}, {name: "v1.22.3/linux/amd64/"}}
+
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		errMsg  string
+	}{
+		{
+			name: "checksum mismatch",
+			entries: append(dirs,
+				tarEntry{name: entry, content: "tampered"},
+				tarEntry{name: ManifestFile, content: sum(good) + "  " + entry + "\n"}),
+			errMsg: "SHA mismatch",
+		},
+		{
+			name: "file not in the manifest",
+			entries: append(dirs,
+				tarEntry{name: entry, content: good},
+				tarEntry{name: "v1.22.3/linux/amd64/extra", content: good},
+				tarEntry{name: ManifestFile, content: sum(good) + "  " + entry + "\n"}),
+			errMsg: "not listed",
+		},
+		{
+			name: "file missing from the bundle",
+			entries: append(dirs,
+				tarEntry{name: ManifestFile, content: sum(good) + "  " + entry + "\n"}),
+			errMsg: "missing from the bundle",
+		},
+		{
+			name: "missing manifest",
+			entries: append(dirs,
+				tarEntry{name: entry, content: good}),
+			errMsg: "no SHA256SUMS manifest",
+		},
+		{
+			name: "path traversal",
+			entries: []tarEntry{
+				{name: "../evil", content: good},
+				{name: ManifestFile, content: sum(good) + "  ../evil\n"},
+			},
+			errMsg: "illegal file path",
+		},
+		{
+			name: "unexpected layout",
+			entries: []tarEntry{
+				{name: "kubectl", content: good},
+				{name: ManifestFile, content: sum(good) + "  kubectl\n"},
+			},
+			errMsg: "unexpected file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			_, err := Import(writeBundle(t, tt.entries), installDirIn(root))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.errMsg, err)
+			}
+
+			installed, err := ioutil.ReadDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(installed) != 0 {
+				t.Errorf("Nothing should be installed from an invalid bundle, found %d files", len(installed))
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// BinaryVersion is the version reported by a binary, along with the size
+// and the modification time of the file at that moment. Error is the reason
+// the binary could not report its version, if any
+type BinaryVersion struct {
+	Size    int64          `json:"size"`
+	ModTime time.Time      `json:"modTime"`
+	Version semver.Version `json:"version"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// BinaryVersions caches the version reported by binaries, or their failure
+// to report it, keyed by path. An entry is valid as long as the size and the
+// modification time of the file do not change
+type BinaryVersions struct {
+	Path string
+}
+
+// NewBinaryVersions returns a BinaryVersions cache stored in the given file
+func NewBinaryVersions(path string) *BinaryVersions {
+	return &BinaryVersions{Path: path}
+}
+
+// Get returns the cached entry of the binary at path, info describes the
+// binary as it is now
+func (c *BinaryVersions) Get(path string, info os.FileInfo) (BinaryVersion, bool) {
+	entries, err := c.load()
+	if err != nil {
+		return BinaryVersion{}, false
+	}
+
+	entry, found := entries[path]
+	if !found || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return BinaryVersion{}, false
+	}
+	return entry, true
+}
+
+// Set records the version reported by the binary at path. Entries of the
+// binaries that no longer exist are dropped
+func (c *BinaryVersions) Set(path string, info os.FileInfo, version semver.Version) error {
+	return c.set(path, BinaryVersion{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Version: version,
+	})
+}
+
+// SetError records that the binary at path could not report its version
+func (c *BinaryVersions) SetError(path string, info os.FileInfo, probeErr error) error {
+	return c.set(path, BinaryVersion{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Error:   probeErr.Error(),
+	})
+}
+
+func (c *BinaryVersions) set(path string, entry BinaryVersion) error {
+	entries, err := c.load()
+	if err != nil {
+		// start over when the cache file is corrupted
+		entries = map[string]BinaryVersion{}
+	}
+
+	for p := range entries {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			delete(entries, p)
+		}
+	}
+	entries[path] = entry
+	return writeJSON(c.Path, entries)
+}
+
+func (c *BinaryVersions) load() (map[string]BinaryVersion, error) {
+	entries := map[string]BinaryVersion{}
+
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestBinaryVersions(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := filepath.Join(dir, "kubectl")
+	if err := ioutil.WriteFile(kubectl, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(kubectl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewBinaryVersions(filepath.Join(dir, "cache", "kubectl-versions.json"))
+	if _, found := c.Get(kubectl, info); found {
+		t.Error("Expected empty cache")
+	}
+	if err := c.Set(kubectl, info, semver.MustParse("1.22.3")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// reload from disk
+	c2 := NewBinaryVersions(c.Path)
+	entry, found := c2.Get(kubectl, info)
+	if !found {
+		t.Fatal("Expected cached version")
+	}
+	if !entry.Version.Equals(semver.MustParse("1.22.3")) || entry.Error != "" {
+		t.Errorf("Got %+v instead of 1.22.3", entry)
+	}
+
+	// the binary is replaced
+	if err := ioutil.WriteFile(kubectl, []byte("v2 is bigger"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(kubectl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c2.Get(kubectl, info); found {
+		t.Error("Expected entry to be invalidated by a size change")
+	}
+
+	// the binary is touched
+	if err := c2.Set(kubectl, info, semver.MustParse("1.23.0")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(kubectl, later, later); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(kubectl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c2.Get(kubectl, info); found {
+		t.Error("Expected entry to be invalidated by a mtime change")
+	}
+
+	// the binary cannot report its version
+	if err := c2.SetError(kubectl, info, errors.New("exec format error")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	entry, found = NewBinaryVersions(c.Path).Get(kubectl, info)
+	if !found || entry.Error != "exec format error" {
+		t.Errorf("Expected the failure to be cached, got %+v", entry)
+	}
+}
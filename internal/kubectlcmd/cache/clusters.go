@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// ClustersFile is the name of the file, inside of the cache directory,
+// holding the EWS clusters looked up
+const ClustersFile = "clusters.json"
+
+// Cluster is an EWS cluster eke looked up
+type Cluster struct {
+	Server   string    `json:"server"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// NamedCluster is a cached cluster along with its name
+type NamedCluster struct {
+	Name string `json:"name"`
+	Cluster
+}
+
+// Clusters caches the EWS clusters looked up by `eke kubeconfig init`,
+// keyed by cluster name
+type Clusters struct {
+	Path string
+
+	now func() time.Time
+}
+
+// NewClusters returns a Clusters cache stored in the given file
+func NewClusters(path string) *Clusters {
+	return &Clusters{
+		Path: path,
+		now:  time.Now,
+	}
+}
+
+// Add records the API server of the given cluster
+func (c *Clusters) Add(name, server string) error {
+	entries := map[string]Cluster{}
+	if err := readJSON(c.Path, &entries); err != nil {
+		// start over when the cache file is corrupted
+		entries = map[string]Cluster{}
+	}
+
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	entries[name] = Cluster{Server: server, LastSeen: now()}
+	return writeJSON(c.Path, entries)
+}
+
+// Names returns the names of the cached clusters, sorted
+func (c *Clusters) Names() ([]string, error) {
+	entries := map[string]Cluster{}
+	if err := readJSON(c.Path, &entries); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// List returns the cached clusters, sorted by name
+func (c *Clusters) List() ([]NamedCluster, error) {
+	entries := map[string]Cluster{}
+	if err := readJSON(c.Path, &entries); err != nil {
+		return nil, err
+	}
+
+	clusters := make([]NamedCluster, 0, len(entries))
+	for name, cluster := range entries {
+		clusters = append(clusters, NamedCluster{Name: name, Cluster: cluster})
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return clusters, nil
+}
+
+// readJSON decodes the json file at path into v, a missing file leaves v
+// untouched
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	c := NewClusters(filepath.Join(t.TempDir(), "cache", "clusters.json"))
+
+	names, err := c.Names()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected empty cache, got %v", names)
+	}
+
+	for _, name := range []string{"prod", "dev", "prod"} {
+		if err := c.Add(name, "https://"+name+":6443"); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	names, err = NewClusters(c.Path).Names()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if strings.Join(names, ",") != "dev,prod" {
+		t.Errorf("Got %v instead of [dev prod]", names)
+	}
+
+	clusters, err := c.List()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(clusters) != 2 || clusters[0].Name != "dev" || clusters[1].Server != "https://prod:6443" || clusters[1].LastSeen.IsZero() {
+		t.Errorf("Unexpected clusters %+v", clusters)
+	}
+}
+
+func TestClustersCorrupted(t *testing.T) {
+	c := NewClusters(filepath.Join(t.TempDir(), "clusters.json"))
+	if err := ioutil.WriteFile(c.Path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Names(); err == nil {
+		t.Error("Expected an error with a corrupted cache")
+	}
+	if err := c.Add("dev", "https://dev:6443"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	names, err := c.Names()
+	if err != nil || strings.Join(names, ",") != "dev" {
+		t.Errorf("Got %v, %v instead of [dev]", names, err)
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"eke/internal/pkg/lockfile"
+
+	"github.com/blang/semver/v4"
+)
+
+// KubectlState holds what eke remembers about the kubectl binaries
+type KubectlState struct {
+	// Default is the version used when the version of the server is unknown
+	Default *semver.Version `json:"default,omitempty"`
+	// LastUsed is the last time each binary has been run by the wrapper,
+	// keyed by path
+	LastUsed map[string]time.Time `json:"lastUsed"`
+}
+
+// KubectlStateFile stores a KubectlState
+type KubectlStateFile struct {
+	Path string
+
+	now func() time.Time
+}
+
+// NewKubectlStateFile returns a KubectlStateFile stored in the given file
+func NewKubectlStateFile(path string) *KubectlStateFile {
+	return &KubectlStateFile{
+		Path: path,
+		now:  time.Now,
+	}
+}
+
+// Load returns the stored state, a missing file results in an empty state
+func (c *KubectlStateFile) Load() (KubectlState, error) {
+	state := KubectlState{LastUsed: map[string]time.Time{}}
+
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	if state.LastUsed == nil {
+		state.LastUsed = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+// MarkUsed records that the binary at path is being used
+func (c *KubectlStateFile) MarkUsed(path string) error {
+	return c.update(func(state *KubectlState) {
+		state.LastUsed[path] = c.clock()()
+	})
+}
+
+// Forget drops what is known about the binary at path
+func (c *KubectlStateFile) Forget(path string) error {
+	return c.update(func(state *KubectlState) {
+		delete(state.LastUsed, path)
+	})
+}
+
+// SetDefault changes the default version, nil removes it
+func (c *KubectlStateFile) SetDefault(version *semver.Version) error {
+	return c.update(func(state *KubectlState) {
+		state.Default = version
+	})
+}
+
+// update changes the stored state, the concurrent eke processes take turns
+// so that none of the changes are lost
+func (c *KubectlStateFile) update(change func(state *KubectlState)) error {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+	lock, err := lockfile.Acquire(c.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	state, err := c.Load()
+	if err != nil {
+		// start over when the state file is corrupted
+		state = KubectlState{LastUsed: map[string]time.Time{}}
+	}
+
+	change(&state)
+	return writeJSON(c.Path, state)
+}
+
+func (c *KubectlStateFile) clock() func() time.Time {
+	if c.now == nil {
+		return time.Now
+	}
+	return c.now
+}
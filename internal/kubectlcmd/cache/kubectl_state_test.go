@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"eke/internal/pkg/lockfile"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestKubectlStateFile(t *testing.T) {
+	now := time.Now().UTC()
+	c := NewKubectlStateFile(filepath.Join(t.TempDir(), "cache", "kubectl-state.json"))
+	c.now = func() time.Time { return now }
+
+	state, err := c.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if state.Default != nil || len(state.LastUsed) != 0 {
+		t.Errorf("Expected empty state, got %+v", state)
+	}
+
+	v := semver.MustParse("1.22.3")
+	if err := c.SetDefault(&v); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := c.MarkUsed("/bin/kubectl1.22.3"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := c.MarkUsed("/bin/kubectl1.21.0"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := c.Forget("/bin/kubectl1.21.0"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// reload from disk
+	state, err = NewKubectlStateFile(c.Path).Load()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if state.Default == nil || !state.Default.Equals(v) {
+		t.Errorf("Got default %v instead of %s", state.Default, v)
+	}
+	if len(state.LastUsed) != 1 || !state.LastUsed["/bin/kubectl1.22.3"].Equal(now) {
+		t.Errorf("Unexpected last uses %+v", state.LastUsed)
+	}
+
+	if err := c.SetDefault(nil); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if state, _ := c.Load(); state.Default != nil {
+		t.Errorf("Expected default to be removed, got %v", state.Default)
+	}
+}
+
+func TestKubectlStateFileConcurrentUpdates(t *testing.T) {
+	old := lockfile.PollInterval
+	lockfile.PollInterval = time.Millisecond
+	t.Cleanup(func() { lockfile.PollInterval = old })
+
+	path := filepath.Join(t.TempDir(), "cache", "kubectl-state.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// each process has its own view of the file
+			if err := NewKubectlStateFile(path).MarkUsed(fmt.Sprintf("/bin/kubectl1.%d.0", i)); err != nil {
+				t.Errorf("Unexpected error %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	state, err := NewKubectlStateFile(path).Load()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(state.LastUsed) != 20 {
+		t.Errorf("Expected the 20 binaries to be recorded, got %d", len(state.LastUsed))
+	}
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// ServerVersion is the last version reported by an API server
+type ServerVersion struct {
+	Version  semver.Version `json:"version"`
+	LastSeen time.Time      `json:"lastSeen"`
+}
+
+// ServerVersions caches the version of the API servers eke talked to,
+// keyed by server URL
+type ServerVersions struct {
+	Path string
+	TTL  time.Duration
+
+	now func() time.Time
+}
+
+// NewServerVersions returns a ServerVersions cache stored in the given file
+func NewServerVersions(path string, ttl time.Duration) *ServerVersions {
+	return &ServerVersions{
+		Path: path,
+		TTL:  ttl,
+		now:  time.Now,
+	}
+}
+
+// Get returns the cached version of the given server
+func (c *ServerVersions) Get(server string) (ServerVersion, bool) {
+	entries, err := c.load()
+	if err != nil {
+		return ServerVersion{}, false
+	}
+	entry, found := entries[server]
+	return entry, found
+}
+
+// Set records the version reported by the given server
+func (c *ServerVersions) Set(server string, version semver.Version) error {
+	entries, err := c.load()
+	if err != nil {
+		// start over when the cache file is corrupted
+		entries = map[string]ServerVersion{}
+	}
+
+	entries[server] = ServerVersion{
+		Version:  version,
+		LastSeen: c.clock()(),
+	}
+	return writeJSON(c.Path, entries)
+}
+
+// IsFresh returns true when the entry is younger than the cache TTL
+func (c *ServerVersions) IsFresh(entry ServerVersion) bool {
+	return c.TTL > 0 && c.clock()().Sub(entry.LastSeen) < c.TTL
+}
+
+func (c *ServerVersions) clock() func() time.Time {
+	if c.now == nil {
+		return time.Now
+	}
+	return c.now
+}
+
+func (c *ServerVersions) load() (map[string]ServerVersion, error) {
+	entries := map[string]ServerVersion{}
+
+	data, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// writeJSON atomically replaces path with the json encoding of v, so that
+// concurrent eke invocations never read a partially written file
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestServerVersions(t *testing.T) {
+	now := time.Now()
+	c := NewServerVersions(filepath.Join(t.TempDir(), "cache", "server-versions.json"), time.Minute)
+	c.now = func() time.Time { return now }
+
+	if _, found := c.Get("https://cluster:6443"); found {
+		t.Error("Expected empty cache")
+	}
+
+	if err := c.Set("https://cluster:6443", semver.MustParse("1.22.3")); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// reload from disk
+	c2 := NewServerVersions(c.Path, time.Minute)
+	c2.now = c.now
+	entry, found := c2.Get("https://cluster:6443")
+	if !found {
+		t.Fatal("Expected cached version")
+	}
+	if !entry.Version.Equals(semver.MustParse("1.22.3")) {
+		t.Errorf("Got %s instead of 1.22.3", entry.Version)
+	}
+	if !c2.IsFresh(entry) {
+		t.Error("Expected entry to be fresh")
+	}
+
+	c2.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if c2.IsFresh(entry) {
+		t.Error("Expected entry to be stale")
+	}
+
+	c2.TTL = 0
+	c2.now = c.now
+	if c2.IsFresh(entry) {
+		t.Error("Expected entries to never be fresh without TTL")
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
 )
 
 // SystemPath contains the default path to look for kubectl binaries
@@ -30,7 +30,13 @@ func HomeDir() string {
 // LocalDownloadDir return the path to where kuberlr saves
 // the kubectl binaries downloaded from kubernetes' upstream mirror
 func LocalDownloadDir() string {
-	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+	return LocalDownloadDirFor(HostPlatform())
+}
+
+// LocalDownloadDirFor returns the path to where the kubectl binaries
+// of the given platform are saved
+func LocalDownloadDirFor(p Platform) string {
+	platform := fmt.Sprintf("%s-%s", p.OS, p.Arch)
 
 	return filepath.Join(
 		HomeDir(),
@@ -39,3 +45,84 @@ func LocalDownloadDir() string {
 		platform,
 	)
 }
+
+// LocalToolDownloadDir returns the path to where the binaries of the
+// given cluster tool, other than kubectl, are saved
+func LocalToolDownloadDir(tool string) string {
+	p := HostPlatform()
+	platform := fmt.Sprintf("%s-%s", p.OS, p.Arch)
+
+	return filepath.Join(
+		HomeDir(),
+		".eke",
+		"tools",
+		tool,
+		platform,
+	)
+}
+
+// CacheDir return the path to where eke keeps the state of the
+// kubectl wrapper, such as the last known API server versions
+func CacheDir() string {
+	return filepath.Join(
+		HomeDir(),
+		".eke",
+		"cache",
+	)
+}
+
+// AuditDir returns the path to where eke logs the kubectl commands run
+// through the wrapper
+func AuditDir() string {
+	return filepath.Join(
+		HomeDir(),
+		".eke",
+		"audit",
+	)
+}
+
+// PluginDir returns the path to where the eke-<name> executables run as
+// eke subcommands are looked for, before the directories of PATH
+func PluginDir() string {
+	return filepath.Join(
+		HomeDir(),
+		".eke",
+		"plugins",
+	)
+}
+
+// ProfileDir returns the path to where eke keeps the credentials of the
+// given profile, apart from the ones of the other profiles. Without profile
+// it is ~/.eke
+func ProfileDir(profile string) string {
+	if profile == "" {
+		return filepath.Join(HomeDir(), ".eke")
+	}
+	return filepath.Join(
+		HomeDir(),
+		".eke",
+		"profiles",
+		profile,
+	)
+}
+
+// ProfileCacheDir returns the path to where eke keeps the state bound to
+// the EWS instance of the given profile, such as its cluster catalog.
+// Without profile it is CacheDir
+func ProfileCacheDir(profile string) string {
+	if profile == "" {
+		return CacheDir()
+	}
+	return filepath.Join(ProfileDir(profile), "cache")
+}
+
+// ExpandHome replaces the leading ~ of path with the user home directory
+func ExpandHome(path string) string {
+	if path == "~" {
+		return HomeDir()
+	}
+	if strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return filepath.Join(HomeDir(), path[2:])
+	}
+	return path
+}
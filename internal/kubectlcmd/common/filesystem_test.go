@@ -0,0 +1,44 @@
+package common
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileDirs(t *testing.T) {
+	t.Setenv(HomeDirEnvKey(), "/home/user")
+
+	tests := []struct {
+		profile  string
+		dir      string
+		cacheDir string
+	}{
+		{"", "/home/user/.eke", "/home/user/.eke/cache"},
+		{"staging", "/home/user/.eke/profiles/staging", "/home/user/.eke/profiles/staging/cache"},
+	}
+	for _, tt := range tests {
+		if got := ProfileDir(tt.profile); got != filepath.FromSlash(tt.dir) {
+			t.Errorf("ProfileDir(%q) = %q, want %q", tt.profile, got, tt.dir)
+		}
+		if got := ProfileCacheDir(tt.profile); got != filepath.FromSlash(tt.cacheDir) {
+			t.Errorf("ProfileCacheDir(%q) = %q, want %q", tt.profile, got, tt.cacheDir)
+		}
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv(HomeDirEnvKey(), "/home/user")
+
+	tests := map[string]string{
+		"~":             "/home/user",
+		"~/.kube/prod":  "/home/user/.kube/prod",
+		"/etc/ca.pem":   "/etc/ca.pem",
+		"~other/ca.pem": "~other/ca.pem",
+		"ca.pem":        "ca.pem",
+	}
+	for path, want := range tests {
+		if got := ExpandHome(path); got != filepath.FromSlash(want) {
+			t.Errorf("ExpandHome(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RejectedKubectl describes a kubectl binary that has been discarded
+// while looking for a compatible one
+type RejectedKubectl struct {
+	Path    string
+	Version string
+	Reason  string
+}
+
+// IncompatibleVersionsError error is raised when kubectl binaries are
+// available but none of them is compatible with the requested version
+type IncompatibleVersionsError struct {
+	Version  string
+	Policy   string
+	Rejected []RejectedKubectl
+}
+
+// Error returns a human description of the error, listing the binaries
+// that have been found and why they have been rejected
+func (e *IncompatibleVersionsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "No kubectl binary compatible with version %s according to the %s policy, found:", e.Version, e.Policy)
+	for _, r := range e.Rejected {
+		fmt.Fprintf(&b, "\n  - %s (%s): %s", r.Path, r.Version, r.Reason)
+	}
+	return b.String()
+}
+
+// NoVersionFound returns true, none of the available binaries can be used
+func (e *IncompatibleVersionsError) NoVersionFound() bool {
+	return true
+}
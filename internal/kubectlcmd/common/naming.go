@@ -21,6 +21,12 @@ func BuildKubectlNameForLocalBin(v semver.Version) string {
 	return fmt.Sprintf(KubectlLocalNamingScheme+osexec.Ext, v.Major, v.Minor, v.Patch)
 }
 
+// BuildKubectlNameForPlatformLocalBin returns how kuberlr names the kubectl
+// binary of the given platform with the specified version
+func BuildKubectlNameForPlatformLocalBin(v semver.Version, p Platform) string {
+	return fmt.Sprintf(KubectlLocalNamingScheme+p.Ext(), v.Major, v.Minor, v.Patch)
+}
+
 // BuildKubectlNameForSystemBin returns how kuberlr expects system-wide
 // kubectl binaries to be named
 func BuildKubectlNameForSystemBin(version semver.Version) string {
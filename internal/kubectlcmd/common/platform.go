@@ -0,0 +1,41 @@
+package common
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Platform identifies the operating system and the architecture a kubectl
+// binary is built for
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// HostPlatform returns the platform eke is running on
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses platforms written as os/arch, e.g. linux/amd64
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected os/arch", s)
+	}
+	return Platform{OS: parts[0], Arch: parts[1]}, nil
+}
+
+// String returns the platform written as os/arch
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// Ext returns the filename extension of the binaries of the platform
+func (p Platform) Ext() string {
+	if p.OS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
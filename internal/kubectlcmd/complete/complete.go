@@ -0,0 +1,127 @@
+package complete
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eke/internal/kubectlcmd/cache"
+	"eke/internal/kubectlcmd/kubehelper"
+
+	"github.com/spf13/cobra"
+)
+
+// Forward asks the kubectl binary at path to complete args, the last one
+// being the word under completion. request is the hidden cobra command
+// answering the completion requests, with or without descriptions
+func Forward(ctx context.Context, path, request string, args []string) ([]string, cobra.ShellCompDirective, error) {
+	cmd := exec.CommandContext(ctx, path, append([]string{request}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError, fmt.Errorf("%s cannot complete %v: %v", path, args, err)
+	}
+	return Parse(output)
+}
+
+// Parse reads the answer to a completion request: one completion per
+// line followed by the directive, e.g. ":4"
+func Parse(output []byte) ([]string, cobra.ShellCompDirective, error) {
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, cobra.ShellCompDirectiveError, errors.New("the completion directive is missing")
+	}
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError, fmt.Errorf("invalid completion directive %q", last)
+	}
+
+	var completions []string
+	for _, line := range lines[:len(lines)-1] {
+		if line != "" {
+			completions = append(completions, line)
+		}
+	}
+	return completions, cobra.ShellCompDirective(directive), nil
+}
+
+// Write answers a completion request like cobra does
+func Write(w io.Writer, completions []string, directive cobra.ShellCompDirective) {
+	var buf bytes.Buffer
+	for _, c := range completions {
+		fmt.Fprintln(&buf, c)
+	}
+	fmt.Fprintf(&buf, ":%d\n", directive)
+	w.Write(buf.Bytes())
+}
+
+// Matching returns the candidates starting with prefix
+func Matching(candidates []string, prefix string) []string {
+	var matching []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matching = append(matching, c)
+		}
+	}
+	return matching
+}
+
+// CommaSeparated completes the last item of a comma separated list, the
+// items already listed are not suggested again
+func CommaSeparated(candidates []string, toComplete string) []string {
+	i := strings.LastIndex(toComplete, ",")
+	listed, prefix := toComplete[:i+1], toComplete[i+1:]
+
+	seen := map[string]bool{}
+	for _, item := range strings.Split(listed, ",") {
+		seen[item] = true
+	}
+
+	var completions []string
+	for _, c := range Matching(candidates, prefix) {
+		if !seen[c] {
+			completions = append(completions, listed+c)
+		}
+	}
+	return completions
+}
+
+// Contexts returns the contexts of the kubeconfig starting with toComplete
+func Contexts(toComplete string) []string {
+	flags, err := kubehelper.ParseConnectionFlags(nil)
+	if err != nil {
+		return nil
+	}
+	contexts, _ := flags.Contexts()
+	return Matching(contexts, toComplete)
+}
+
+// Clusters returns the EWS clusters starting with toComplete, they come
+// from the cache of the clusters looked up before and from the kubeconfig
+func Clusters(clusterCache *cache.Clusters, toComplete string) []string {
+	seen := map[string]bool{}
+	if names, err := clusterCache.Names(); err == nil {
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+	if flags, err := kubehelper.ParseConnectionFlags(nil); err == nil {
+		names, _ := flags.Clusters()
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	clusters := make([]string, 0, len(seen))
+	for name := range seen {
+		clusters = append(clusters, name)
+	}
+	sort.Strings(clusters)
+	return Matching(clusters, toComplete)
+}
@@ -0,0 +1,116 @@
+package complete
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		completions []string
+		directive   cobra.ShellCompDirective
+		errMsg      string
+	}{
+		{
+			name:        "completions",
+			output:      "pods\tPods\nservices\n:4\n",
+			completions: []string{"pods\tPods", "services"},
+			directive:   cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			name:      "no completion",
+			output:    ":0\n",
+			directive: cobra.ShellCompDirectiveDefault,
+		},
+		{
+			name:   "missing directive",
+			output: "pods\n",
+			errMsg: "directive is missing",
+		},
+		{
+			name:   "invalid directive",
+			output: "pods\n:x\n",
+			errMsg: "invalid completion directive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			completions, directive, err := Parse([]byte(tt.output))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(completions, "|") != strings.Join(tt.completions, "|") || directive != tt.directive {
+				t.Errorf("Got %q %d instead of %q %d", completions, directive, tt.completions, tt.directive)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, []string{"pods", "services"}, cobra.ShellCompDirectiveNoFileComp)
+	if buf.String() != "pods\nservices\n:4\n" {
+		t.Errorf("Got %q", buf.String())
+	}
+}
+
+func TestCommaSeparated(t *testing.T) {
+	candidates := []string{"dev", "prod-eu", "prod-us"}
+
+	tests := []struct {
+		toComplete string
+		expected   []string
+	}{
+		{"", []string{"dev", "prod-eu", "prod-us"}},
+		{"pr", []string{"prod-eu", "prod-us"}},
+		{"dev,", []string{"dev,prod-eu", "dev,prod-us"}},
+		{"prod-eu,prod", []string{"prod-eu,prod-us"}},
+		{"dev,x", nil},
+	}
+	for _, tt := range tests {
+		actual := CommaSeparated(candidates, tt.toComplete)
+		if strings.Join(actual, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Got %q instead of %q for %q", actual, tt.expected, tt.toComplete)
+		}
+	}
+}
+
+func TestForward(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported")
+	}
+
+	kubectl := filepath.Join(t.TempDir(), "kubectl")
+	script := "#!/bin/sh\n[ \"$1\" = __complete ] || exit 1\nshift\nfor arg in \"$@\"; do echo \"arg:$arg\"; done\necho :4\n"
+	if err := ioutil.WriteFile(kubectl, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	completions, directive, err := Forward(context.Background(), kubectl, cobra.ShellCompRequestCmd, []string{"get", "pods", ""})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(completions, " ") != "arg:get arg:pods arg:" || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Got %q %d", completions, directive)
+	}
+
+	if _, _, err := Forward(context.Background(), kubectl, cobra.ShellCompNoDescRequestCmd, []string{""}); err == nil {
+		t.Error("Expected an error when kubectl fails")
+	}
+}
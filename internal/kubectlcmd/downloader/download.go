@@ -1,52 +1,250 @@
 package downloader
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
+	"text/template"
 	"time"
 
 	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/manifest"
+	"eke/internal/pkg/debug"
+	"eke/internal/pkg/lockfile"
 
-	"eke/internal/kubectlcmd/osexec"
+	"eke/pkg/config/cmdconfig"
 
+	"github.com/avast/retry-go"
 	"github.com/blang/semver/v4"
 	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
 )
 
 // KubectlStableURL URL of the text file used by kubernetes community
 // to hold the latest stable version of kubernetes released
 const KubectlStableURL = "https://storage.googleapis.com/kubernetes-release/release/stable.txt"
 
+// KubectlDownloadURLTemplate is the template of the kubernetes community
+// location of released kubectl binaries
+const KubectlDownloadURLTemplate = "https://storage.googleapis.com/kubernetes-release/release/v{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl{{.Ext}}"
+
+// DefaultMirror is used when no mirror has been configured
+var DefaultMirror = cmdconfig.KubectlMirror{
+	URL:       KubectlDownloadURLTemplate,
+	StableURL: KubectlStableURL,
+}
+
+// time to wait before retrying a download, doubled after each attempt
+var retryInterval = 2 * time.Second
+
+// errRangeNotSatisfiable is returned when a download cannot be resumed
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// transientError flags the failures worth a retry, such as dropped
+// connections and server side errors
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func isRetryable(err error) bool {
+	var t *transientError
+	return errors.As(err, &t) || common.IsShaMismatch(err)
+}
+
 // Downloder is a helper class that is used to interact with the
 // kubernetes infrastructure holding released binaries and release information
 type Downloder struct {
+	// Mirrors are tried in order, DefaultMirror is used when empty
+	Mirrors []cmdconfig.KubectlMirror
+	// PartialDir holds the interrupted downloads and the download locks,
+	// the cache directory is used when empty
+	PartialDir string
+	// Verification holds the keys trusted to sign the checksum manifests
+	// of the mirrors
+	Verification cmdconfig.KubectlVerification
+	// Binary is the name of the downloaded binary, it names the partial
+	// downloads and the entries of the checksum manifests. Defaults to
+	// kubectl
+	Binary string
+}
+
+// NewDownloder returns a Downloder fetching binaries from the given mirrors
+func NewDownloder(mirrors []cmdconfig.KubectlMirror) *Downloder {
+	return &Downloder{
+		Mirrors: mirrors,
+	}
+}
+
+// NewDownloderFromConfig returns a Downloder using the mirrors and the
+// verification settings of the given configuration
+func NewDownloderFromConfig(config cmdconfig.EkeKubectlConfig) *Downloder {
+	d := NewDownloder(config.Mirrors)
+	d.Verification = config.Verification
+	return d
+}
+
+// mirrorTemplateData holds the values available to the mirror url templates
+type mirrorTemplateData struct {
+	Version string
+	OS      string
+	Arch    string
+	Ext     string
+	URL     string
+}
+
+func (d *Downloder) partialDir() string {
+	if d.PartialDir == "" {
+		return filepath.Join(common.CacheDir(), "downloads")
+	}
+	return d.PartialDir
+}
+
+func newMirrorTemplateData(v semver.Version, platform common.Platform) mirrorTemplateData {
+	return mirrorTemplateData{
+		Version: fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch),
+		OS:      platform.OS,
+		Arch:    platform.Arch,
+		Ext:     platform.Ext(),
+	}
+}
+
+func (d *Downloder) binary() string {
+	if d.Binary == "" {
+		return "kubectl"
+	}
+	return d.Binary
+}
+
+func (d *Downloder) mirrors() []cmdconfig.KubectlMirror {
+	if len(d.Mirrors) == 0 {
+		return []cmdconfig.KubectlMirror{DefaultMirror}
+	}
+	return d.Mirrors
 }
 
-func (d *Downloder) getContentsOfURL(url string) (string, error) {
-	res, err := http.Get(url)
+func renderURL(tmpl string, data mirrorTemplateData) (string, error) {
+	t, err := template.New("url").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid mirror url template %q: %v", tmpl, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid mirror url template %q: %v", tmpl, err)
+	}
+
+	u, err := url.Parse(buf.String())
 	if err != nil {
 		return "", err
 	}
-	if res.StatusCode != http.StatusOK {
-		return "",
-			fmt.Errorf(
-				"GET %s returned http status %s",
-				url,
-				res.Status,
-			)
+	return u.String(), nil
+}
+
+// open returns a reader over the resource pointed by urlToGet, starting
+// at offset, together with the number of bytes left (-1 when unknown).
+// resumed is false when the resource is read from the start regardless
+// of offset
+func (d *Downloder) open(mirror cmdconfig.KubectlMirror, urlToGet string, offset int64) (r io.ReadCloser, size int64, resumed bool, err error) {
+	u, err := url.Parse(urlToGet)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		f, err := os.Open(filepath.FromSlash(u.Path))
+		if err != nil {
+			return nil, 0, false, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, false, err
+		}
+		if offset > info.Size() {
+			f.Close()
+			return nil, 0, false, errRangeNotSatisfiable
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, 0, false, err
+		}
+		return f, info.Size() - offset, offset > 0, nil
+	case "http", "https":
+		req, err := http.NewRequest("GET", urlToGet, nil)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf(
+				"error while issuing GET request against %s: %v",
+				urlToGet, err)
+		}
+		setAuth(req, mirror)
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, 0, false, &transientError{fmt.Errorf(
+				"error while issuing GET request against %s: %v",
+				urlToGet, err)}
+		}
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp.Body, resp.ContentLength, false, nil
+		case resp.StatusCode == http.StatusPartialContent && offset > 0:
+			return resp.Body, resp.ContentLength, true, nil
+		}
+
+		resp.Body.Close()
+		err = fmt.Errorf(
+			"GET %s returned http status %s",
+			urlToGet,
+			resp.Status,
+		)
+		switch {
+		case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+			return nil, 0, false, errRangeNotSatisfiable
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return nil, 0, false, &transientError{err}
+		}
+		return nil, 0, false, err
+	default:
+		return nil, 0, false, fmt.Errorf("unsupported mirror url scheme %q in %s", u.Scheme, urlToGet)
+	}
+}
+
+// setAuth adds the mirror credentials to the request. Credentials can
+// reference environment variables, e.g. `token: ${ARTIFACTORY_TOKEN}`
+func setAuth(req *http.Request, mirror cmdconfig.KubectlMirror) {
+	if token := os.ExpandEnv(mirror.Token); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if username := os.ExpandEnv(mirror.Username); username != "" {
+		req.SetBasicAuth(username, os.ExpandEnv(mirror.Password))
+	}
+}
+
+func (d *Downloder) getContentsOfURL(mirror cmdconfig.KubectlMirror, url string) (string, error) {
+	r, _, _, err := d.open(mirror, url, 0)
+	if err != nil {
+		return "", err
 	}
 
-	v, err := ioutil.ReadAll(res.Body)
-	res.Body.Close()
+	v, err := ioutil.ReadAll(r)
+	r.Close()
 	if err != nil {
 		return "", err
 	}
@@ -54,116 +252,379 @@ func (d *Downloder) getContentsOfURL(url string) (string, error) {
 }
 
 // UpstreamStableVersion returns the latest version of kubernetes that upstream
-// considers stable
+// considers stable. The first mirror providing a stable url is used
 func (d *Downloder) UpstreamStableVersion() (semver.Version, error) {
-	v, err := d.getContentsOfURL(KubectlStableURL)
+	var firstErr error
+	host := common.HostPlatform()
+	data := mirrorTemplateData{
+		OS:   host.OS,
+		Arch: host.Arch,
+		Ext:  host.Ext(),
+	}
+
+	for _, mirror := range d.mirrors() {
+		if mirror.StableURL == "" {
+			continue
+		}
+
+		v, err := d.stableVersionFromMirror(mirror, data)
+		if err == nil {
+			return v, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = errors.New("none of the configured kubectl mirrors provides a stable version url")
+	}
+	return semver.Version{}, firstErr
+}
+
+func (d *Downloder) stableVersionFromMirror(mirror cmdconfig.KubectlMirror, data mirrorTemplateData) (semver.Version, error) {
+	stableURL, err := renderURL(mirror.StableURL, data)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	v, err := d.getContentsOfURL(mirror, stableURL)
 	if err != nil {
 		return semver.Version{}, err
 	}
-	return semver.ParseTolerant(v)
+	return semver.ParseTolerant(strings.TrimSpace(v))
 }
 
-// GetKubectlBinary downloads the kubectl binary identified by the given version
-// to the specified destination
-func (d *Downloder) GetKubectlBinary(version semver.Version, destination string) error {
+// LatestPatchVersion returns the latest patch release of the given minor
+// version of kubernetes. Like upstream, the mirrors are expected to publish
+// it as stable-<major>.<minor>.txt next to their stable url
+func (d *Downloder) LatestPatchVersion(major, minor uint64) (semver.Version, error) {
 	var firstErr error
-	const maxNumTries = 3
-	const timeToSleepOnRetryPerIter = 10 // seconds
+	host := common.HostPlatform()
+	data := mirrorTemplateData{
+		OS:   host.OS,
+		Arch: host.Arch,
+		Ext:  host.Ext(),
+	}
+	minorFile := fmt.Sprintf("stable-%d.%d.txt", major, minor)
 
-	for iter := 1; iter <= maxNumTries; iter++ {
-		downloadURL, err := d.kubectlDownloadURL(version)
+	for _, mirror := range d.mirrors() {
+		if mirror.StableURL == "" {
+			continue
+		}
+		stableURL, err := renderURL(mirror.StableURL, data)
 		if err != nil {
+			return semver.Version{}, err
+		}
+		if path.Base(stableURL) != "stable.txt" {
+			continue
+		}
+
+		mirror.StableURL = strings.TrimSuffix(stableURL, "stable.txt") + minorFile
+		v, err := d.stableVersionFromMirror(mirror, data)
+		if err == nil && (v.Major != major || v.Minor != minor) {
+			err = fmt.Errorf("%s holds the version %s", mirror.StableURL, v)
+		}
+		if err == nil {
+			return v, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = errors.New("none of the configured kubectl mirrors provides a stable.txt url")
+	}
+	return semver.Version{}, firstErr
+}
+
+// GetKubectlBinary downloads the kubectl binary identified by the given version
+// to the specified destination. Mirrors are tried in order until one of them
+// provides a binary matching its checksum. Concurrent downloads of the same
+// version are serialized, interrupted downloads are resumed
+func (d *Downloder) GetKubectlBinary(version semver.Version, destination string) error {
+	return d.GetKubectlBinaryFor(version, common.HostPlatform(), destination)
+}
+
+// GetKubectlBinaryFor works like GetKubectlBinary for the binary of the
+// given platform
+func (d *Downloder) GetKubectlBinaryFor(version semver.Version, platform common.Platform, destination string) error {
+	for _, dir := range []string{filepath.Dir(destination), d.partialDir()} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 			return err
 		}
+	}
 
-		if _, err := os.Stat(filepath.Dir(destination)); err != nil {
-			if os.IsNotExist(err) {
-				err = os.MkdirAll(filepath.Dir(destination), os.ModePerm)
-			}
+	before, err := os.Stat(destination)
+	if err != nil {
+		before = nil
+	}
+
+	name := fmt.Sprintf("%s%s-%s-%s%s", d.binary(), version, platform.OS, platform.Arch, platform.Ext())
+	lock, err := lockfile.Acquire(filepath.Join(d.partialDir(), name+".lock"))
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if after, err := os.Stat(destination); err == nil && (before == nil || !os.SameFile(before, after)) {
+		// downloaded by another eke process while waiting for the lock, an
+		// existing binary is only downloaded again when nobody replaced it
+		return nil
+	}
+
+	partial := filepath.Join(d.partialDir(), name+".partial")
+	var firstErr error
+	for _, mirror := range d.mirrors() {
+		err := d.getKubectlBinaryFromMirror(mirror, version, platform, partial, destination)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		fmt.Fprintf(os.Stderr, "Error downloading %s from mirror %s: %s\n", d.binary(), mirror.URL, err)
+	}
+	return firstErr
+}
+
+func (d *Downloder) getKubectlBinaryFromMirror(mirror cmdconfig.KubectlMirror, version semver.Version, platform common.Platform, partial, destination string) error {
+	const maxNumTries = 5
+
+	downloadURL, _, err := d.kubectlDownloadURL(mirror, version, platform)
+	if err != nil {
+		return err
+	}
+
+	return retry.Do(
+		func() error {
+			checksum, _, err := d.expectedChecksum(mirror, version, platform)
 			if err != nil {
 				return err
 			}
-		}
+			return d.download(
+				fmt.Sprintf("%s%s%s", d.binary(), version, platform.Ext()),
+				mirror, downloadURL, checksum, partial, destination, 0755)
+		},
+		retry.Attempts(maxNumTries),
+		retry.Delay(retryInterval),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(isRetryable),
+		retry.OnRetry(func(n uint, err error) {
+			fmt.Fprintf(os.Stderr, "Error on download attempt #%d: %s\n", n+1, err)
+		}),
+	)
+}
 
-		err = d.download(fmt.Sprintf("kubectl%s%s", version, osexec.Ext), downloadURL, destination, 0755)
+// KubectlChecksum returns the sha256 checksum of the kubectl binary with the
+// given version, as published by the first mirror able to provide it.
+// signed is true when the checksum comes from a signed manifest
+func (d *Downloder) KubectlChecksum(version semver.Version) (checksum string, signed bool, err error) {
+	var firstErr error
+	for _, mirror := range d.mirrors() {
+		checksum, signed, err := d.expectedChecksum(mirror, version, common.HostPlatform())
 		if err == nil {
-			return nil
+			return checksum, signed, nil
 		}
-		if iter == 1 {
+		if firstErr == nil {
 			firstErr = err
 		}
-		if common.IsShaMismatch(err) {
-			// Try downloading an older subversion
-			fmt.Fprintf(os.Stderr, "Error on download attempt #%d: %s\n", iter, err)
-			time.Sleep(time.Duration(iter*timeToSleepOnRetryPerIter) * time.Second)
-		} else {
-			break
-		}
 	}
-	return firstErr
+	return "", false, firstErr
 }
 
-func (d *Downloder) kubectlDownloadURL(v semver.Version) (string, error) {
-	// Example: https://storage.googleapis.com/kubernetes-release/release/v1.18.0/bin/linux/amd64/kubectlI
-	u, err := url.Parse(fmt.Sprintf(
-		"https://storage.googleapis.com/kubernetes-release/release/v%d.%d.%d/bin/%s/%s/kubectl%s",
-		v.Major,
-		v.Minor,
-		v.Patch,
-		runtime.GOOS,
-		runtime.GOARCH,
-		osexec.Ext,
-	))
+// expectedChecksum returns the checksum of the kubectl binary published by
+// the mirror. The signed manifest of the mirror is used when configured,
+// the checksum file otherwise
+func (d *Downloder) expectedChecksum(mirror cmdconfig.KubectlMirror, version semver.Version, platform common.Platform) (string, bool, error) {
+	if mirror.ManifestURL == "" {
+		if d.Verification.RequireSignature {
+			return "", false, fmt.Errorf("mirror %s provides no signed checksum manifest", mirror.URL)
+		}
+
+		_, checksumURL, err := d.kubectlDownloadURL(mirror, version, platform)
+		if err != nil {
+			return "", false, err
+		}
+		contents, err := d.getContentsOfURL(mirror, checksumURL)
+		if err != nil {
+			return "", false, fmt.Errorf("error while trying to get contents of %s: %v", checksumURL, err)
+		}
+		return parseChecksum(contents), false, nil
+	}
+
+	verifier, err := manifest.NewVerifier(d.Verification.PublicKeys)
 	if err != nil {
-		return "", err
+		return "", false, err
+	}
+	manifestURL, signatureURL, err := d.manifestURLs(mirror, version, platform)
+	if err != nil {
+		return "", false, err
 	}
 
-	return u.String(), nil
+	data, err := d.getContentsOfURL(mirror, manifestURL)
+	if err != nil {
+		return "", false, fmt.Errorf("error while trying to get contents of %s: %v", manifestURL, err)
+	}
+	signature, err := d.getContentsOfURL(mirror, signatureURL)
+	if err != nil {
+		return "", false, fmt.Errorf("error while trying to get contents of %s: %v", signatureURL, err)
+	}
+	if err := verifier.Verify([]byte(data), []byte(signature)); err != nil {
+		return "", false, fmt.Errorf("cannot trust the checksum manifest %s: %v", manifestURL, err)
+	}
+
+	m, err := manifest.Parse([]byte(data))
+	if err != nil {
+		return "", false, err
+	}
+	checksum, found := m.Lookup(d.binary(), version, platform.OS, platform.Arch)
+	if !found {
+		return "", false, fmt.Errorf("the checksum manifest %s has no entry for %s",
+			manifestURL, manifest.Entry(d.binary(), version, platform.OS, platform.Arch))
+	}
+	return checksum, true, nil
 }
 
-func (d *Downloder) download(desc, urlToGet, destination string, mode os.FileMode) error {
-	shaURLToGet := urlToGet + ".sha256"
-	shaExpected, err := d.getContentsOfURL(shaURLToGet)
+// manifestURLs returns the location of the checksum manifest of the mirror
+// and of its signature
+func (d *Downloder) manifestURLs(mirror cmdconfig.KubectlMirror, v semver.Version, platform common.Platform) (string, string, error) {
+	data := newMirrorTemplateData(v, platform)
+
+	manifestURL, err := renderURL(mirror.ManifestURL, data)
 	if err != nil {
-		return fmt.Errorf("error while trying to get contents of %s: %v", shaURLToGet, err)
+		return "", "", err
+	}
+	if mirror.SignatureURL == "" {
+		return manifestURL, manifestURL + ".sig", nil
 	}
-	shaExpected = strings.TrimRight(shaExpected, "\n")
 
-	req, err := http.NewRequest("GET", urlToGet, nil)
+	data.URL = manifestURL
+	signatureURL, err := renderURL(mirror.SignatureURL, data)
 	if err != nil {
-		return fmt.Errorf(
-			"error while issuing GET request against %s: %v",
-			urlToGet, err)
+		return "", "", err
 	}
+	return manifestURL, signatureURL, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// kubectlDownloadURL returns the location of the kubectl binary and of its
+// checksum on the given mirror
+func (d *Downloder) kubectlDownloadURL(mirror cmdconfig.KubectlMirror, v semver.Version, platform common.Platform) (string, string, error) {
+	// Example: https://storage.googleapis.com/kubernetes-release/release/v1.18.0/bin/linux/amd64/kubectl
+	data := newMirrorTemplateData(v, platform)
+
+	binURL, err := renderURL(mirror.URL, data)
 	if err != nil {
-		return fmt.Errorf(
-			"error while issuing GET request against %s: %v",
-			urlToGet, err)
+		return "", "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf(
-			"GET %s returned http status %s",
-			urlToGet,
-			resp.Status,
-		)
+	if mirror.ChecksumURL == "" {
+		return binURL, binURL + ".sha256", nil
 	}
-	temporaryDestinationFile, err := ioutil.TempFile(os.TempDir(), "kuberlr-kubectl-")
+
+	data.URL = binURL
+	checksumURL, err := renderURL(mirror.ChecksumURL, data)
+	if err != nil {
+		return "", "", err
+	}
+	return binURL, checksumURL, nil
+}
+
+// parseChecksum accepts both a bare digest and the `sha256sum` output format
+func parseChecksum(contents string) string {
+	fields := strings.Fields(contents)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// download fetches urlToGet into the partial file, resuming a previous
+// attempt when possible, and moves it to destination once its checksum
+// matches shaExpected. The partial file is kept when the transfer fails
+func (d *Downloder) download(desc string, mirror cmdconfig.KubectlMirror, urlToGet, shaExpected, partial, destination string, mode os.FileMode) error {
+	partialFile, err := os.OpenFile(partial, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
-		return fmt.Errorf("error trying to create temporary file in %s: %v", os.TempDir(), err)
+		return fmt.Errorf("error trying to open %s: %v", partial, err)
+	}
+	defer partialFile.Close()
+
+	// hash what has already been downloaded, this moves to the end of the file
+	hasher := sha256.New()
+	offset, err := io.Copy(hasher, partialFile)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 && hex.EncodeToString(hasher.Sum(nil)) == shaExpected {
+		// a previous attempt stopped right before installing the binary
+		partialFile.Close()
+		return install(partial, destination, mode)
 	}
 
-	tmpname := temporaryDestinationFile.Name()
-	defer os.Remove(tmpname)
+	body, size, resumed, err := d.open(mirror, urlToGet, offset)
+	if err == errRangeNotSatisfiable {
+		// the partial file does not belong to this binary
+		body, size, resumed, err = d.open(mirror, urlToGet, 0)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
 
 	// write progress to stderr, writing to stdout would
 	// break bash/zsh/shell completion
-	fmt.Fprintf(os.Stderr, "Downloading %s\n", urlToGet)
-	bar := progressbar.NewOptions(
-		int(resp.ContentLength),
+	if resumed {
+		fmt.Fprintf(os.Stderr, "Resuming download of %s at %d bytes\n", urlToGet, offset)
+	} else {
+		if offset != 0 {
+			// the partial file cannot be used, start over
+			if err := partialFile.Truncate(0); err != nil {
+				return err
+			}
+			if _, err := partialFile.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			hasher.Reset()
+		}
+		fmt.Fprintf(os.Stderr, "Downloading %s\n", urlToGet)
+	}
+
+	span := debug.StartStep(debug.StepDownload, urlToGet)
+	_, err = io.Copy(io.MultiWriter(partialFile, progressWriter(desc, size), hasher, debug.CountingWriter(debug.DownloadBytes)), body)
+	span.End()
+	if err != nil {
+		return &transientError{fmt.Errorf(
+			"error while downloading %s into file %s: %v",
+			urlToGet, partial, err)}
+	}
+
+	// Closing the file handler prior to performing a rename so this process (the
+	// open file handler) does not conflict with the rename.
+	if err := partialFile.Close(); err != nil {
+		return err
+	}
+
+	shaActual := hex.EncodeToString(hasher.Sum(nil))
+	if shaExpected != shaActual {
+		os.Remove(partial)
+		return &common.ShaMismatchError{URL: urlToGet, ShaExpected: shaExpected, ShaActual: shaActual}
+	}
+
+	return install(partial, destination, mode)
+}
+
+// progressWriter returns the writer displaying the download progress on
+// stderr, nothing is displayed when stderr is not a terminal
+func progressWriter(desc string, size int64) io.Writer {
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return ioutil.Discard
+	}
+
+	return progressbar.NewOptions64(
+		size,
 		progressbar.OptionSetDescription(desc),
 		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionShowBytes(true),
@@ -174,40 +635,33 @@ func (d *Downloder) download(desc, urlToGet, destination string, mode os.FileMod
 			fmt.Fprintln(os.Stderr, " done.")
 		}),
 	)
-	hasher := sha256.New()
+}
 
-	_, err = io.Copy(io.MultiWriter(temporaryDestinationFile, bar, hasher), resp.Body)
-	if err != nil {
-		temporaryDestinationFile.Close()
-		return fmt.Errorf(
-			"error while downloading text of %s into file %s: %v",
-			urlToGet, tmpname, err)
+// install moves the downloaded file to destination, which is replaced
+// atomically
+func install(downloaded, destination string, mode os.FileMode) error {
+	if err := os.Chmod(downloaded, mode); err != nil {
+		return err
 	}
 
-	// Closing the file handler prior to performing a rename so this process (the
-	// open file handler) does not conflict with the rename.
-	temporaryDestinationFile.Close()
-
-	shaActual := hex.EncodeToString(hasher.Sum(nil))
-	if shaExpected != shaActual {
-		return &common.ShaMismatchError{URL: urlToGet, ShaExpected: shaExpected, ShaActual: shaActual}
+	err := os.Rename(downloaded, destination)
+	linkErr, ok := err.(*os.LinkError)
+	if !ok {
+		return err
 	}
 
-	err = os.Rename(tmpname, destination)
+	fmt.Fprintf(os.Stderr, "Cross-device error trying to rename a file: %s -- will do a full copy\n", linkErr)
+	data, err := ioutil.ReadFile(downloaded)
 	if err != nil {
-		linkErr, ok := err.(*os.LinkError)
-		if ok {
-			fmt.Fprintf(os.Stderr, "Cross-device error trying to rename a file: %s -- will do a full copy\n", linkErr)
-			var tempInput []byte
-			tempInput, err = ioutil.ReadFile(tmpname)
-			if err != nil {
-				return fmt.Errorf("error reading temporary file %s: %v",
-					tmpname, err)
-			}
-			err = ioutil.WriteFile(destination, tempInput, mode)
-		}
-	} else {
-		err = os.Chmod(destination, mode)
+		return fmt.Errorf("error reading temporary file %s: %v", downloaded, err)
+	}
+	tmp := destination + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, destination); err != nil {
+		os.Remove(tmp)
+		return err
 	}
-	return err
+	return os.Remove(downloaded)
 }
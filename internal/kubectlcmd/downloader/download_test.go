@@ -0,0 +1,278 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/osexec"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+const fakeKubectl = "#!/bin/sh\necho fake kubectl\n"
+
+func sha256Of(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func binPath(version string) string {
+	return fmt.Sprintf("/v%s/%s/%s/kubectl%s", version, runtime.GOOS, runtime.GOARCH, osexec.Ext)
+}
+
+func newMirrorServer(t *testing.T, checksum string, auth func(r *http.Request) bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(binPath("1.22.3"), func(w http.ResponseWriter, r *http.Request) {
+		if auth != nil && !auth(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, fakeKubectl)
+	})
+	mux.HandleFunc(binPath("1.22.3")+".sha256", func(w http.ResponseWriter, r *http.Request) {
+		if auth != nil && !auth(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintln(w, checksum)
+	})
+	mux.HandleFunc("/stable.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "v1.23.4")
+	})
+	mux.HandleFunc("/stable-1.22.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "v1.22.9")
+	})
+
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestDownloder returns a Downloder keeping its partial downloads and
+// locks in a temporary directory
+func newTestDownloder(t *testing.T, mirrors []cmdconfig.KubectlMirror) *Downloder {
+	d := NewDownloder(mirrors)
+	d.PartialDir = t.TempDir()
+	return d
+}
+
+// setDuration changes one of the delays of the package until the end of
+// the test
+func setDuration(t *testing.T, d *time.Duration, value time.Duration) {
+	old := *d
+	*d = value
+	t.Cleanup(func() { *d = old })
+}
+
+func mirrorURL(base string) string {
+	return base + "/v{{.Version}}/{{.OS}}/{{.Arch}}/kubectl{{.Ext}}"
+}
+
+func assertDownloaded(t *testing.T, destination string) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(destination)
+	if err != nil {
+		t.Fatalf("Expected binary to be downloaded: %v", err)
+	}
+	if string(data) != fakeKubectl {
+		t.Errorf("Got unexpected binary content %q", string(data))
+	}
+}
+
+func TestGetKubectlBinaryFromHTTPMirror(t *testing.T) {
+	s := newMirrorServer(t, sha256Of(fakeKubectl), nil)
+	destination := filepath.Join(t.TempDir(), "bin", "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+}
+
+func TestGetKubectlBinaryFallsBackToNextMirror(t *testing.T) {
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+	s := newMirrorServer(t, sha256Of(fakeKubectl)+"  kubectl", nil)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{
+		{URL: mirrorURL(broken.URL)},
+		{URL: mirrorURL(s.URL)},
+	})
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+}
+
+func TestGetKubectlBinaryShaMismatch(t *testing.T) {
+	setDuration(t, &retryInterval, 0)
+	s := newMirrorServer(t, sha256Of("something else"), nil)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination)
+	if !common.IsShaMismatch(err) {
+		t.Fatalf("Expected a sha mismatch error, got %v", err)
+	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Errorf("Binary with wrong checksum should not be installed")
+	}
+}
+
+func TestGetKubectlBinaryWithAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		mirror cmdconfig.KubectlMirror
+		auth   func(r *http.Request) bool
+	}{
+		{
+			name:   "basic",
+			mirror: cmdconfig.KubectlMirror{Username: "user", Password: "${EKE_TEST_MIRROR_PASSWORD}"},
+			auth: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "user" && p == "secret"
+			},
+		},
+		{
+			name:   "bearer",
+			mirror: cmdconfig.KubectlMirror{Token: "token"},
+			auth: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer token"
+			},
+		},
+	}
+	os.Setenv("EKE_TEST_MIRROR_PASSWORD", "secret")
+	defer os.Unsetenv("EKE_TEST_MIRROR_PASSWORD")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMirrorServer(t, sha256Of(fakeKubectl), tt.auth)
+			destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+			mirror := tt.mirror
+			mirror.URL = mirrorURL(s.URL)
+			d := newTestDownloder(t, []cmdconfig.KubectlMirror{mirror})
+			if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assertDownloaded(t, destination)
+		})
+	}
+}
+
+func TestGetKubectlBinaryFromFileMirror(t *testing.T) {
+	mirrorDir := t.TempDir()
+	dir := filepath.Join(mirrorDir, "1.22.3")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "kubectl"), []byte(fakeKubectl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte(sha256Of(fakeKubectl)+"  kubectl\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(mirrorDir, "stable.txt"), []byte("v1.22.3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := "file://" + filepath.ToSlash(mirrorDir)
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{
+		URL:         base + "/{{.Version}}/kubectl",
+		ChecksumURL: base + "/{{.Version}}/SHA256SUMS",
+		StableURL:   base + "/stable.txt",
+	}})
+
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+
+	v, err := d.UpstreamStableVersion()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !v.Equals(semver.MustParse("1.22.3")) {
+		t.Errorf("Got %s instead of 1.22.3", v)
+	}
+}
+
+func TestUpstreamStableVersionFromMirror(t *testing.T) {
+	s := newMirrorServer(t, "", nil)
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{
+		{URL: mirrorURL(s.URL)},
+		{URL: mirrorURL(s.URL), StableURL: s.URL + "/stable.txt"},
+	})
+	v, err := d.UpstreamStableVersion()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !v.Equals(semver.MustParse("1.23.4")) {
+		t.Errorf("Got %s instead of 1.23.4", v)
+	}
+}
+
+func TestUpstreamStableVersionWithoutStableURL(t *testing.T) {
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: "file:///nowhere/kubectl"}})
+	if _, err := d.UpstreamStableVersion(); err == nil {
+		t.Error("Expected an error when no mirror provides a stable url")
+	}
+}
+
+func TestLatestPatchVersion(t *testing.T) {
+	s := newMirrorServer(t, "", nil)
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{
+		{URL: mirrorURL(s.URL), StableURL: s.URL + "/latest"},
+		{URL: mirrorURL(s.URL), StableURL: s.URL + "/stable.txt"},
+	})
+	v, err := d.LatestPatchVersion(1, 22)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !v.Equals(semver.MustParse("1.22.9")) {
+		t.Errorf("Got %s instead of 1.22.9", v)
+	}
+
+	if _, err := d.LatestPatchVersion(1, 21); err == nil {
+		t.Error("Expected an error for a minor version the mirrors do not publish")
+	}
+	d = newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: "file:///nowhere/kubectl"}})
+	if _, err := d.LatestPatchVersion(1, 22); err == nil {
+		t.Error("Expected an error when no mirror provides a stable url")
+	}
+}
+
+func TestKubectlDownloadURL(t *testing.T) {
+	d := Downloder{}
+	binURL, shaURL, err := d.kubectlDownloadURL(DefaultMirror, semver.MustParse("1.18.0"), common.HostPlatform())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := fmt.Sprintf(
+		"https://storage.googleapis.com/kubernetes-release/release/v1.18.0/bin/%s/%s/kubectl%s",
+		runtime.GOOS, runtime.GOARCH, osexec.Ext)
+	if binURL != expected {
+		t.Errorf("Got %s instead of %s", binURL, expected)
+	}
+	if shaURL != expected+".sha256" {
+		t.Errorf("Got %s instead of %s", shaURL, expected+".sha256")
+	}
+}
@@ -0,0 +1,231 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"eke/internal/kubectlcmd/osexec"
+	"eke/internal/pkg/lockfile"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+// flakyMirror serves fakeKubectl, honoring Range requests. The first
+// `drops` binary requests are cut after half of the content
+type flakyMirror struct {
+	mu            sync.Mutex
+	drops         int
+	ignoreRange   bool
+	failures      int
+	delay         time.Duration
+	ranges        []string
+	fullDownloads int
+}
+
+func (m *flakyMirror) serveBinary(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.ranges = append(m.ranges, r.Header.Get("Range"))
+	if m.failures > 0 {
+		m.failures--
+		m.mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	drop := m.drops > 0
+	if drop {
+		m.drops--
+	}
+	m.mu.Unlock()
+
+	time.Sleep(m.delay)
+
+	content := fakeKubectl
+	var offset int
+	if rng := r.Header.Get("Range"); rng != "" && !m.ignoreRange {
+		if _, err := fmt.Sscanf(rng, "bytes=%d-", &offset); err != nil || offset >= len(fakeKubectl) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		content = fakeKubectl[offset:]
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(fakeKubectl)-1, len(fakeKubectl)))
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		m.mu.Lock()
+		m.fullDownloads++
+		m.mu.Unlock()
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	}
+
+	if !drop {
+		fmt.Fprint(w, content)
+		return
+	}
+
+	// send half of the content and drop the connection
+	fmt.Fprint(w, content[:len(content)/2])
+	w.(http.Flusher).Flush()
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func newFlakyMirrorServer(t *testing.T, m *flakyMirror) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(binPath("1.22.3"), m.serveBinary)
+	mux.HandleFunc(binPath("1.22.3")+".sha256", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, sha256Of(fakeKubectl))
+	})
+
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestGetKubectlBinaryResumesDroppedDownload(t *testing.T) {
+	setDuration(t, &retryInterval, 0)
+	m := &flakyMirror{drops: 1}
+	s := newFlakyMirrorServer(t, m)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+
+	expected := []string{"", fmt.Sprintf("bytes=%d-", len(fakeKubectl)/2)}
+	if strings.Join(m.ranges, ",") != strings.Join(expected, ",") {
+		t.Errorf("Got requests with ranges %q instead of %q", m.ranges, expected)
+	}
+	if m.fullDownloads != 1 {
+		t.Errorf("The binary has been downloaded from the start %d times", m.fullDownloads)
+	}
+}
+
+func TestGetKubectlBinaryResumesPreviousRun(t *testing.T) {
+	m := &flakyMirror{}
+	s := newFlakyMirrorServer(t, m)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	partial := filepath.Join(d.PartialDir, fmt.Sprintf("kubectl1.22.3-%s-%s%s.partial", runtime.GOOS, runtime.GOARCH, osexec.Ext))
+	if err := ioutil.WriteFile(partial, []byte(fakeKubectl[:5]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+	if len(m.ranges) != 1 || m.ranges[0] != "bytes=5-" {
+		t.Errorf("Expected the download to resume at byte 5, got ranges %q", m.ranges)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Error("Expected the partial download to be removed")
+	}
+}
+
+func TestGetKubectlBinaryRestartsWhenRangeIsIgnored(t *testing.T) {
+	setDuration(t, &retryInterval, 0)
+	m := &flakyMirror{drops: 1, ignoreRange: true}
+	s := newFlakyMirrorServer(t, m)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+	if m.fullDownloads != 2 {
+		t.Errorf("Expected the download to start over, got %d full downloads", m.fullDownloads)
+	}
+}
+
+func TestGetKubectlBinaryRetriesServerErrors(t *testing.T) {
+	setDuration(t, &retryInterval, 0)
+	m := &flakyMirror{failures: 2}
+	s := newFlakyMirrorServer(t, m)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertDownloaded(t, destination)
+	if len(m.ranges) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(m.ranges))
+	}
+}
+
+func TestGetKubectlBinaryConcurrentDownloads(t *testing.T) {
+	setDuration(t, &lockfile.PollInterval, 10*time.Millisecond)
+	m := &flakyMirror{delay: 100 * time.Millisecond}
+	s := newFlakyMirrorServer(t, m)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.GetKubectlBinary(semver.MustParse("1.22.3"), destination)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	assertDownloaded(t, destination)
+	if m.fullDownloads != 1 {
+		t.Errorf("Expected a single download, got %d", m.fullDownloads)
+	}
+}
+
+func TestGetKubectlBinaryConcurrentRedownloads(t *testing.T) {
+	setDuration(t, &lockfile.PollInterval, 10*time.Millisecond)
+	m := &flakyMirror{delay: 100 * time.Millisecond}
+	s := newFlakyMirrorServer(t, m)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+	if err := ioutil.WriteFile(destination, []byte("corrupted"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.GetKubectlBinary(semver.MustParse("1.22.3"), destination)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	assertDownloaded(t, destination)
+	if m.fullDownloads != 1 {
+		t.Errorf("Expected a single download, got %d", m.fullDownloads)
+	}
+}
@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"eke/internal/kubectlcmd/manifest"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+func newSignedMirrorServer(t *testing.T, priv ed25519.PrivateKey, checksum string) string {
+	s := newMirrorServer(t, sha256Of(fakeKubectl), nil)
+
+	m := manifest.Manifest{
+		manifest.KubectlEntry(semver.MustParse("1.22.3"), runtime.GOOS, runtime.GOARCH): checksum,
+	}
+	data := m.Bytes()
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+
+	mux := s.Config.Handler.(*http.ServeMux)
+	mux.HandleFunc("/manifests/v1.22.3/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+	mux.HandleFunc("/manifests/v1.22.3/SHA256SUMS.sig", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, signature)
+	})
+	return s.URL
+}
+
+func newSigningKey(t *testing.T) (string, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), priv
+}
+
+func TestGetKubectlBinaryWithSignedManifest(t *testing.T) {
+	pub, priv := newSigningKey(t)
+	otherPub, _ := newSigningKey(t)
+	setDuration(t, &retryInterval, 0)
+
+	tests := []struct {
+		name     string
+		checksum string
+		keys     []string
+		success  bool
+	}{
+		{"trusted signature", sha256Of(fakeKubectl), []string{otherPub, pub}, true},
+		{"untrusted signature", sha256Of(fakeKubectl), []string{otherPub}, false},
+		{"no trusted key", sha256Of(fakeKubectl), nil, false},
+		// the sibling .sha256 file matches, the manifest wins
+		{"checksum mismatch", sha256Of("something else"), []string{pub}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := newSignedMirrorServer(t, priv, tt.checksum)
+			destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+			d := newTestDownloder(t, []cmdconfig.KubectlMirror{{
+				URL:         mirrorURL(base),
+				ManifestURL: base + "/manifests/v{{.Version}}/SHA256SUMS",
+			}})
+			d.Verification.PublicKeys = tt.keys
+
+			err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination)
+			if tt.success {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				assertDownloaded(t, destination)
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected the download to be refused")
+			}
+			if _, err := os.Stat(destination); !os.IsNotExist(err) {
+				t.Error("Unverified binary should not be installed")
+			}
+		})
+	}
+}
+
+func TestGetKubectlBinaryRequireSignature(t *testing.T) {
+	s := newMirrorServer(t, sha256Of(fakeKubectl), nil)
+	destination := filepath.Join(t.TempDir(), "kubectl1.22.3")
+
+	d := newTestDownloder(t, []cmdconfig.KubectlMirror{{URL: mirrorURL(s.URL)}})
+	d.Verification.RequireSignature = true
+	if err := d.GetKubectlBinary(semver.MustParse("1.22.3"), destination); err == nil {
+		t.Error("Expected binaries without signed manifest to be refused")
+	}
+}
+
+func TestKubectlChecksum(t *testing.T) {
+	pub, priv := newSigningKey(t)
+	signed := newSignedMirrorServer(t, priv, sha256Of(fakeKubectl))
+	unsigned := newMirrorServer(t, sha256Of(fakeKubectl), nil)
+
+	tests := []struct {
+		name    string
+		mirrors []cmdconfig.KubectlMirror
+		signed  bool
+	}{
+		{"signed", []cmdconfig.KubectlMirror{{URL: mirrorURL(signed), ManifestURL: signed + "/manifests/v{{.Version}}/SHA256SUMS"}}, true},
+		{"unsigned", []cmdconfig.KubectlMirror{{URL: mirrorURL(unsigned.URL)}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDownloder(t, tt.mirrors)
+			d.Verification.PublicKeys = []string{pub}
+
+			checksum, isSigned, err := d.KubectlChecksum(semver.MustParse("1.22.3"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if checksum != sha256Of(fakeKubectl) || isSigned != tt.signed {
+				t.Errorf("Got %s, signed: %v", checksum, isSigned)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package fanout
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"eke/internal/pkg/debug"
+)
+
+// Result is the outcome of a command run against a kubeconfig context
+type Result struct {
+	Context  string
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	// Err is set when the command could not be run at all
+	Err error
+}
+
+// Failed returns true when the command could not be run or exited with
+// a non zero status
+func (r Result) Failed() bool {
+	return r.Err != nil || r.ExitCode != 0
+}
+
+// Runner runs the command against the given kubeconfig context
+type Runner func(ctx context.Context, kubeContext string) Result
+
+// Run invokes runner for every context, at most parallel of them at the
+// same time. Results are returned in the order of contexts
+func Run(ctx context.Context, contexts []string, parallel int, runner Runner) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]Result, len(contexts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, kubeContext := range contexts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, kubeContext string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i] = runner(ctx, kubeContext)
+			results[i].Context = kubeContext
+		}(i, kubeContext)
+	}
+	wg.Wait()
+	return results
+}
+
+// Command runs the binary at path with args and captures its output, it
+// is killed when ctx is done
+func Command(ctx context.Context, path string, args []string, env []string) Result {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	res := Result{}
+	span := debug.StartStep(debug.StepExec, path)
+	err := cmd.Run()
+	span.End()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		res.Err = err
+	}
+	res.Stdout = stdout.Bytes()
+	res.Stderr = stderr.Bytes()
+	return res
+}
+
+// ExitCode combines the exit statuses of the results: zero when all of
+// them succeeded, the status shared by all the failed ones otherwise, 1
+// when they differ
+func ExitCode(results []Result) int {
+	code := 0
+	for _, r := range results {
+		if !r.Failed() {
+			continue
+		}
+		c := r.ExitCode
+		if r.Err != nil {
+			c = 1
+		}
+		if code != 0 && code != c {
+			return 1
+		}
+		code = c
+	}
+	return code
+}
+
+// WritePrefixed writes the output of every result, each line prefixed
+// with its context. Errors go to stderr
+func WritePrefixed(stdout, stderr io.Writer, results []Result) {
+	width := 0
+	for _, r := range results {
+		if len(r.Context) > width {
+			width = len(r.Context)
+		}
+	}
+
+	for _, r := range results {
+		prefix := fmt.Sprintf("%-*s | ", width, r.Context)
+		writeLines(stdout, prefix, r.Stdout)
+		writeLines(stderr, prefix, r.Stderr)
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "%s%v\n", prefix, r.Err)
+		}
+	}
+}
+
+// WriteGrouped writes the output of every result below a header naming
+// its context. Errors go to stderr
+func WriteGrouped(stdout, stderr io.Writer, results []Result) {
+	for i, r := range results {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "=== %s\n", r.Context)
+		stdout.Write(r.Stdout)
+		stderr.Write(r.Stderr)
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", r.Context, r.Err)
+		}
+	}
+}
+
+// jsonResult is the JSON representation of a Result
+type jsonResult struct {
+	Context  string          `json:"context"`
+	ExitCode int             `json:"exitCode"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// WriteJSON writes a single JSON document wrapping the JSON output of
+// every result with its context. Outputs which are not valid JSON are
+// reported as errors
+func WriteJSON(w io.Writer, results []Result) error {
+	list := struct {
+		Items []jsonResult `json:"items"`
+	}{Items: []jsonResult{}}
+
+	for _, r := range results {
+		item := jsonResult{Context: r.Context, ExitCode: r.ExitCode}
+		var errs []string
+		if r.Err != nil {
+			errs = append(errs, r.Err.Error())
+		}
+		if msg := strings.TrimSpace(string(r.Stderr)); msg != "" {
+			errs = append(errs, msg)
+		}
+
+		out := bytes.TrimSpace(r.Stdout)
+		if len(out) > 0 {
+			if json.Valid(out) {
+				item.Result = out
+			} else {
+				errs = append(errs, "the output is not valid JSON: "+string(out))
+			}
+		}
+		item.Error = strings.Join(errs, "\n")
+		list.Items = append(list.Items, item)
+	}
+
+	data, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// WantsJSON returns true when the kubectl arguments ask for JSON output
+func WantsJSON(args []string) bool {
+	for i, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		switch arg {
+		case "-o", "--output":
+			if i+1 < len(args) && args[i+1] == "json" {
+				return true
+			}
+		case "-ojson", "-o=json", "--output=json":
+			return true
+		}
+	}
+	return false
+}
+
+func writeLines(w io.Writer, prefix string, data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fmt.Fprintf(w, "%s%s\n", prefix, scanner.Text())
+	}
+}
@@ -0,0 +1,171 @@
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunBoundsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	contexts := []string{"a", "b", "c", "d", "e"}
+	results := Run(context.Background(), contexts, 2, func(ctx context.Context, kubeContext string) Result {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return Result{Stdout: []byte(kubeContext)}
+	})
+
+	if maxRunning > 2 {
+		t.Errorf("Got %d commands running at the same time, expected at most 2", maxRunning)
+	}
+	for i, r := range results {
+		if r.Context != contexts[i] || string(r.Stdout) != contexts[i] {
+			t.Errorf("Got result %+v at position %d, expected context %s", r, i, contexts[i])
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []Result
+		expected int
+	}{
+		{"all succeeded", []Result{{}, {}}, 0},
+		{"same failure", []Result{{}, {ExitCode: 3}, {ExitCode: 3}}, 3},
+		{"different failures", []Result{{ExitCode: 2}, {ExitCode: 3}}, 1},
+		{"not run", []Result{{}, {Err: errors.New("no kubectl")}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ExitCode(tt.results); actual != tt.expected {
+				t.Errorf("Got %d instead of %d", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWritePrefixed(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	WritePrefixed(&stdout, &stderr, []Result{
+		{Context: "dev", Stdout: []byte("NAME\nnode-1\n")},
+		{Context: "prod", Stderr: []byte("forbidden\n"), ExitCode: 1},
+		{Context: "qa", Err: errors.New("no kubectl")},
+	})
+
+	expected := "dev  | NAME\ndev  | node-1\n"
+	if stdout.String() != expected {
+		t.Errorf("Got stdout %q instead of %q", stdout.String(), expected)
+	}
+	expected = "prod | forbidden\nqa   | no kubectl\n"
+	if stderr.String() != expected {
+		t.Errorf("Got stderr %q instead of %q", stderr.String(), expected)
+	}
+}
+
+func TestWriteGrouped(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	WriteGrouped(&stdout, &stderr, []Result{
+		{Context: "dev", Stdout: []byte("node-1\n")},
+		{Context: "prod", Stdout: []byte("node-2\n")},
+	})
+
+	expected := "=== dev\nnode-1\n\n=== prod\nnode-2\n"
+	if stdout.String() != expected {
+		t.Errorf("Got stdout %q instead of %q", stdout.String(), expected)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteJSON(&b, []Result{
+		{Context: "dev", Stdout: []byte(`{"kind": "List", "items": []}`)},
+		{Context: "prod", Stdout: []byte("not json"), Stderr: []byte("warning\n"), ExitCode: 1},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Items []struct {
+			Context  string                 `json:"context"`
+			ExitCode int                    `json:"exitCode"`
+			Result   map[string]interface{} `json:"result"`
+			Error    string                 `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", b.String(), err)
+	}
+	if len(doc.Items) != 2 {
+		t.Fatalf("Got %d items instead of 2", len(doc.Items))
+	}
+	if doc.Items[0].Context != "dev" || doc.Items[0].Result["kind"] != "List" || doc.Items[0].Error != "" {
+		t.Errorf("Unexpected first item %+v", doc.Items[0])
+	}
+	if doc.Items[1].ExitCode != 1 || doc.Items[1].Result != nil || doc.Items[1].Error != "warning\nthe output is not valid JSON: not json" {
+		t.Errorf("Unexpected second item %+v", doc.Items[1])
+	}
+}
+
+func TestWantsJSON(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"get", "pods", "-o", "json"}, true},
+		{[]string{"get", "pods", "-ojson"}, true},
+		{[]string{"get", "pods", "--output=json"}, true},
+		{[]string{"get", "pods", "-o", "yaml"}, false},
+		{[]string{"exec", "pod", "--", "cmd", "-o", "json"}, false},
+	}
+	for _, tt := range tests {
+		if actual := WantsJSON(tt.args); actual != tt.expected {
+			t.Errorf("Got %t instead of %t for %v", actual, tt.expected, tt.args)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported")
+	}
+
+	script := filepath.Join(t.TempDir(), "kubectl")
+	err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\necho oops >&2\nexit 3\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Command(context.Background(), script, []string{"--context", "dev", "get", "nodes"}, nil)
+	if r.Err != nil {
+		t.Fatalf("Unexpected error: %v", r.Err)
+	}
+	if string(r.Stdout) != "--context dev get nodes\n" || string(r.Stderr) != "oops\n" || r.ExitCode != 3 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+
+	r = Command(context.Background(), filepath.Join(t.TempDir(), "missing"), nil, nil)
+	if r.Err == nil || !r.Failed() {
+		t.Errorf("Expected an error running a missing binary, got %+v", r)
+	}
+}
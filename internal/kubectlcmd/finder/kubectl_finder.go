@@ -17,6 +17,16 @@ import (
 type KubectlFinder struct {
 	LocalBinaryPath string
 	SysBinaryPath   string
+	// SearchPaths are searched for system binaries on top of SysBinaryPath
+	SearchPaths []string
+	// SearchPATH enables the search of system binaries in the PATH
+	SearchPATH bool
+	// Prober finds out the version of the binaries named kubectl, they
+	// are ignored when nil
+	Prober versionProber
+	// Policy selects the compatible binaries, the skew policy is used
+	// when nil
+	Policy *VersionPolicy
 }
 
 // NewKubectlFinder returns a properly initialized KubectlFinder object
@@ -35,15 +45,54 @@ func NewKubectlFinder(local, sys string) *KubectlFinder {
 }
 
 // SystemKubectlBinaries returns the list of kubectl binaries that are
-// available to all the users of the system
+// available to all the users of the system, they are looked for in
+// SysBinaryPath, SearchPaths and, when enabled, the PATH directories.
+// Only the errors about SysBinaryPath are reported
 func (f *KubectlFinder) SystemKubectlBinaries() (KubectlBinaries, error) {
-	return findKubectlBinaries(f.SysBinaryPath)
+	bins, err := findKubectlBinaries(f.SysBinaryPath, f.Prober)
+	if err != nil {
+		return bins, err
+	}
+
+	for _, dir := range f.searchDirs() {
+		found, err := findKubectlBinaries(dir, f.Prober)
+		if err == nil {
+			bins = append(bins, found...)
+		}
+	}
+	return bins, nil
+}
+
+// searchDirs returns the directories searched on top of SysBinaryPath,
+// without duplicates
+func (f *KubectlFinder) searchDirs() []string {
+	dirs := append([]string{}, f.SearchPaths...)
+	if f.SearchPATH {
+		dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	}
+
+	seen := map[string]bool{
+		filepath.Clean(f.SysBinaryPath):   true,
+		filepath.Clean(f.LocalBinaryPath): true,
+	}
+	var res []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			res = append(res, dir)
+		}
+	}
+	return res
 }
 
 // LocalKubectlBinaries returns the list of kubectl binaries that are
 // available only to the user currently running kuberlr
 func (f *KubectlFinder) LocalKubectlBinaries() (KubectlBinaries, error) {
-	return findKubectlBinaries(f.LocalBinaryPath)
+	return findKubectlBinaries(f.LocalBinaryPath, nil)
 }
 
 // AllKubectlBinaries returns all the kubectl binaries available to the
@@ -67,29 +116,19 @@ func (f *KubectlFinder) AllKubectlBinaries(reverseSort bool) KubectlBinaries {
 }
 
 // FindCompatibleKubectl returns a kubectl binary compatible with the
-// version given via the `requestedVersion` parameter
+// version given via the `requestedVersion` parameter, according to the
+// policy of the finder
 func (f *KubectlFinder) FindCompatibleKubectl(requestedVersion semver.Version) (KubectlBinary, error) {
 	bins := f.AllKubectlBinaries(true)
 	if len(bins) == 0 {
 		return KubectlBinary{}, &common.NoVersionFoundError{}
 	}
 
-	lowerBound := lowerBoundVersion(requestedVersion)
-	upperBound := upperBoundVersion(requestedVersion)
-	rangeRule := fmt.Sprintf(">=%s <%s", lowerBound.String(), upperBound.String())
-
-	validRange, err := semver.ParseRange(rangeRule)
-	if err != nil {
-		return KubectlBinary{}, err
+	policy := f.Policy
+	if policy == nil {
+		policy = defaultPolicy
 	}
-
-	for _, b := range bins {
-		if validRange(b.Version) {
-			return b, nil
-		}
-	}
-
-	return KubectlBinary{}, &common.NoVersionFoundError{}
+	return policy.choose(bins, requestedVersion)
 }
 
 // MostRecentKubectlAvailable returns the most recent version of
@@ -144,7 +183,10 @@ func inferSystemKubectlVersion(filename string) (semver.Version, error) {
 	return semver.Version{}, errors.New("not parsable")
 }
 
-func findKubectlBinaries(path string) (KubectlBinaries, error) {
+// findKubectlBinaries returns the kubectl binaries inside of path. The
+// version is inferred from the name of the binary, prober is used for the
+// ones named kubectl
+func findKubectlBinaries(path string, prober versionProber) (KubectlBinaries, error) {
 	var binaries KubectlBinaries
 
 	kubectlBins, err := ioutil.ReadDir(path)
@@ -159,16 +201,20 @@ func findKubectlBinaries(path string) (KubectlBinaries, error) {
 		var sv semver.Version
 		var err error
 
+		binPath := filepath.Join(path, f.Name())
 		sv, err = inferLocalKubectlVersion(f.Name())
 		if err != nil {
 			sv, err = inferSystemKubectlVersion(f.Name())
-			if err != nil {
-				continue
-			}
+		}
+		if err != nil && prober != nil && osexec.TrimExt(f.Name()) == "kubectl" {
+			sv, err = probeKubectlVersion(binPath, prober)
+		}
+		if err != nil {
+			continue
 		}
 
 		bin := KubectlBinary{
-			Path:    filepath.Join(path, f.Name()),
+			Path:    binPath,
 			Version: sv,
 		}
 		binaries = append(binaries, bin)
@@ -177,6 +223,25 @@ func findKubectlBinaries(path string) (KubectlBinaries, error) {
 	return binaries, nil
 }
 
+// probeKubectlVersion asks prober the version of the binary at path. Eke
+// itself is skipped, it could be installed as kubectl
+func probeKubectlVersion(path string, prober versionProber) (semver.Version, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return semver.Version{}, errors.New("not a regular file")
+	}
+
+	if self, err := os.Executable(); err == nil {
+		if selfInfo, err := os.Stat(self); err == nil && os.SameFile(info, selfInfo) {
+			return semver.Version{}, errors.New("eke itself")
+		}
+	}
+	return prober.ProbeVersion(path, info)
+}
+
 func lowerBoundVersion(v semver.Version) semver.Version {
 	res := v
 
@@ -0,0 +1,77 @@
+package finder
+
+import (
+	"eke/internal/kubectlcmd/cache"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// KubectlStateFile is the name of the file, inside of the cache directory,
+// holding the default kubectl version and the last use of the binaries
+const KubectlStateFile = "kubectl-state.json"
+
+// InstalledKubectl describes a kubectl binary available on disk
+type InstalledKubectl struct {
+	KubectlBinary
+	Size    int64
+	ModTime time.Time
+	// LastUsed is zero when eke never ran the binary
+	LastUsed time.Time
+}
+
+// lastActivity returns the last time the binary has been used or, when
+// it never was, installed
+func (k InstalledKubectl) lastActivity() time.Time {
+	if k.LastUsed.IsZero() {
+		return k.ModTime
+	}
+	return k.LastUsed
+}
+
+// DescribeKubectlBinaries returns the size and the last use of the given
+// binaries, the ones that cannot be read are skipped
+func DescribeKubectlBinaries(bins KubectlBinaries, state cache.KubectlState) []InstalledKubectl {
+	var res []InstalledKubectl
+	for _, b := range bins {
+		info, err := os.Stat(b.Path)
+		if err != nil {
+			continue
+		}
+		res = append(res, InstalledKubectl{
+			KubectlBinary: b,
+			Size:          info.Size(),
+			ModTime:       info.ModTime(),
+			LastUsed:      state.LastUsed[b.Path],
+		})
+	}
+	return res
+}
+
+// PruneCandidates returns the binaries that can be removed: all but the
+// keep most recently used ones, which have not been used for unusedFor.
+// Binaries never used are considered used when installed. The binaries
+// with the default version are never pruned
+func PruneCandidates(bins []InstalledKubectl, keep int, unusedFor time.Duration, now time.Time, defaultVersion *semver.Version) []InstalledKubectl {
+	sorted := append([]InstalledKubectl{}, bins...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].lastActivity().After(sorted[j].lastActivity())
+	})
+
+	var res []InstalledKubectl
+	for i, b := range sorted {
+		if i < keep {
+			continue
+		}
+		if defaultVersion != nil && b.Version.Equals(*defaultVersion) {
+			continue
+		}
+		if now.Sub(b.lastActivity()) < unusedFor {
+			continue
+		}
+		res = append(res, b)
+	}
+	return res
+}
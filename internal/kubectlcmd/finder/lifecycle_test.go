@@ -0,0 +1,114 @@
+package finder
+
+import (
+	"testing"
+	"time"
+
+	"eke/internal/kubectlcmd/cache"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	installed := func(version string, installedAgo, usedAgo time.Duration) InstalledKubectl {
+		k := InstalledKubectl{
+			KubectlBinary: KubectlBinary{Path: "kubectl" + version, Version: semver.MustParse(version)},
+			ModTime:       now.Add(-installedAgo),
+		}
+		if usedAgo > 0 {
+			k.LastUsed = now.Add(-usedAgo)
+		}
+		return k
+	}
+	bins := []InstalledKubectl{
+		installed("1.20.0", 400*day, 200*day),
+		installed("1.21.0", 300*day, 1*day),
+		installed("1.22.0", 100*day, 0),
+		installed("1.23.0", 10*day, 0),
+		installed("1.24.0", 5*day, 100*day),
+	}
+	defaultVersion := semver.MustParse("1.20.0")
+
+	tests := []struct {
+		name           string
+		keep           int
+		unusedFor      time.Duration
+		defaultVersion *semver.Version
+		expected       []string
+	}{
+		{"everything", 0, 0, nil, []string{"1.21.0", "1.23.0", "1.22.0", "1.24.0", "1.20.0"}},
+		{"keep most recently used", 2, 0, nil, []string{"1.22.0", "1.24.0", "1.20.0"}},
+		{"unused for", 0, 90 * day, nil, []string{"1.22.0", "1.24.0", "1.20.0"}},
+		{"keep and unused for", 3, 90 * day, nil, []string{"1.24.0", "1.20.0"}},
+		{"default is kept", 3, 90 * day, &defaultVersion, []string{"1.24.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := PruneCandidates(bins, tt.keep, tt.unusedFor, now, tt.defaultVersion)
+			if len(actual) != len(tt.expected) {
+				t.Fatalf("Got %+v instead of %v", actual, tt.expected)
+			}
+			for i, v := range tt.expected {
+				if !actual[i].Version.Equals(semver.MustParse(v)) {
+					t.Errorf("Got %s instead of %s at position %d", actual[i].Version, v, i)
+				}
+			}
+		})
+	}
+}
+
+type mockStateStore struct {
+	state cache.KubectlState
+	used  []string
+}
+
+func (m *mockStateStore) Load() (cache.KubectlState, error) {
+	return m.state, nil
+}
+
+func (m *mockStateStore) MarkUsed(path string) error {
+	m.used = append(m.used, path)
+	return nil
+}
+
+func TestKubectlVersionToUseTimeoutFallsBackToDefaultVersion(t *testing.T) {
+	versioner, _ := newVersionerWithCache("", false, func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, &mockTimeoutError{}
+	})
+	defaultVersion := semver.MustParse("1.21.4")
+	versioner.state = &mockStateStore{state: cache.KubectlState{Default: &defaultVersion}}
+
+	actual, err := versioner.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !actual.Equals(defaultVersion) {
+		t.Errorf("Got %s instead of the default version %s", actual, defaultVersion)
+	}
+}
+
+func TestKubectlToUseRecordsUse(t *testing.T) {
+	versioner, _ := newVersionerWithCache("1.22.3", true, nil)
+	state := &mockStateStore{}
+	versioner.state = state
+	versioner.kFinder.(*mockFinder).findCompatibleKubectl = func(v semver.Version) (KubectlBinary, error) {
+		return KubectlBinary{Path: "/usr/bin/kubectl1.22", Version: semver.MustParse("1.22.0")}, nil
+	}
+
+	if _, err := versioner.KubectlToUse(1, false); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if len(state.used) != 1 || state.used[0] != "/usr/bin/kubectl1.22" {
+		t.Errorf("Unexpected uses recorded: %v", state.used)
+	}
+
+	if _, err := versioner.ExplainKubectlToUse(1, false); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if len(state.used) != 1 {
+		t.Errorf("Explaining the choice should not record a use: %v", state.used)
+	}
+}
@@ -0,0 +1,115 @@
+package finder
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+// kubectlPin is a parsed cmdconfig.KubectlPin
+type kubectlPin struct {
+	rule cmdconfig.KubectlPin
+	// exact is set when the pin refers to a single version
+	exact *semver.Version
+	valid semver.Range
+}
+
+func parsePin(rule cmdconfig.KubectlPin) (*kubectlPin, error) {
+	if rule.Context == "" && rule.Server == "" {
+		return nil, fmt.Errorf("invalid kubectl pin %s: either context or server must be set", describePin(rule))
+	}
+
+	valid, exact, err := ParseVersionConstraint(rule.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubectl pin %s: %v", describePin(rule), err)
+	}
+	return &kubectlPin{
+		rule:  rule,
+		exact: exact,
+		valid: valid,
+	}, nil
+}
+
+// ParseVersionConstraint parses either an exact version, with or without
+// the 'v' prefix, or a semver range. The version is returned only when
+// the constraint is an exact version
+func ParseVersionConstraint(constraint string) (semver.Range, *semver.Version, error) {
+	if v, err := semver.Parse(strings.TrimPrefix(constraint, "v")); err == nil {
+		return func(other semver.Version) bool { return other.Equals(v) }, &v, nil
+	}
+
+	valid, err := semver.ParseRange(constraint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return valid, nil, nil
+}
+
+// matches returns true when the pin applies to the given context and server
+func (p *kubectlPin) matches(context, server string) bool {
+	if p.rule.Context != "" && !globMatch(p.rule.Context, context) {
+		return false
+	}
+	if p.rule.Server != "" && !serverMatch(p.rule.Server, server) {
+		return false
+	}
+	return true
+}
+
+func globMatch(pattern, name string) bool {
+	if name == "" {
+		return false
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// serverMatch matches a pattern against the URL of an API server. Patterns
+// with a scheme are matched against the whole URL, * matching / as well,
+// the other ones against the host, with or without its port
+func serverMatch(pattern, server string) bool {
+	if strings.Contains(pattern, "://") {
+		// path.Match does not let * cross the / it separates elements with
+		return globMatch(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(server, "/", "\x00"))
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return globMatch(pattern, u.Host) || globMatch(pattern, u.Hostname())
+}
+
+func (p *kubectlPin) String() string {
+	return describePin(p.rule)
+}
+
+func describePin(rule cmdconfig.KubectlPin) string {
+	var keys []string
+	if rule.Context != "" {
+		keys = append(keys, fmt.Sprintf("context=%s", rule.Context))
+	}
+	if rule.Server != "" {
+		keys = append(keys, fmt.Sprintf("server=%s", rule.Server))
+	}
+	keys = append(keys, fmt.Sprintf("version=%q", rule.Version))
+	return "{" + strings.Join(keys, " ") + "}"
+}
+
+// findPin returns the first pin matching the given context and server
+func findPin(rules []cmdconfig.KubectlPin, context, server string) (*kubectlPin, error) {
+	for _, rule := range rules {
+		pin, err := parsePin(rule)
+		if err != nil {
+			return nil, err
+		}
+		if pin.matches(context, server) {
+			return pin, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,163 @@
+package finder
+
+import (
+	"eke/internal/kubectlcmd/common"
+	"eke/pkg/config/cmdconfig"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+)
+
+// Policies driving the choice of the kubectl binary compatible with a server
+const (
+	// PolicySkew accepts binaries within one minor version of the server,
+	// the newest one is used
+	PolicySkew = "skew"
+	// PolicyExactMinor accepts only binaries with the minor version of the
+	// server, the newest patch release is used
+	PolicyExactMinor = "exact-minor"
+	// PolicyExactPatch accepts only binaries with the version of the server
+	PolicyExactPatch = "exact-patch"
+	// PolicyNewestCompatible accepts binaries up to one minor version older
+	// than the server but never newer than it, the newest one is used
+	PolicyNewestCompatible = "newest-compatible"
+	// PolicyOldestCompatible accepts the same binaries as PolicySkew, the
+	// oldest one is used
+	PolicyOldestCompatible = "oldest-compatible"
+)
+
+type versionConstraint struct {
+	raw   string
+	valid semver.Range
+}
+
+// VersionPolicy decides which kubectl binaries can be used against a
+// server, see cmdconfig.KubectlPolicy
+type VersionPolicy struct {
+	Mode  string
+	allow []versionConstraint
+	deny  []versionConstraint
+}
+
+// NewVersionPolicy validates the given configuration and returns the
+// matching policy. An empty configuration results in the skew policy
+func NewVersionPolicy(config cmdconfig.KubectlPolicy) (*VersionPolicy, error) {
+	p := &VersionPolicy{Mode: config.Mode}
+	switch p.Mode {
+	case "":
+		p.Mode = PolicySkew
+	case PolicySkew, PolicyExactMinor, PolicyExactPatch, PolicyNewestCompatible, PolicyOldestCompatible:
+	default:
+		return nil, fmt.Errorf("unknown kubectl policy %q", config.Mode)
+	}
+
+	var err error
+	if p.allow, err = parseVersionConstraints(config.Allow); err != nil {
+		return nil, fmt.Errorf("invalid kubectl policy allowlist: %v", err)
+	}
+	if p.deny, err = parseVersionConstraints(config.Deny); err != nil {
+		return nil, fmt.Errorf("invalid kubectl policy denylist: %v", err)
+	}
+	return p, nil
+}
+
+func parseVersionConstraints(constraints []string) ([]versionConstraint, error) {
+	var res []versionConstraint
+	for _, c := range constraints {
+		valid, _, err := ParseVersionConstraint(c)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", c, err)
+		}
+		res = append(res, versionConstraint{raw: c, valid: valid})
+	}
+	return res, nil
+}
+
+// defaultPolicy is used when no policy has been configured
+var defaultPolicy = &VersionPolicy{Mode: PolicySkew}
+
+// Allows checks the given version against the allowlist and the denylist,
+// the reason of a refusal is returned as well
+func (p *VersionPolicy) Allows(v semver.Version) (bool, string) {
+	for _, c := range p.deny {
+		if c.valid(v) {
+			return false, fmt.Sprintf("denied by %q", c.raw)
+		}
+	}
+	if len(p.allow) == 0 {
+		return true, ""
+	}
+	for _, c := range p.allow {
+		if c.valid(v) {
+			return true, ""
+		}
+	}
+	return false, "not in the allowlist"
+}
+
+// window returns the range of the versions compatible with the requested
+// one, ignoring the allowlist and the denylist
+func (p *VersionPolicy) window(requested semver.Version) string {
+	switch p.Mode {
+	case PolicyExactPatch:
+		return fmt.Sprintf(">=%d.%d.%d <%d.%d.%d",
+			requested.Major, requested.Minor, requested.Patch,
+			requested.Major, requested.Minor, requested.Patch+1)
+	case PolicyExactMinor:
+		return fmt.Sprintf(">=%d.%d.0 <%d.%d.0",
+			requested.Major, requested.Minor,
+			requested.Major, requested.Minor+1)
+	case PolicyNewestCompatible:
+		return fmt.Sprintf(">=%s <%d.%d.0",
+			lowerBoundVersion(requested),
+			requested.Major, requested.Minor+1)
+	default:
+		return fmt.Sprintf(">=%s <%s",
+			lowerBoundVersion(requested),
+			upperBoundVersion(requested))
+	}
+}
+
+// choose returns the binary to use against the requested version. bins
+// must be sorted from the newest to the oldest. When nothing matches the
+// returned error lists why each binary has been rejected
+func (p *VersionPolicy) choose(bins KubectlBinaries, requested semver.Version) (KubectlBinary, error) {
+	rangeRule := p.window(requested)
+	validRange, err := semver.ParseRange(rangeRule)
+	if err != nil {
+		return KubectlBinary{}, err
+	}
+
+	var accepted KubectlBinaries
+	var rejected []common.RejectedKubectl
+	for _, b := range bins {
+		reason := ""
+		if !validRange(b.Version) {
+			reason = fmt.Sprintf("outside of the %s range", rangeRule)
+		} else if ok, why := p.Allows(b.Version); !ok {
+			reason = why
+		}
+
+		if reason == "" {
+			accepted = append(accepted, b)
+			continue
+		}
+		rejected = append(rejected, common.RejectedKubectl{
+			Path:    b.Path,
+			Version: b.Version.String(),
+			Reason:  reason,
+		})
+	}
+
+	if len(accepted) == 0 {
+		return KubectlBinary{}, &common.IncompatibleVersionsError{
+			Version:  requested.String(),
+			Policy:   p.Mode,
+			Rejected: rejected,
+		}
+	}
+	if p.Mode == PolicyOldestCompatible {
+		return accepted[len(accepted)-1], nil
+	}
+	return accepted[0], nil
+}
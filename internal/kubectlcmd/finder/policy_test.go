@@ -0,0 +1,135 @@
+package finder
+
+import (
+	"strings"
+	"testing"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+func policyTestBinaries() KubectlBinaries {
+	bins := fakeKubectlBinaries(
+		"/home/user/.eke/bin",
+		[]string{"1.24.1", "1.23.5", "1.23.2", "1.22.7", "1.21.3"},
+		&localKubectlNamer{})
+	SortKubectlByVersion(bins, true)
+	return bins
+}
+
+func TestVersionPolicyChoose(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   cmdconfig.KubectlPolicy
+		server   string
+		expected string
+	}{
+		{"default", cmdconfig.KubectlPolicy{}, "1.23.0", "1.24.1"},
+		{"skew", cmdconfig.KubectlPolicy{Mode: PolicySkew}, "1.23.0", "1.24.1"},
+		{"exact minor", cmdconfig.KubectlPolicy{Mode: PolicyExactMinor}, "1.23.0", "1.23.5"},
+		{"exact patch", cmdconfig.KubectlPolicy{Mode: PolicyExactPatch}, "1.23.2", "1.23.2"},
+		{"newest compatible", cmdconfig.KubectlPolicy{Mode: PolicyNewestCompatible}, "1.23.0", "1.23.5"},
+		{"oldest compatible", cmdconfig.KubectlPolicy{Mode: PolicyOldestCompatible}, "1.23.0", "1.22.7"},
+		{"denylist", cmdconfig.KubectlPolicy{Deny: []string{"1.24.1", "1.23.5"}}, "1.23.0", "1.23.2"},
+		{"denylist range", cmdconfig.KubectlPolicy{Mode: PolicyExactMinor, Deny: []string{">=1.23.3"}}, "1.23.0", "1.23.2"},
+		{"allowlist", cmdconfig.KubectlPolicy{Allow: []string{"1.22.x"}}, "1.23.0", "1.22.7"},
+		{"allowlist with prefix", cmdconfig.KubectlPolicy{Allow: []string{"v1.23.2"}}, "1.23.0", "1.23.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewVersionPolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			actual, err := p.choose(policyTestBinaries(), semver.MustParse(tt.server))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !actual.Version.Equals(semver.MustParse(tt.expected)) {
+				t.Errorf("Got %s instead of %s", actual.Version, tt.expected)
+			}
+		})
+	}
+}
+
+func TestVersionPolicyExplainsRejections(t *testing.T) {
+	p, err := NewVersionPolicy(cmdconfig.KubectlPolicy{
+		Mode: PolicyExactMinor,
+		Deny: []string{"1.23.5"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bins := policyTestBinaries()[:2]
+
+	_, err = p.choose(bins, semver.MustParse("1.23.0"))
+	if !common.IsNoVersionFound(err) {
+		t.Fatalf("Expected a no version found error, got %v", err)
+	}
+
+	incompatible, ok := err.(*common.IncompatibleVersionsError)
+	if !ok {
+		t.Fatalf("Unexpected error type %T", err)
+	}
+	expected := []common.RejectedKubectl{
+		{Path: bins[0].Path, Version: "1.24.1", Reason: "outside of the >=1.23.0 <1.24.0 range"},
+		{Path: bins[1].Path, Version: "1.23.5", Reason: `denied by "1.23.5"`},
+	}
+	if len(incompatible.Rejected) != len(expected) {
+		t.Fatalf("Got %+v instead of %+v", incompatible.Rejected, expected)
+	}
+	for i := range expected {
+		if incompatible.Rejected[i] != expected[i] {
+			t.Errorf("Got %+v instead of %+v", incompatible.Rejected[i], expected[i])
+		}
+	}
+	if !strings.Contains(err.Error(), bins[1].Path+` (1.23.5): denied by "1.23.5"`) {
+		t.Errorf("Rejections missing from the error message: %v", err)
+	}
+}
+
+func TestInvalidVersionPolicy(t *testing.T) {
+	invalid := []cmdconfig.KubectlPolicy{
+		{Mode: "closest"},
+		{Allow: []string{"not a version"}},
+		{Deny: []string{"1.2.3", ">>1"}},
+	}
+
+	for _, policy := range invalid {
+		if _, err := NewVersionPolicy(policy); err == nil {
+			t.Errorf("Expected %+v to be rejected", policy)
+		}
+	}
+}
+
+func TestEnsureCompatibleKubectlAvailableDeniedVersion(t *testing.T) {
+	finderMock := mockFinder{}
+	finderMock.findCompatibleKubectl = func(v semver.Version) (KubectlBinary, error) {
+		return KubectlBinary{}, &common.NoVersionFoundError{}
+	}
+
+	downloaderMock := mockDownloader{}
+	downloaderMock.getKubectlBinary = func(semver.Version, string) error {
+		t.Error("Denied version should not be downloaded")
+		return nil
+	}
+
+	policy, err := NewVersionPolicy(cmdconfig.KubectlPolicy{Deny: []string{"1.22.x"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	versioner := Versioner{
+		kFinder:    &finderMock,
+		downloader: &downloaderMock,
+		Policy:     policy,
+	}
+
+	_, err = versioner.EnsureCompatibleKubectlAvailable(semver.MustParse("1.22.3"), true)
+	if err == nil || !strings.Contains(err.Error(), `denied by "1.22.x"`) {
+		t.Errorf("Expected the download to be denied, got %v", err)
+	}
+}
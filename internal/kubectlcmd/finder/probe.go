@@ -0,0 +1,99 @@
+package finder
+
+import (
+	"context"
+	"eke/internal/kubectlcmd/cache"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// KubectlVersionCacheFile is the name of the file, inside of the cache
+// directory, holding the versions reported by the kubectl binaries
+const KubectlVersionCacheFile = "kubectl-versions.json"
+
+// probeTimeout bounds the time a kubectl binary has to report its version
+var probeTimeout = 5 * time.Second
+
+type versionProber interface {
+	ProbeVersion(path string, info os.FileInfo) (semver.Version, error)
+}
+
+type binaryVersionCache interface {
+	Get(path string, info os.FileInfo) (cache.BinaryVersion, bool)
+	Set(path string, info os.FileInfo, version semver.Version) error
+	SetError(path string, info os.FileInfo, probeErr error) error
+}
+
+// KubectlProber finds out the version of kubectl binaries that do not
+// have it in their name by running `kubectl version --client`. Answers
+// and failures are cached until the binary changes
+type KubectlProber struct {
+	cache binaryVersionCache
+	run   func(path string) ([]byte, error)
+}
+
+// NewKubectlProber returns a KubectlProber caching the versions in the
+// given file
+func NewKubectlProber(cachePath string) *KubectlProber {
+	return &KubectlProber{
+		cache: cache.NewBinaryVersions(cachePath),
+		run:   runKubectlVersion,
+	}
+}
+
+// ProbeVersion returns the version of the kubectl binary at path, info
+// describes the binary
+func (p *KubectlProber) ProbeVersion(path string, info os.FileInfo) (semver.Version, error) {
+	if entry, found := p.cache.Get(path, info); found {
+		if entry.Error != "" {
+			return semver.Version{}, fmt.Errorf("cannot get the version of %s: %s", path, entry.Error)
+		}
+		return entry.Version, nil
+	}
+
+	out, err := p.run(path)
+	version := semver.Version{}
+	if err == nil {
+		version, err = parseClientVersion(out)
+	}
+	// failing to cache only means probing again next time. Failures are
+	// cached as well, a broken binary is not run on every lookup
+	if err != nil {
+		_ = p.cache.SetError(path, info, err)
+		return semver.Version{}, fmt.Errorf("cannot get the version of %s: %v", path, err)
+	}
+	_ = p.cache.Set(path, info, version)
+	return version, nil
+}
+
+func runKubectlVersion(path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, path, "version", "--client", "-o", "json").Output()
+}
+
+// parseClientVersion extracts the version from the output of
+// `kubectl version --client -o json`. Vendor suffixes like "-eks-1234"
+// are dropped, only the major, minor and patch versions are kept
+func parseClientVersion(out []byte) (semver.Version, error) {
+	var info struct {
+		ClientVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"clientVersion"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return semver.Version{}, err
+	}
+
+	v, err := semver.ParseTolerant(info.ClientVersion.GitVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}, nil
+}
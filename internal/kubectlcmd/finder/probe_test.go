@@ -0,0 +1,191 @@
+package finder
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eke/internal/kubectlcmd/cache"
+	"eke/internal/kubectlcmd/osexec"
+
+	"github.com/blang/semver/v4"
+)
+
+type mockProber struct {
+	versions map[string]string
+}
+
+func (m *mockProber) ProbeVersion(path string, info os.FileInfo) (semver.Version, error) {
+	v, found := m.versions[path]
+	if !found {
+		return semver.Version{}, errors.New("unknown binary")
+	}
+	return semver.MustParse(v), nil
+}
+
+func writeFakeKubectl(t *testing.T, dir string) string {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "kubectl"+osexec.Ext)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseClientVersion(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{`{"clientVersion": {"major": "1", "minor": "22", "gitVersion": "v1.22.3"}}`, "1.22.3"},
+		{`{"clientVersion": {"gitVersion": "v1.21.14-eks-fb459a0"}, "kustomizeVersion": "v4.5.4"}`, "1.21.14"},
+	}
+
+	for _, tt := range tests {
+		actual, err := parseClientVersion([]byte(tt.output))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !actual.Equals(semver.MustParse(tt.expected)) {
+			t.Errorf("Got %s instead of %s", actual, tt.expected)
+		}
+	}
+
+	if _, err := parseClientVersion([]byte(`Client Version: v1.22.3`)); err == nil {
+		t.Error("Expected an error when the output is not json")
+	}
+}
+
+func TestKubectlProberCachesVersions(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := writeFakeKubectl(t, filepath.Join(dir, "bin"))
+
+	runs := 0
+	prober := &KubectlProber{
+		cache: cache.NewBinaryVersions(filepath.Join(dir, "cache", KubectlVersionCacheFile)),
+		run: func(path string) ([]byte, error) {
+			runs++
+			return []byte(`{"clientVersion": {"gitVersion": "v1.22.3"}}`), nil
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		info, err := os.Stat(kubectl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := prober.ProbeVersion(kubectl, info)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !version.Equals(semver.MustParse("1.22.3")) {
+			t.Errorf("Got %s instead of 1.22.3", version)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("kubectl has been run %d times instead of once", runs)
+	}
+}
+
+func TestKubectlProberCachesFailures(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := writeFakeKubectl(t, filepath.Join(dir, "bin"))
+
+	runs := 0
+	prober := &KubectlProber{
+		cache: cache.NewBinaryVersions(filepath.Join(dir, "cache", KubectlVersionCacheFile)),
+		run: func(path string) ([]byte, error) {
+			runs++
+			return []byte(`Client Version: v1.22.3`), nil
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		info, err := os.Stat(kubectl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := prober.ProbeVersion(kubectl, info); err == nil {
+			t.Error("Expected an error when the output is not json")
+		}
+	}
+	if runs != 1 {
+		t.Errorf("kubectl has been run %d times instead of once", runs)
+	}
+
+	// the binary is replaced
+	if err := ioutil.WriteFile(kubectl, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(kubectl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prober.ProbeVersion(kubectl, info)
+	if runs != 2 {
+		t.Errorf("Expected the replaced binary to be probed again")
+	}
+}
+
+func TestSystemKubectlBinariesSearchPaths(t *testing.T) {
+	td, err := setupFilesystemTest()
+	if err != nil {
+		t.Fatalf("Unexpeted failure: %v", err)
+	}
+	defer teardownFilesystemTest(td)
+
+	searchDir := filepath.Join(td.FakeSysBinPath, "search")
+	pathDir := filepath.Join(td.FakeSysBinPath, "path")
+	unknownDir := filepath.Join(td.FakeSysBinPath, "unknown")
+	searched := writeFakeKubectl(t, searchDir)
+	inPath := writeFakeKubectl(t, pathDir)
+	writeFakeKubectl(t, unknownDir)
+	named := fakeKubectlBinaries(pathDir, []string{"1.20.0"}, &systemKubectlNamer{})
+	if err := createFakeKubectlBinaries(named); err != nil {
+		t.Fatal(err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", pathDir+string(os.PathListSeparator)+unknownDir+string(os.PathListSeparator)+searchDir)
+
+	td.Finder.SearchPaths = []string{searchDir, filepath.Join(td.FakeSysBinPath, "missing")}
+	td.Finder.Prober = &mockProber{versions: map[string]string{
+		searched: "1.22.3",
+		inPath:   "1.21.2",
+	}}
+
+	bins, err := td.Finder.SystemKubectlBinaries()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(bins) != 1 || bins[0].Path != searched {
+		t.Errorf("Expected only %s to be found, got %+v", searched, bins)
+	}
+
+	td.Finder.SearchPATH = true
+	bins, err = td.Finder.SystemKubectlBinaries()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	SortKubectlByVersion(bins, true)
+	expected := KubectlBinaries{
+		{Path: searched, Version: semver.MustParse("1.22.3")},
+		{Path: inPath, Version: semver.MustParse("1.21.2")},
+		named[0],
+	}
+	if len(bins) != len(expected) {
+		t.Fatalf("Got %+v instead of %+v", bins, expected)
+	}
+	for i := range expected {
+		if bins[i].Path != expected[i].Path || !bins[i].Version.Equals(expected[i].Version) {
+			t.Errorf("Got %+v instead of %+v", bins[i], expected[i])
+		}
+	}
+}
@@ -1,17 +1,24 @@
 package finder
 
 import (
+	"eke/internal/kubectlcmd/cache"
 	"eke/internal/kubectlcmd/common"
 	"eke/internal/kubectlcmd/downloader"
 	"eke/internal/kubectlcmd/kubehelper"
+	"eke/internal/pkg/debug"
+	"eke/internal/pkg/logging"
+	"eke/pkg/config/cmdconfig"
 	"errors"
+	"fmt"
 	"path/filepath"
-
-	"log"
+	"time"
 
 	"github.com/blang/semver/v4"
 )
 
+// logger writes the messages of the kubectl subsystem
+var logger = logging.For(logging.Kubectl)
+
 type downloadHelper interface {
 	GetKubectlBinary(version semver.Version, destination string) error
 	UpstreamStableVersion() (semver.Version, error)
@@ -19,6 +26,19 @@ type downloadHelper interface {
 
 type kubeAPIHelper interface {
 	Version(timeout int64) (semver.Version, error)
+	ServerURL() (string, error)
+	ContextName() (string, error)
+}
+
+type serverVersionCache interface {
+	Get(server string) (cache.ServerVersion, bool)
+	Set(server string, version semver.Version) error
+	IsFresh(entry cache.ServerVersion) bool
+}
+
+type kubectlStateStore interface {
+	Load() (cache.KubectlState, error)
+	MarkUsed(path string) error
 }
 
 type iFinder interface {
@@ -29,71 +49,330 @@ type iFinder interface {
 	MostRecentKubectlAvailable() (KubectlBinary, error)
 }
 
+var errDownloadDisabled = errors.New("the right kubectl is missing, binary downloads from kubernetes' upstream mirror are disabled")
+
+// ServerVersionCacheFile is the name of the file, inside of the cache
+// directory, holding the last known version of the API servers
+const ServerVersionCacheFile = "server-versions.json"
+
 // Versioner is used to manage the local kubectl binaries used by kuberlr
 type Versioner struct {
-	kFinder    iFinder
-	downloader downloadHelper
-	apiServer  kubeAPIHelper
+	kFinder      iFinder
+	downloader   downloadHelper
+	apiServer    kubeAPIHelper
+	versionCache serverVersionCache
+	pins         []cmdconfig.KubectlPin
+	state        kubectlStateStore
+
+	// Policy restricts the versions that can be downloaded, it should be
+	// the one used by the finder. The skew policy is used when nil
+	Policy *VersionPolicy
+	// RefreshInBackground is invoked when a stale cached server version
+	// is used, it is expected to update the cache without blocking
+	RefreshInBackground func()
 }
 
-// NewVersioner is an helper function that creates a new Versioner instance
-func NewVersioner(f iFinder) *Versioner {
+// NewVersioner is an helper function that creates a new Versioner instance.
+// kubectlArgs are the arguments kubectl is going to be invoked with
+func NewVersioner(f iFinder, config cmdconfig.EkeKubectlConfig, kubectlArgs []string) *Versioner {
 	return &Versioner{
 		kFinder:    f,
-		downloader: &downloader.Downloder{},
-		apiServer:  &kubehelper.KubeAPI{},
+		downloader: downloader.NewDownloderFromConfig(config),
+		apiServer:  &kubehelper.KubeAPI{Args: kubectlArgs},
+		versionCache: cache.NewServerVersions(
+			filepath.Join(common.CacheDir(), ServerVersionCacheFile),
+			time.Duration(config.ServerVersionCacheTTL)*time.Second),
+		pins:  config.Pins,
+		state: cache.NewKubectlStateFile(filepath.Join(common.CacheDir(), KubectlStateFile)),
 	}
 }
 
-// KubectlVersionToUse returns the kubectl version to be used to interact with
-// the remote server. The method takes into account different failure scenarios
-// and acts accordingly.
-func (v *Versioner) KubectlVersionToUse(timeout int64) (semver.Version, error) {
-	version, err := v.apiServer.Version(timeout)
-	if err != nil {
-		if isTimeout(err) {
-			// the remote server is unreachable, let's get
-			// the latest version of kubectl that is available on the system
-			log.Println("Remote kubernetes server unreachable")
-		} else {
-			log.Println(err)
+// Selection describes the kubectl binary picked to run against a cluster
+type Selection struct {
+	Path    string
+	Version semver.Version
+	// Reasons explain, step by step, why the binary was picked
+	Reasons []string
+}
+
+// KubectlToUse returns the kubectl binary to run against the remote server,
+// downloading it when needed and allowed. Pinned versions take precedence
+// over the version of the server. The use of the binary is recorded.
+func (v *Versioner) KubectlToUse(timeout int64, allowDownload bool) (Selection, error) {
+	sel, err := v.selectKubectl(timeout, allowDownload, false)
+	if err == nil && v.state != nil {
+		if err := v.state.MarkUsed(sel.Path); err != nil {
+			logger.Warnf("Cannot record the use of %s: %v", sel.Path, err)
 		}
-		kubectl, err := v.kFinder.MostRecentKubectlAvailable()
-		if err == nil {
-			return kubectl.Version, nil
-		} else if common.IsNoVersionFound(err) {
-			log.Println("No local kubectl binary found, fetching latest stable release version")
-			return v.downloader.UpstreamStableVersion()
+	}
+	return sel, err
+}
+
+// ExplainKubectlToUse works like KubectlToUse without downloading anything,
+// a missing binary is reported at the path it would be downloaded to
+func (v *Versioner) ExplainKubectlToUse(timeout int64, allowDownload bool) (Selection, error) {
+	return v.selectKubectl(timeout, allowDownload, true)
+}
+
+// InstalledKubectl returns the kubectl binary KubectlToUse would most likely
+// pick without contacting the API server nor downloading anything: the last
+// known version of the server is used whatever its age, then the default and
+// the most recent binaries. The use of the binary is not recorded
+func (v *Versioner) InstalledKubectl() (Selection, error) {
+	pin, reason, err := v.matchingPin()
+	if err != nil {
+		return Selection{}, err
+	}
+	if pin != nil {
+		return v.pinnedKubectl(Selection{Reasons: []string{reason}}, pin, false, false)
+	}
+
+	if server, cached, found := v.cachedServerVersion(); found {
+		reason := fmt.Sprintf("server %s reported version %s at %s", server, cached.Version, cached.LastSeen.Format(time.RFC3339))
+		return v.compatibleKubectl(Selection{Reasons: []string{reason}}, cached.Version, false, false)
+	}
+	if defaultVersion := v.defaultVersion(); defaultVersion != nil {
+		reason := fmt.Sprintf("server version unknown, %s is the default version", defaultVersion)
+		return v.compatibleKubectl(Selection{Reasons: []string{reason}}, *defaultVersion, false, false)
+	}
+
+	kubectl, err := v.kFinder.MostRecentKubectlAvailable()
+	if err != nil {
+		return Selection{}, err
+	}
+	return Selection{
+		Path:    kubectl.Path,
+		Version: kubectl.Version,
+		Reasons: []string{fmt.Sprintf("server version unknown, %s is the most recent kubectl available", kubectl.Path)},
+	}, nil
+}
+
+func (v *Versioner) selectKubectl(timeout int64, allowDownload, dryRun bool) (Selection, error) {
+	pin, reason, err := v.matchingPin()
+	if err != nil {
+		return Selection{}, err
+	}
+	if pin != nil {
+		return v.pinnedKubectl(Selection{Reasons: []string{reason}}, pin, allowDownload, dryRun)
+	}
+
+	version, reason, err := v.versionToUse(timeout)
+	if err != nil {
+		return Selection{}, err
+	}
+	return v.compatibleKubectl(Selection{Reasons: []string{reason}}, version, allowDownload, dryRun)
+}
+
+// matchingPin returns the pin applying to the context and server kubectl
+// is going to use, if any
+func (v *Versioner) matchingPin() (*kubectlPin, string, error) {
+	if len(v.pins) == 0 {
+		return nil, "", nil
+	}
+
+	// missing values are not fatal, pins on the other key can still match
+	context, _ := v.apiServer.ContextName()
+	server, _ := v.apiServer.ServerURL()
+
+	pin, err := findPin(v.pins, context, server)
+	if err != nil || pin == nil {
+		return nil, "", err
+	}
+	return pin, fmt.Sprintf("context %q (server %s) matches the pin %s", context, server, pin), nil
+}
+
+func (v *Versioner) pinnedKubectl(sel Selection, pin *kubectlPin, allowDownload, dryRun bool) (Selection, error) {
+	span := debug.StartStep(debug.StepLookup, pin.String())
+	binaries := v.kFinder.AllKubectlBinaries(true)
+	span.End()
+	for _, kubectl := range binaries {
+		if pin.valid(kubectl.Version) {
+			sel.Path = kubectl.Path
+			sel.Version = kubectl.Version
+			sel.Reasons = append(sel.Reasons, fmt.Sprintf("%s satisfies the pinned version", kubectl.Path))
+			return sel, nil
 		}
 	}
-	return version, err
+
+	if pin.exact == nil {
+		return Selection{}, fmt.Errorf(
+			"no kubectl binary satisfies the pinned version %q, use `eke kubectl get-bin` to download one",
+			pin.rule.Version)
+	}
+	return v.downloadKubectl(sel, *pin.exact, allowDownload, dryRun)
 }
 
-// EnsureCompatibleKubectlAvailable ensures the kubectl binary with the specified
-// version is available on the system. It will return the full path to the
-// binary
-func (v *Versioner) EnsureCompatibleKubectlAvailable(version semver.Version, allowDownload bool) (string, error) {
+func (v *Versioner) compatibleKubectl(sel Selection, version semver.Version, allowDownload, dryRun bool) (Selection, error) {
+	policy := v.Policy
+	if policy == nil {
+		policy = defaultPolicy
+	}
+
+	span := debug.StartStep(debug.StepLookup, version.String())
 	kubectl, err := v.kFinder.FindCompatibleKubectl(version)
+	span.End()
 	if err == nil {
-		return kubectl.Path, nil
+		sel.Path = kubectl.Path
+		sel.Version = kubectl.Version
+		sel.Reasons = append(sel.Reasons, fmt.Sprintf("%s is compatible with version %s according to the %s policy", kubectl.Path, version, policy.Mode))
+		return sel, nil
 	}
 
+	if ok, reason := policy.Allows(version); !ok {
+		return Selection{}, fmt.Errorf("%v\nkubectl %s cannot be downloaded, it is %s", err, version, reason)
+	}
 	if !allowDownload {
-		return "", errors.New("the right kubectl is missing, binary downloads from kubernetes' upstream mirror are disabled")
+		return Selection{}, fmt.Errorf("%v\n%v", err, errDownloadDisabled)
 	}
+	return v.downloadKubectl(sel, version, allowDownload, dryRun)
+}
 
-	log.Printf("Right kubectl missing, downloading version %s", version.String())
+func (v *Versioner) downloadKubectl(sel Selection, version semver.Version, allowDownload, dryRun bool) (Selection, error) {
+	if !allowDownload {
+		return Selection{}, errDownloadDisabled
+	}
 
 	//download the right kubectl to the local cache
 	filename := filepath.Join(
 		common.LocalDownloadDir(),
 		common.BuildKubectlNameForLocalBin(version))
 
+	sel.Path = filename
+	sel.Version = version
+	if dryRun {
+		sel.Reasons = append(sel.Reasons, fmt.Sprintf("kubectl %s is missing and would be downloaded", version))
+		return sel, nil
+	}
+
+	logger.Infof("Right kubectl missing, downloading version %s", version.String())
 	if err := v.downloader.GetKubectlBinary(version, filename); err != nil {
-		return "", err
+		return Selection{}, err
+	}
+	sel.Reasons = append(sel.Reasons, fmt.Sprintf("kubectl %s was missing and has been downloaded", version))
+	return sel, nil
+}
+
+// KubectlVersionToUse returns the kubectl version to be used to interact with
+// the remote server. The method takes into account different failure scenarios
+// and acts accordingly.
+func (v *Versioner) KubectlVersionToUse(timeout int64) (semver.Version, error) {
+	version, _, err := v.versionToUse(timeout)
+	return version, err
+}
+
+// versionToUse implements KubectlVersionToUse, it also explains where the
+// version comes from
+func (v *Versioner) versionToUse(timeout int64) (semver.Version, string, error) {
+	server, cached, found := v.cachedServerVersion()
+	if found && v.versionCache.IsFresh(cached) {
+		debug.CacheHits.Add(1)
+		return cached.Version, fmt.Sprintf("server %s reported version %s at %s", server, cached.Version, cached.LastSeen.Format(time.RFC3339)), nil
+	}
+	if found && v.RefreshInBackground != nil {
+		// use the last known version right away, the cache
+		// is going to be updated by someone else
+		v.RefreshInBackground()
+		return cached.Version, fmt.Sprintf("server %s reported version %s at %s, refreshing it in the background", server, cached.Version, cached.LastSeen.Format(time.RFC3339)), nil
+	}
+	if v.versionCache != nil {
+		debug.CacheMisses.Add(1)
+	}
+
+	version, err := v.serverVersion(server, timeout)
+	if err == nil {
+		if server != "" {
+			if err := v.versionCache.Set(server, version); err != nil {
+				logger.Warnf("Cannot cache the version of %s: %v", server, err)
+			}
+		}
+		return version, fmt.Sprintf("server %s reports version %s", server, version), nil
+	}
+
+	if isTimeout(err) {
+		// the remote server is unreachable, let's get
+		// the latest version of kubectl that is available on the system
+		logger.Warnln("Remote kubernetes server unreachable")
+	} else {
+		logger.Warnln(err)
+	}
+	if found {
+		logger.Infof("Using the last known version of %s: %s", server, cached.Version)
+		return cached.Version, fmt.Sprintf("server %s cannot be reached (%v), its last known version is %s", server, err, cached.Version), nil
+	}
+
+	if defaultVersion := v.defaultVersion(); defaultVersion != nil {
+		return *defaultVersion, fmt.Sprintf("server version unknown, %s is the default version", defaultVersion), nil
+	}
+
+	kubectl, err := v.kFinder.MostRecentKubectlAvailable()
+	if err == nil {
+		return kubectl.Version, fmt.Sprintf("server version unknown, %s is the most recent kubectl available", kubectl.Path), nil
+	} else if common.IsNoVersionFound(err) {
+		logger.Infoln("No local kubectl binary found, fetching latest stable release version")
+		version, err := v.downloader.UpstreamStableVersion()
+		return version, fmt.Sprintf("server version unknown and no kubectl available, %s is the latest stable release", version), err
+	}
+	return version, "", err
+}
+
+// RefreshServerVersion asks the API server for its version and updates the cache
+func (v *Versioner) RefreshServerVersion(timeout int64) error {
+	server, err := v.apiServer.ServerURL()
+	if err != nil {
+		return err
+	}
+	version, err := v.serverVersion(server, timeout)
+	if err != nil {
+		return err
+	}
+	if v.versionCache == nil {
+		return nil
+	}
+	return v.versionCache.Set(server, version)
+}
+
+// defaultVersion returns the version chosen with `eke kubectl use`, if any
+func (v *Versioner) defaultVersion() *semver.Version {
+	if v.state == nil {
+		return nil
+	}
+
+	state, err := v.state.Load()
+	if err != nil {
+		logger.Warnf("Cannot read the default kubectl version: %v", err)
+		return nil
 	}
+	return state.Default
+}
+
+// serverVersion asks the API server for its version, the request is a
+// step of the debug timeline
+func (v *Versioner) serverVersion(server string, timeout int64) (semver.Version, error) {
+	span := debug.StartStep(debug.StepDiscovery, server)
+	defer span.End()
+	return v.apiServer.Version(timeout)
+}
+
+func (v *Versioner) cachedServerVersion() (string, cache.ServerVersion, bool) {
+	if v.versionCache == nil {
+		return "", cache.ServerVersion{}, false
+	}
+
+	server, err := v.apiServer.ServerURL()
+	if err != nil {
+		return "", cache.ServerVersion{}, false
+	}
+
+	cached, found := v.versionCache.Get(server)
+	return server, cached, found
+}
 
-	return filename, nil
+// EnsureCompatibleKubectlAvailable ensures the kubectl binary with the specified
+// version is available on the system. It will return the full path to the
+// binary
+func (v *Versioner) EnsureCompatibleKubectlAvailable(version semver.Version, allowDownload bool) (string, error) {
+	sel, err := v.compatibleKubectl(Selection{}, version, allowDownload, false)
+	return sel.Path, err
 }
 
 func isTimeout(err error) bool {
@@ -1,13 +1,17 @@
 package finder
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/blang/semver/v4"
 
+	"eke/internal/kubectlcmd/cache"
 	"eke/internal/kubectlcmd/common"
+	"eke/pkg/config/cmdconfig"
 )
 
 type mockFinder struct {
@@ -56,13 +60,29 @@ func (m *mockDownloader) UpstreamStableVersion() (semver.Version, error) {
 }
 
 type mockAPIServer struct {
-	version func(timeout int64) (semver.Version, error)
+	version     func(timeout int64) (semver.Version, error)
+	serverURL   string
+	contextName string
 }
 
 func (m *mockAPIServer) Version(timeout int64) (semver.Version, error) {
 	return m.version(timeout)
 }
 
+func (m *mockAPIServer) ServerURL() (string, error) {
+	if m.serverURL == "" {
+		return "", errors.New("no server configured")
+	}
+	return m.serverURL, nil
+}
+
+func (m *mockAPIServer) ContextName() (string, error) {
+	if m.contextName == "" {
+		return "", errors.New("no context configured")
+	}
+	return m.contextName, nil
+}
+
 type mockTimeoutError struct {
 	Err error
 }
@@ -242,3 +262,330 @@ func genericTestKubectlVersionToUseTimeout(localBins, systemBins KubectlBinaries
 
 	return nil
 }
+
+type mockVersionCache struct {
+	entries map[string]cache.ServerVersion
+	fresh   bool
+}
+
+func (m *mockVersionCache) Get(server string) (cache.ServerVersion, bool) {
+	entry, found := m.entries[server]
+	return entry, found
+}
+
+func (m *mockVersionCache) Set(server string, version semver.Version) error {
+	m.entries[server] = cache.ServerVersion{Version: version, LastSeen: time.Now()}
+	return nil
+}
+
+func (m *mockVersionCache) IsFresh(entry cache.ServerVersion) bool {
+	return m.fresh
+}
+
+func newVersionerWithCache(cachedVersion string, fresh bool, apiVersion func(timeout int64) (semver.Version, error)) (*Versioner, *mockVersionCache) {
+	versionCache := &mockVersionCache{
+		entries: map[string]cache.ServerVersion{},
+		fresh:   fresh,
+	}
+	if cachedVersion != "" {
+		versionCache.entries["https://cluster:6443"] = cache.ServerVersion{
+			Version: semver.MustParse(cachedVersion),
+		}
+	}
+
+	finderMock := mockFinder{}
+	finderMock.mostRecentKubectlAvailable = func() (KubectlBinary, error) {
+		return KubectlBinary{Version: semver.MustParse("1.99.0")}, nil
+	}
+
+	return &Versioner{
+		kFinder:      &finderMock,
+		apiServer:    &mockAPIServer{version: apiVersion, serverURL: "https://cluster:6443"},
+		versionCache: versionCache,
+	}, versionCache
+}
+
+func TestKubectlVersionToUseFreshCachedVersion(t *testing.T) {
+	versioner, _ := newVersionerWithCache("1.21.3", true, func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, errors.New("the API server should not be contacted")
+	})
+
+	actual, err := versioner.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !actual.Equals(semver.MustParse("1.21.3")) {
+		t.Errorf("Got %s instead of 1.21.3", actual)
+	}
+}
+
+func TestKubectlVersionToUseStaleCachedVersionRefreshedInBackground(t *testing.T) {
+	versioner, _ := newVersionerWithCache("1.21.3", false, func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, errors.New("the API server should not be contacted")
+	})
+	refreshed := false
+	versioner.RefreshInBackground = func() {
+		refreshed = true
+	}
+
+	actual, err := versioner.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !actual.Equals(semver.MustParse("1.21.3")) {
+		t.Errorf("Got %s instead of 1.21.3", actual)
+	}
+	if !refreshed {
+		t.Error("Expected a background refresh of the stale version")
+	}
+}
+
+func TestKubectlVersionToUseDiscoveredVersionIsCached(t *testing.T) {
+	versioner, versionCache := newVersionerWithCache("", false, func(timeout int64) (semver.Version, error) {
+		return semver.MustParse("1.22.1"), nil
+	})
+
+	actual, err := versioner.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !actual.Equals(semver.MustParse("1.22.1")) {
+		t.Errorf("Got %s instead of 1.22.1", actual)
+	}
+
+	cached, found := versionCache.Get("https://cluster:6443")
+	if !found || !cached.Version.Equals(actual) {
+		t.Errorf("Expected %s to be cached, got %+v", actual, cached)
+	}
+}
+
+func TestKubectlVersionToUseTimeoutFallsBackToLastKnownVersion(t *testing.T) {
+	versioner, _ := newVersionerWithCache("1.20.7", false, func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, &mockTimeoutError{}
+	})
+
+	actual, err := versioner.KubectlVersionToUse(1)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !actual.Equals(semver.MustParse("1.20.7")) {
+		t.Errorf("Got %s instead of the last known version 1.20.7", actual)
+	}
+}
+
+func newPinnedVersioner(pins []cmdconfig.KubectlPin, bins KubectlBinaries, download func(semver.Version, string) error) *Versioner {
+	finderMock := mockFinder{}
+	finderMock.localKubectlBinaries = func() (KubectlBinaries, error) {
+		return bins, nil
+	}
+	finderMock.systemKubectlBinaries = func() (KubectlBinaries, error) {
+		return KubectlBinaries{}, nil
+	}
+	finderMock.findCompatibleKubectl = func(v semver.Version) (KubectlBinary, error) {
+		return KubectlBinary{}, &common.NoVersionFoundError{}
+	}
+
+	return &Versioner{
+		kFinder:    &finderMock,
+		downloader: &mockDownloader{getKubectlBinary: download},
+		apiServer: &mockAPIServer{
+			version: func(timeout int64) (semver.Version, error) {
+				return semver.Version{}, errors.New("the API server should not be contacted")
+			},
+			serverURL:   "https://prod-eu.example.com:6443",
+			contextName: "prod-eu",
+		},
+		pins: pins,
+	}
+}
+
+func TestKubectlToUsePinnedExactVersionAvailable(t *testing.T) {
+	bins := fakeKubectlBinaries("/fake/home", []string{"1.21.14", "1.22.3"}, &localKubectlNamer{})
+	expected := bins[0].Path
+	versioner := newPinnedVersioner(
+		[]cmdconfig.KubectlPin{
+			{Context: "dev-*", Version: "1.22.3"},
+			{Context: "prod-*", Version: "v1.21.14"},
+		},
+		bins,
+		nil)
+
+	sel, err := versioner.KubectlToUse(1, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if sel.Path != expected {
+		t.Errorf("Got %s instead of %s", sel.Path, expected)
+	}
+	if len(sel.Reasons) == 0 || !strings.Contains(sel.Reasons[0], "context=prod-*") {
+		t.Errorf("Expected the pin to be part of the reasons, got %v", sel.Reasons)
+	}
+}
+
+func TestKubectlToUsePinnedRangeByServer(t *testing.T) {
+	bins := fakeKubectlBinaries("/fake/home", []string{"1.20.1", "1.20.9", "1.22.3"}, &localKubectlNamer{})
+	versioner := newPinnedVersioner(
+		[]cmdconfig.KubectlPin{{Server: "https://prod-*.example.com:6443", Version: ">=1.20.0 <1.21.0"}},
+		bins,
+		nil)
+
+	sel, err := versioner.KubectlToUse(1, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !sel.Version.Equals(semver.MustParse("1.20.9")) {
+		t.Errorf("Got %s instead of 1.20.9", sel.Version)
+	}
+}
+
+func TestKubectlToUsePinnedExactVersionDownloaded(t *testing.T) {
+	downloaded := semver.Version{}
+	versioner := newPinnedVersioner(
+		[]cmdconfig.KubectlPin{{Context: "prod-eu", Server: "https://*", Version: "1.21.14"}},
+		KubectlBinaries{},
+		func(v semver.Version, destination string) error {
+			downloaded = v
+			return nil
+		})
+
+	explained, err := versioner.ExplainKubectlToUse(1, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !downloaded.Equals(semver.Version{}) {
+		t.Error("Nothing should be downloaded while explaining the choice")
+	}
+
+	sel, err := versioner.KubectlToUse(1, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !downloaded.Equals(semver.MustParse("1.21.14")) {
+		t.Errorf("Downloaded %s instead of 1.21.14", downloaded)
+	}
+	if sel.Path != explained.Path {
+		t.Errorf("Got %s instead of %s", sel.Path, explained.Path)
+	}
+}
+
+func TestKubectlToUsePinnedRangeNotAvailable(t *testing.T) {
+	bins := fakeKubectlBinaries("/fake/home", []string{"1.22.3"}, &localKubectlNamer{})
+	versioner := newPinnedVersioner(
+		[]cmdconfig.KubectlPin{{Context: "prod-*", Version: "1.20.x"}},
+		bins,
+		nil)
+
+	if _, err := versioner.KubectlToUse(1, true); err == nil {
+		t.Error("Expected an error when no binary satisfies the pinned range")
+	}
+}
+
+func TestKubectlToUseNoMatchingPin(t *testing.T) {
+	bins := fakeKubectlBinaries("/fake/home", []string{"1.21.14"}, &localKubectlNamer{})
+	versioner := newPinnedVersioner(
+		[]cmdconfig.KubectlPin{{Context: "staging", Version: "1.21.14"}},
+		bins,
+		nil)
+
+	// without a matching pin the API server is asked for its version
+	discovered := false
+	versioner.apiServer.(*mockAPIServer).version = func(timeout int64) (semver.Version, error) {
+		discovered = true
+		return semver.MustParse("1.21.2"), nil
+	}
+	versioner.kFinder.(*mockFinder).findCompatibleKubectl = func(v semver.Version) (KubectlBinary, error) {
+		return bins[0], nil
+	}
+
+	if _, err := versioner.KubectlToUse(1, true); err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !discovered {
+		t.Error("Expected the server version to be discovered")
+	}
+}
+
+func TestFindPinServerPatterns(t *testing.T) {
+	server := "https://api.prod-eu.example.com:6443/k8s/clusters/c-42"
+	for pattern, expected := range map[string]bool{
+		"https://api.prod-eu.example.com:6443/k8s/clusters/c-42": true,
+		"https://*.example.com:6443/*":                           true,
+		"https://api.prod-eu.example.com*":                       true,
+		"https://*":                                              true,
+		"https://*.example.com:6443":                             false,
+		"http://*":                                               false,
+		"*.example.com":                                          true,
+		"api.prod-eu.example.com:6443":                           true,
+		"*.example.com:6443":                                     true,
+		"*.staging.example.com":                                  false,
+		"example.com":                                            false,
+	} {
+		pin, err := findPin([]cmdconfig.KubectlPin{{Server: pattern, Version: "1.22.3"}}, "prod-eu", server)
+		if err != nil {
+			t.Fatalf("Unexpected error %+v", err)
+		}
+		if (pin != nil) != expected {
+			t.Errorf("Pattern %s matching %s: got %v instead of %v", pattern, server, pin != nil, expected)
+		}
+	}
+}
+
+func TestInvalidPin(t *testing.T) {
+	for _, rule := range []cmdconfig.KubectlPin{
+		{Version: "1.21.14"},
+		{Context: "prod", Version: "not a version"},
+	} {
+		if _, err := parsePin(rule); err == nil {
+			t.Errorf("Expected %+v to be invalid", rule)
+		}
+	}
+}
+
+func TestInstalledKubectl(t *testing.T) {
+	offline := func(timeout int64) (semver.Version, error) {
+		return semver.Version{}, errors.New("the API server should not be contacted")
+	}
+	compatible := func(v semver.Version) (KubectlBinary, error) {
+		if v.Minor == 20 {
+			return KubectlBinary{Path: "/fake/kubectl1.20.7", Version: semver.MustParse("1.20.7")}, nil
+		}
+		return KubectlBinary{}, &common.NoVersionFoundError{}
+	}
+
+	// a stale cached version is used without refreshing it
+	versioner, _ := newVersionerWithCache("1.20.7", false, offline)
+	versioner.kFinder.(*mockFinder).findCompatibleKubectl = compatible
+	versioner.downloader = &mockDownloader{getKubectlBinary: func(semver.Version, string) error {
+		return errors.New("nothing should be downloaded")
+	}}
+	versioner.RefreshInBackground = func() {
+		t.Error("the cached version should not be refreshed")
+	}
+	sel, err := versioner.InstalledKubectl()
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if sel.Path != "/fake/kubectl1.20.7" {
+		t.Errorf("Got %s instead of /fake/kubectl1.20.7", sel.Path)
+	}
+
+	// a missing binary is not downloaded
+	versioner, _ = newVersionerWithCache("1.22.1", true, offline)
+	versioner.kFinder.(*mockFinder).findCompatibleKubectl = compatible
+	versioner.downloader = &mockDownloader{getKubectlBinary: func(semver.Version, string) error {
+		return errors.New("nothing should be downloaded")
+	}}
+	if _, err := versioner.InstalledKubectl(); err == nil || strings.Contains(err.Error(), "nothing should be downloaded") {
+		t.Errorf("Expected no binary and no download, got %v", err)
+	}
+
+	// without any known version, the most recent binary is used
+	versioner, _ = newVersionerWithCache("", false, offline)
+	sel, err = versioner.InstalledKubectl()
+	if err != nil {
+		t.Fatalf("Unexpected error %+v", err)
+	}
+	if !sel.Version.Equals(semver.MustParse("1.99.0")) {
+		t.Errorf("Got %s instead of the most recent kubectl 1.99.0", sel.Version)
+	}
+}
@@ -0,0 +1,195 @@
+package guard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"eke/internal/kubectlcmd/kubehelper"
+	"eke/internal/pkg/logging"
+
+	"golang.org/x/term"
+)
+
+// logger writes the messages of the kubectl subsystem
+var logger = logging.For(logging.Kubectl)
+
+// Outcomes of the commands matching a guardrail
+const (
+	OutcomeBlocked      = "blocked"
+	OutcomeAcknowledged = "acknowledged"
+	OutcomeConfirmed    = "confirmed"
+	OutcomeDeclined     = "declined"
+)
+
+// DecisionLogFile is the name of the file, inside of the cache directory,
+// recording the decisions of the guardrails
+const DecisionLogFile = "guardrails.log"
+
+// Decision records what happened to a command matching a guardrail
+type Decision struct {
+	Time      time.Time `json:"time"`
+	Context   string    `json:"context"`
+	Server    string    `json:"server,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Args      []string  `json:"args"`
+	Rule      string    `json:"rule"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+}
+
+// Guard checks the kubectl commands against the guardrails
+type Guard struct {
+	Rules []*Rule
+	// Prompt asks a question to the user and returns the answer, it is
+	// nil when nobody can answer
+	Prompt func(question string) (string, error)
+	// Record stores the decisions, they are not stored when nil
+	Record func(d Decision) error
+	now    func() time.Time
+}
+
+// Check returns an error when the kubectl command run against target with
+// args is stopped by a guardrail. acknowledged tells whether the --i-know
+// flag has been given
+func (g *Guard) Check(target Target, args []string, acknowledged bool) error {
+	r := Match(g.Rules, target, args)
+	if r == nil {
+		return nil
+	}
+
+	command := strings.Join(append([]string{"kubectl"}, args...), " ")
+	var err error
+	outcome := OutcomeBlocked
+	switch r.Action {
+	case ActionBlock:
+		err = fmt.Errorf("`%s` on %s is %v %s", command, target.Context, ErrBlocked, r)
+	case ActionFlag:
+		if acknowledged {
+			outcome = OutcomeAcknowledged
+		} else {
+			err = fmt.Errorf("`%s` on %s is protected by the guardrail %s, add %s to run it", command, target.Context, r, IKnowFlag)
+		}
+	case ActionConfirm:
+		outcome, err = g.confirm(r, target, command)
+	}
+
+	g.record(Decision{
+		Context:   target.Context,
+		Server:    target.Server,
+		Namespace: target.Namespace,
+		Args:      args,
+		Rule:      r.String(),
+		Action:    r.Action,
+		Outcome:   outcome,
+	})
+	return err
+}
+
+// unnamedConfirmation is the answer confirming a command when neither the
+// context nor the server has a name to type
+const unnamedConfirmation = "confirm"
+
+func (g *Guard) confirm(r *Rule, target Target, command string) (string, error) {
+	expected, what := target.Context, "context"
+	if expected == "" {
+		expected, what = target.Server, "server"
+	}
+	cluster := expected
+	question := fmt.Sprintf("`%s` is protected by the guardrail %s.\nType the name of the %s, %s, to run it: ", command, r, what, expected)
+	if expected == "" {
+		// an empty answer must never confirm anything
+		expected, cluster = unnamedConfirmation, "the current cluster"
+		question = fmt.Sprintf("`%s` is protected by the guardrail %s.\nType %s to run it on the current cluster: ", command, r, expected)
+	}
+	if g.Prompt == nil {
+		return OutcomeBlocked, fmt.Errorf("`%s` on %s is protected by the guardrail %s and cannot be confirmed without a terminal", command, cluster, r)
+	}
+
+	answer, err := g.Prompt(question)
+	if err != nil {
+		return OutcomeDeclined, err
+	}
+	if strings.TrimSpace(answer) != expected {
+		return OutcomeDeclined, fmt.Errorf("`%s` on %s has not been confirmed", command, cluster)
+	}
+	return OutcomeConfirmed, nil
+}
+
+func (g *Guard) record(d Decision) {
+	if g.Record == nil {
+		return
+	}
+	now := time.Now
+	if g.now != nil {
+		now = g.now
+	}
+	d.Time = now()
+	if err := g.Record(d); err != nil {
+		logger.Warnf("Cannot record the guardrail decision: %v", err)
+	}
+}
+
+// FileRecorder returns a Record function appending the decisions to the
+// file at path, one JSON document per line
+func FileRecorder(path string) func(d Decision) error {
+	return func(d Decision) error {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(data, '\n'))
+		return err
+	}
+}
+
+// TerminalPrompt returns a Prompt function reading the answers from the
+// terminal, it returns nil when stdin is not a terminal
+func TerminalPrompt() func(question string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	return func(question string) (string, error) {
+		fmt.Fprint(os.Stderr, question)
+		return bufio.NewReader(os.Stdin).ReadString('\n')
+	}
+}
+
+// TargetOf returns where kubectl runs when invoked with args. Values that
+// cannot be found out are left empty
+func TargetOf(args []string) (Target, error) {
+	flags, err := kubehelper.ParseConnectionFlags(args)
+	if err != nil {
+		return Target{}, err
+	}
+
+	t := Target{}
+	t.Context, _ = flags.ContextName()
+	t.Namespace, _ = flags.Namespace()
+	if restConfig, err := flags.ClientConfig().ClientConfig(); err == nil {
+		t.Server = restConfig.Host
+	}
+
+	_, parsed := splitArgs(args)
+	for _, name := range []string{"-A", "--all-namespaces"} {
+		for _, value := range parsed[name] {
+			if value != "false" {
+				t.AllNamespaces = true
+			}
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,282 @@
+package guard
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"eke/pkg/config/cmdconfig"
+)
+
+// Actions taken when a guardrail matches a command
+const (
+	ActionConfirm = "confirm"
+	ActionBlock   = "block"
+	ActionFlag    = "flag"
+)
+
+// IKnowFlag acknowledges the commands protected by the guardrails with
+// the flag action
+const IKnowFlag = "--i-know"
+
+// DefaultVerbs are protected by the guardrails without verbs
+var DefaultVerbs = []string{"delete", "drain", "scale --replicas=0", "apply --prune"}
+
+// kubectl global flags taking a value, used to tell the values apart from
+// the command words
+var globalValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--cluster": true,
+	"--user": true, "--kubeconfig": true, "-s": true, "--server": true,
+	"--token": true, "--as": true, "--as-group": true, "--as-uid": true,
+	"--certificate-authority": true, "--client-certificate": true,
+	"--client-key": true, "--request-timeout": true, "--tls-server-name": true,
+	"--cache-dir": true, "-v": true, "--v": true, "--log-file": true,
+	"--username": true, "--password": true,
+}
+
+// verb is a parsed guardrail verb such as "scale --replicas=0"
+type verb struct {
+	words []string
+	// flags maps the required flags to their value, empty when the flag
+	// only has to be set
+	flags map[string]string
+}
+
+// Rule is a parsed cmdconfig.KubectlGuardrail
+type Rule struct {
+	config cmdconfig.KubectlGuardrail
+	verbs  []verb
+	Action string
+}
+
+// Target describes where a kubectl command runs
+type Target struct {
+	Context   string
+	Server    string
+	Namespace string
+	// AllNamespaces is set for the commands run with --all-namespaces,
+	// they match any namespace pattern
+	AllNamespaces bool
+}
+
+// NewRules validates the guardrails of the configuration
+func NewRules(guardrails []cmdconfig.KubectlGuardrail) ([]*Rule, error) {
+	var rules []*Rule
+	for _, g := range guardrails {
+		r, err := newRule(g)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func newRule(g cmdconfig.KubectlGuardrail) (*Rule, error) {
+	r := &Rule{config: g, Action: g.Action}
+	if r.Action == "" {
+		r.Action = ActionConfirm
+	}
+	switch r.Action {
+	case ActionConfirm, ActionBlock, ActionFlag:
+	default:
+		return nil, fmt.Errorf("invalid guardrail %s: unknown action %q, expected confirm, block or flag", r, g.Action)
+	}
+
+	for _, pattern := range []string{g.Context, g.Server, g.Namespace} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid guardrail %s: %q: %v", r, pattern, err)
+		}
+	}
+
+	verbs := g.Verbs
+	if len(verbs) == 0 {
+		verbs = DefaultVerbs
+	}
+	for _, v := range verbs {
+		parsed, err := parseVerb(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid guardrail %s: %v", r, err)
+		}
+		r.verbs = append(r.verbs, parsed)
+	}
+	return r, nil
+}
+
+func parseVerb(s string) (verb, error) {
+	v := verb{flags: map[string]string{}}
+	for _, field := range strings.Fields(s) {
+		if !strings.HasPrefix(field, "-") {
+			if len(v.flags) > 0 {
+				return verb{}, fmt.Errorf("verb %q: flags must follow the command", s)
+			}
+			v.words = append(v.words, field)
+			continue
+		}
+		name, value := field, ""
+		if i := strings.Index(field, "="); i >= 0 {
+			name, value = field[:i], field[i+1:]
+		}
+		v.flags[name] = value
+	}
+	if len(v.words) == 0 {
+		return verb{}, fmt.Errorf("verb %q: the kubectl command is missing", s)
+	}
+	return v, nil
+}
+
+// Match returns the first rule protecting target from the kubectl command
+// run with args, if any
+func Match(rules []*Rule, target Target, args []string) *Rule {
+	words, flags := splitArgs(args)
+	for _, r := range rules {
+		if r.matchesTarget(target) && r.matchesCommand(words, flags) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matchesTarget(t Target) bool {
+	if r.config.Context != "" && !globMatch(r.config.Context, t.Context) {
+		return false
+	}
+	if r.config.Server != "" && !globMatch(r.config.Server, t.Server) {
+		return false
+	}
+	if r.config.Namespace != "" && !t.AllNamespaces && !globMatch(r.config.Namespace, t.Namespace) {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) matchesCommand(words []string, flags map[string][]string) bool {
+	for _, v := range r.verbs {
+		if v.matches(words, flags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v verb) matches(words []string, flags map[string][]string) bool {
+	if len(words) < len(v.words) {
+		return false
+	}
+	for i, w := range v.words {
+		if words[i] != w {
+			return false
+		}
+	}
+
+	for name, expected := range v.flags {
+		values, found := flags[name]
+		if !found {
+			return false
+		}
+		matched := false
+		for _, value := range values {
+			if (expected == "" && value != "false") || value == expected {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// splitArgs returns the command words and the flags of kubectl arguments.
+// Flags without a value are reported with an empty value, parsing stops
+// at the first "--"
+func splitArgs(args []string) ([]string, map[string][]string) {
+	var words []string
+	flags := map[string][]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			words = append(words, arg)
+			continue
+		}
+
+		name, value := arg, ""
+		if j := strings.Index(arg, "="); j >= 0 {
+			name, value = arg[:j], arg[j+1:]
+		} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && takesValue(name, words) {
+			value = args[i+1]
+			i++
+		}
+		flags[name] = append(flags[name], value)
+	}
+	return words, flags
+}
+
+// takesValue guesses whether the flag is followed by its value. Global
+// flags are known, the flags of the commands are assumed to take a value
+// once the command has been seen
+func takesValue(name string, words []string) bool {
+	if globalValueFlags[name] {
+		return true
+	}
+	return len(words) > 0 && strings.HasPrefix(name, "--")
+}
+
+// Acknowledged returns true when args hold the --i-know flag
+func Acknowledged(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == IKnowFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// StripAcknowledgement removes the --i-know flag, kubectl does not know it
+func StripAcknowledgement(args []string) []string {
+	res := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(res, args[i:]...)
+		}
+		if arg != IKnowFlag {
+			res = append(res, arg)
+		}
+	}
+	return res
+}
+
+func (r *Rule) String() string {
+	var parts []string
+	if r.config.Context != "" {
+		parts = append(parts, "context="+r.config.Context)
+	}
+	if r.config.Server != "" {
+		parts = append(parts, "server="+r.config.Server)
+	}
+	if r.config.Namespace != "" {
+		parts = append(parts, "namespace="+r.config.Namespace)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "any cluster")
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func globMatch(pattern, name string) bool {
+	if name == "" {
+		return false
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// ErrBlocked is returned for the commands stopped by a guardrail
+var ErrBlocked = errors.New("blocked by a guardrail")
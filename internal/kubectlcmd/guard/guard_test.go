@@ -0,0 +1,203 @@
+package guard
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"eke/pkg/config/cmdconfig"
+)
+
+func mustRules(t *testing.T, guardrails ...cmdconfig.KubectlGuardrail) []*Rule {
+	t.Helper()
+
+	rules, err := NewRules(guardrails)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return rules
+}
+
+func TestMatch(t *testing.T) {
+	rules := mustRules(t,
+		cmdconfig.KubectlGuardrail{Context: "prod-*"},
+		cmdconfig.KubectlGuardrail{Server: "https://*.example.com:6443", Namespace: "kube-*", Verbs: []string{"rollout restart"}},
+	)
+	prod := Target{Context: "prod-eu", Server: "https://prod.local:6443", Namespace: "web"}
+	staging := Target{Context: "staging", Server: "https://staging.example.com:6443", Namespace: "kube-system"}
+
+	tests := []struct {
+		name     string
+		target   Target
+		args     []string
+		expected int
+	}{
+		{"delete", prod, []string{"delete", "pod", "web-1"}, 0},
+		{"delete after global flags", prod, []string{"-n", "web", "--context", "prod-eu", "delete", "pod", "web-1"}, 0},
+		{"get", prod, []string{"get", "pods"}, -1},
+		{"drain", prod, []string{"drain", "node-1", "--ignore-daemonsets"}, 0},
+		{"scale to zero", prod, []string{"scale", "deploy/web", "--replicas=0"}, 0},
+		{"scale to zero with separate value", prod, []string{"scale", "--replicas", "0", "deploy/web"}, 0},
+		{"scale up", prod, []string{"scale", "deploy/web", "--replicas=3"}, -1},
+		{"apply with prune", prod, []string{"apply", "--prune", "-f", "manifests/", "-l", "app=web"}, 0},
+		{"apply with prune disabled", prod, []string{"apply", "--prune=false", "-f", "manifests/"}, -1},
+		{"apply", prod, []string{"apply", "-f", "manifests/"}, -1},
+		{"other context", staging, []string{"delete", "pod", "web-1"}, -1},
+		{"namespace and verb", staging, []string{"rollout", "restart", "deploy/coredns"}, 1},
+		{"other namespace", Target{Context: "staging", Server: staging.Server, Namespace: "web"}, []string{"rollout", "restart", "deploy/web"}, -1},
+		{"all namespaces", Target{Context: "staging", Server: staging.Server, Namespace: "web", AllNamespaces: true}, []string{"rollout", "restart", "deploy"}, 1},
+		{"after double dash", prod, []string{"exec", "pod", "--", "delete"}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := Match(rules, tt.target, tt.args)
+			if tt.expected < 0 {
+				if actual != nil {
+					t.Errorf("Expected no guardrail to match, got %s", actual)
+				}
+				return
+			}
+			if actual != rules[tt.expected] {
+				t.Errorf("Expected the guardrail %s to match, got %v", rules[tt.expected], actual)
+			}
+		})
+	}
+}
+
+func TestNewRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		guardrail cmdconfig.KubectlGuardrail
+	}{
+		{"action", cmdconfig.KubectlGuardrail{Action: "ask"}},
+		{"pattern", cmdconfig.KubectlGuardrail{Context: "prod-["}},
+		{"verb without command", cmdconfig.KubectlGuardrail{Verbs: []string{"--force"}}},
+		{"flag before command", cmdconfig.KubectlGuardrail{Verbs: []string{"--force delete"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRules([]cmdconfig.KubectlGuardrail{tt.guardrail}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	target := Target{Context: "prod", Namespace: "web"}
+	args := []string{"delete", "pod", "web-1"}
+
+	tests := []struct {
+		name         string
+		action       string
+		prompt       func(string) (string, error)
+		acknowledged bool
+		outcome      string
+		allowed      bool
+	}{
+		{"block", ActionBlock, nil, true, OutcomeBlocked, false},
+		{"flag given", ActionFlag, nil, true, OutcomeAcknowledged, true},
+		{"flag missing", ActionFlag, nil, false, OutcomeBlocked, false},
+		{"confirmed", ActionConfirm, func(string) (string, error) { return "prod\n", nil }, false, OutcomeConfirmed, true},
+		{"wrong answer", ActionConfirm, func(string) (string, error) { return "yes\n", nil }, false, OutcomeDeclined, false},
+		{"prompt error", ActionConfirm, func(string) (string, error) { return "", errors.New("EOF") }, false, OutcomeDeclined, false},
+		{"no terminal", ActionConfirm, nil, true, OutcomeBlocked, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decisions []Decision
+			g := &Guard{
+				Rules:  mustRules(t, cmdconfig.KubectlGuardrail{Context: "prod", Action: tt.action}),
+				Prompt: tt.prompt,
+				Record: func(d Decision) error {
+					decisions = append(decisions, d)
+					return nil
+				},
+			}
+
+			err := g.Check(target, args, tt.acknowledged)
+			if tt.allowed && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Error("Expected the command to be stopped")
+			}
+			if len(decisions) != 1 || decisions[0].Outcome != tt.outcome || decisions[0].Context != "prod" {
+				t.Errorf("Expected a %s decision to be recorded, got %+v", tt.outcome, decisions)
+			}
+		})
+	}
+
+	g := &Guard{
+		Rules: mustRules(t, cmdconfig.KubectlGuardrail{Context: "prod"}),
+		Record: func(d Decision) error {
+			t.Errorf("Unexpected decision recorded %+v", d)
+			return nil
+		},
+	}
+	if err := g.Check(target, []string{"get", "pods"}, false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestConfirmUnnamedCluster(t *testing.T) {
+	g := &Guard{Rules: mustRules(t, cmdconfig.KubectlGuardrail{Action: ActionConfirm})}
+	args := []string{"delete", "pod", "web-1"}
+
+	for answer, allowed := range map[string]bool{"\n": false, "yes\n": false, "confirm\n": true} {
+		answer := answer
+		g.Prompt = func(string) (string, error) { return answer, nil }
+		if err := g.Check(Target{}, args, false); (err == nil) != allowed {
+			t.Errorf("Answering %q: got %v", answer, err)
+		}
+	}
+}
+
+func TestAcknowledgement(t *testing.T) {
+	args := []string{"delete", "pod", "x", "--i-know", "--", "--i-know"}
+	if !Acknowledged(args) {
+		t.Error("Expected the command to be acknowledged")
+	}
+	if Acknowledged([]string{"exec", "x", "--", "--i-know"}) {
+		t.Error("Flags after -- belong to the command run by kubectl")
+	}
+
+	stripped := StripAcknowledgement(args)
+	if strings.Join(stripped, " ") != "delete pod x -- --i-know" {
+		t.Errorf("Got %v", stripped)
+	}
+}
+
+func TestFileRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", DecisionLogFile)
+	record := FileRecorder(path)
+
+	now := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, outcome := range []string{OutcomeBlocked, OutcomeConfirmed} {
+		if err := record(Decision{Time: now, Context: "prod", Args: []string{"delete"}, Outcome: outcome}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 decisions, got %q", string(data))
+	}
+	var d Decision
+	if err := json.Unmarshal([]byte(lines[1]), &d); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Outcome != OutcomeConfirmed || !d.Time.Equal(now) {
+		t.Errorf("Unexpected decision %+v", d)
+	}
+}
@@ -6,11 +6,14 @@ import (
 
 // KubeAPI helps interactions with kubernetes API server
 type KubeAPI struct {
+	// Args are the arguments given to kubectl, they are used to
+	// find the kubeconfig kubectl is going to use
+	Args []string
 }
 
 // Version returns the version of the remote kubernetes API server
 func (k *KubeAPI) Version(timeout int64) (semver.Version, error) {
-	client, err := createKubeClient(timeout)
+	client, err := createKubeClient(k.Args, timeout)
 	if err != nil {
 		return semver.Version{}, err
 	}
@@ -21,3 +24,23 @@ func (k *KubeAPI) Version(timeout int64) (semver.Version, error) {
 	}
 	return semver.ParseTolerant(v.GitVersion)
 }
+
+// ServerURL returns the URL of the kubernetes API server kubectl is
+// going to talk to. The server is not contacted
+func (k *KubeAPI) ServerURL() (string, error) {
+	restConfig, err := createRestConfig(k.Args)
+	if err != nil {
+		return "", err
+	}
+	return restConfig.Host, nil
+}
+
+// ContextName returns the name of the kubeconfig context kubectl is
+// going to use
+func (k *KubeAPI) ContextName() (string, error) {
+	flags, err := ParseConnectionFlags(k.Args)
+	if err != nil {
+		return "", err
+	}
+	return flags.ContextName()
+}
@@ -0,0 +1,111 @@
+package kubehelper
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// EkeContexts returns the names of the kubeconfig contexts whose user
+// authenticates with `eke kubeconfig auth`, as set up by
+// `eke kubeconfig init`. Contexts are sorted by name
+func (f *ConnectionFlags) EkeContexts() ([]string, error) {
+	rawConfig, err := f.ClientConfig().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []string
+	for name, context := range rawConfig.Contexts {
+		if isEkeUser(rawConfig.AuthInfos[context.AuthInfo]) {
+			contexts = append(contexts, name)
+		}
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+func isEkeUser(user *clientcmdapi.AuthInfo) bool {
+	if user == nil || user.Exec == nil {
+		return false
+	}
+
+	command := strings.TrimSuffix(filepath.Base(user.Exec.Command), ".exe")
+	args := user.Exec.Args
+	return command == "eke" && len(args) >= 2 && args[0] == "kubeconfig" && args[1] == "auth"
+}
+
+// Contexts returns the names of all the kubeconfig contexts, sorted
+func (f *ConnectionFlags) Contexts() ([]string, error) {
+	rawConfig, err := f.ClientConfig().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []string
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// Clusters returns the names of all the kubeconfig clusters, sorted
+func (f *ConnectionFlags) Clusters() ([]string, error) {
+	rawConfig, err := f.ClientConfig().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var clusters []string
+	for name := range rawConfig.Clusters {
+		clusters = append(clusters, name)
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
+// ContextInfo describes a kubeconfig context
+type ContextInfo struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	Server    string `json:"server"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+	Current   bool   `json:"current"`
+	// Eke tells whether the user authenticates with `eke kubeconfig auth`
+	Eke bool `json:"eke"`
+}
+
+// ContextInfos describes all the kubeconfig contexts, sorted by name. The
+// current context is the one kubectl is going to use
+func (f *ConnectionFlags) ContextInfos() ([]ContextInfo, error) {
+	rawConfig, err := f.ClientConfig().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	current := rawConfig.CurrentContext
+	if f.Overrides.CurrentContext != "" {
+		current = f.Overrides.CurrentContext
+	}
+
+	contexts := []ContextInfo{}
+	for name, context := range rawConfig.Contexts {
+		info := ContextInfo{
+			Name:      name,
+			Cluster:   context.Cluster,
+			User:      context.AuthInfo,
+			Namespace: context.Namespace,
+			Current:   name == current,
+			Eke:       isEkeUser(rawConfig.AuthInfos[context.AuthInfo]),
+		}
+		if cluster := rawConfig.Clusters[context.Cluster]; cluster != nil {
+			info.Server = cluster.Server
+		}
+		contexts = append(contexts, info)
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].Name < contexts[j].Name })
+	return contexts, nil
+}
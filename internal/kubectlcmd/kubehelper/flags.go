@@ -0,0 +1,79 @@
+package kubehelper
+
+import (
+	"io/ioutil"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ConnectionFlags holds the kubectl global flags that select the cluster,
+// the user and the namespace kubectl is going to use. They follow the
+// semantics of kubectl's genericclioptions.ConfigFlags
+type ConnectionFlags struct {
+	KubeConfig string
+	Overrides  clientcmd.ConfigOverrides
+}
+
+// ParseConnectionFlags extracts the connection flags from the arguments
+// given to kubectl. All the other flags and the positional arguments are
+// ignored, parsing stops at the first "--"
+func ParseConnectionFlags(args []string) (*ConnectionFlags, error) {
+	f := &ConnectionFlags{}
+
+	flagset := pflag.NewFlagSet("kubectl", pflag.ContinueOnError)
+	flagset.ParseErrorsWhitelist.UnknownFlags = true
+	flagset.SetOutput(ioutil.Discard)
+	flagset.Usage = func() {}
+
+	flagNames := clientcmd.RecommendedConfigOverrideFlags("")
+	// kubectl adds a shorthand to the server flag
+	flagNames.ClusterOverrideFlags.APIServer.ShortName = "s"
+
+	flagset.StringVar(&f.KubeConfig, clientcmd.RecommendedConfigPathFlag, "", "")
+	clientcmd.BindOverrideFlags(&f.Overrides, flagset, flagNames)
+
+	if err := flagset.Parse(args); err != nil && err != pflag.ErrHelp {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ClientConfig returns the client configuration kubectl is going to build
+// out of these flags, the kubeconfig files and the KUBECONFIG variable
+func (f *ConnectionFlags) ClientConfig() clientcmd.ClientConfig {
+	// Let the NewDefaultClientConfigLoadingRules do the heavy lifting like
+	// parsing the KUBECONFIG value
+	// TIL: it's possible to specify multiple kubeconfig files via KUBECONFIG
+	// For example: `KUBECONFIG=~/cluster1.yaml:~/cluster2.yaml`
+	// See https://github.com/kubernetes/kubernetes/issues/46381#issuecomment-303926031
+	//
+	// The NewDefaultClientConfigLoadingRules function has all the logic built
+	// inside of it that handles this special case.
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	// give precedence to --kubeconfig flag
+	loadingRules.ExplicitPath = f.KubeConfig
+
+	overrides := f.Overrides
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &overrides)
+}
+
+// ContextName returns the name of the kubeconfig context kubectl is going
+// to use
+func (f *ConnectionFlags) ContextName() (string, error) {
+	if f.Overrides.CurrentContext != "" {
+		return f.Overrides.CurrentContext, nil
+	}
+
+	rawConfig, err := f.ClientConfig().RawConfig()
+	if err != nil {
+		return "", err
+	}
+	return rawConfig.CurrentContext, nil
+}
+
+// Namespace returns the namespace kubectl is going to use
+func (f *ConnectionFlags) Namespace() (string, error) {
+	namespace, _, err := f.ClientConfig().Namespace()
+	return namespace, err
+}
@@ -0,0 +1,378 @@
+package kubehelper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+users:
+- name: alice
+  user:
+    token: alice-token
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: alice
+    namespace: dev-ns
+- name: prod
+  context:
+    cluster: prod
+    user: alice
+current-context: dev
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseConnectionFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		check func(f *ConnectionFlags) bool
+	}{
+		{
+			name:  "context with separate value",
+			args:  []string{"get", "pods", "--context", "prod"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.CurrentContext == "prod" },
+		},
+		{
+			name:  "context with equal sign",
+			args:  []string{"--context=prod", "get", "pods"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.CurrentContext == "prod" },
+		},
+		{
+			name:  "last occurrence wins",
+			args:  []string{"--context=dev", "get", "pods", "--context", "prod"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.CurrentContext == "prod" },
+		},
+		{
+			name:  "kubeconfig",
+			args:  []string{"--kubeconfig", "/tmp/kubeconfig", "get", "nodes"},
+			check: func(f *ConnectionFlags) bool { return f.KubeConfig == "/tmp/kubeconfig" },
+		},
+		{
+			name:  "cluster",
+			args:  []string{"--cluster=prod", "get", "nodes"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.Context.Cluster == "prod" },
+		},
+		{
+			name:  "user",
+			args:  []string{"get", "nodes", "--user", "bob"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.Context.AuthInfo == "bob" },
+		},
+		{
+			name:  "namespace shorthand",
+			args:  []string{"get", "pods", "-n", "kube-system"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.Context.Namespace == "kube-system" },
+		},
+		{
+			name:  "namespace shorthand without space",
+			args:  []string{"get", "pods", "-nkube-system"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.Context.Namespace == "kube-system" },
+		},
+		{
+			name:  "namespace",
+			args:  []string{"get", "pods", "--namespace=kube-system"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.Context.Namespace == "kube-system" },
+		},
+		{
+			name:  "server",
+			args:  []string{"--server", "https://other:6443", "get", "pods"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.ClusterInfo.Server == "https://other:6443" },
+		},
+		{
+			name:  "server shorthand",
+			args:  []string{"-s", "https://other:6443", "get", "pods"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.ClusterInfo.Server == "https://other:6443" },
+		},
+		{
+			name:  "token",
+			args:  []string{"--token=secret", "get", "pods"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.AuthInfo.Token == "secret" },
+		},
+		{
+			name:  "certificate authority",
+			args:  []string{"--certificate-authority", "/tmp/ca.crt", "get", "pods"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.ClusterInfo.CertificateAuthority == "/tmp/ca.crt" },
+		},
+		{
+			name:  "insecure skip tls verify",
+			args:  []string{"--insecure-skip-tls-verify", "get", "pods"},
+			check: func(f *ConnectionFlags) bool { return f.Overrides.ClusterInfo.InsecureSkipTLSVerify },
+		},
+		{
+			name: "other kubectl flags are ignored",
+			args: []string{"get", "pods", "-o", "wide", "-l", "app=web", "--watch", "-A", "--context", "prod"},
+			check: func(f *ConnectionFlags) bool {
+				return f.Overrides.CurrentContext == "prod"
+			},
+		},
+		{
+			name: "arguments after -- are ignored",
+			args: []string{"exec", "-it", "pod", "--", "sh", "--context", "prod", "-n", "foo"},
+			check: func(f *ConnectionFlags) bool {
+				return f.Overrides.CurrentContext == "" && f.Overrides.Context.Namespace == ""
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseConnectionFlags(tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.check(f) {
+				t.Errorf("Unexpected flags parsed from %v: %+v", tt.args, f)
+			}
+		})
+	}
+}
+
+func TestConnectionFlagsClientConfig(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	tests := []struct {
+		name      string
+		args      []string
+		host      string
+		context   string
+		namespace string
+	}{
+		{
+			name:      "current context",
+			args:      []string{"--kubeconfig", kubeconfig, "get", "pods"},
+			host:      "https://dev.example.com:6443",
+			context:   "dev",
+			namespace: "dev-ns",
+		},
+		{
+			name:      "context flag",
+			args:      []string{"--kubeconfig", kubeconfig, "--context", "prod", "get", "pods"},
+			host:      "https://prod.example.com:6443",
+			context:   "prod",
+			namespace: "default",
+		},
+		{
+			name:      "cluster flag",
+			args:      []string{"--kubeconfig", kubeconfig, "--cluster=prod", "get", "pods", "-n", "web"},
+			host:      "https://prod.example.com:6443",
+			context:   "dev",
+			namespace: "web",
+		},
+		{
+			name:      "server flag",
+			args:      []string{"--kubeconfig=" + kubeconfig, "-s", "https://other.example.com:6443", "get", "pods"},
+			host:      "https://other.example.com:6443",
+			context:   "dev",
+			namespace: "dev-ns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseConnectionFlags(tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			restConfig, err := f.ClientConfig().ClientConfig()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if restConfig.Host != tt.host {
+				t.Errorf("Got host %s instead of %s", restConfig.Host, tt.host)
+			}
+
+			context, err := f.ContextName()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if context != tt.context {
+				t.Errorf("Got context %s instead of %s", context, tt.context)
+			}
+
+			namespace, err := f.Namespace()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if namespace != tt.namespace {
+				t.Errorf("Got namespace %s instead of %s", namespace, tt.namespace)
+			}
+		})
+	}
+}
+
+func TestConnectionFlagsTokenAndInsecure(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	f, err := ParseConnectionFlags([]string{
+		"--kubeconfig", kubeconfig,
+		"--token", "other-token",
+		"--insecure-skip-tls-verify",
+		"get", "pods",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restConfig, err := f.ClientConfig().ClientConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restConfig.BearerToken != "other-token" {
+		t.Errorf("Got token %s instead of other-token", restConfig.BearerToken)
+	}
+	if !restConfig.Insecure {
+		t.Error("Expected insecure connection")
+	}
+}
+
+func TestConnectionFlagsKubeconfigEnv(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	os.Setenv("KUBECONFIG", kubeconfig)
+	defer os.Unsetenv("KUBECONFIG")
+
+	f, err := ParseConnectionFlags([]string{"--context", "prod", "get", "pods"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	restConfig, err := f.ClientConfig().ClientConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restConfig.Host != "https://prod.example.com:6443" {
+		t.Errorf("Got host %s instead of https://prod.example.com:6443", restConfig.Host)
+	}
+}
+
+const ekeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+users:
+- name: alice
+  user:
+    token: alice-token
+- name: eke
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: /usr/local/bin/eke
+      args: ["kubeconfig", "auth"]
+- name: aws
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: ["eks", "get-token"]
+contexts:
+- name: ews-b
+  context:
+    cluster: dev
+    user: eke
+- name: ews-a
+  context:
+    cluster: dev
+    user: eke
+- name: static
+  context:
+    cluster: dev
+    user: alice
+- name: other-exec
+  context:
+    cluster: dev
+    user: aws
+current-context: static
+`
+
+func TestEkeContexts(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(kubeconfig, []byte(ekeKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ParseConnectionFlags([]string{"--kubeconfig", kubeconfig})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	contexts, err := f.EkeContexts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(contexts, ",") != "ews-a,ews-b" {
+		t.Errorf("Got contexts %v instead of [ews-a ews-b]", contexts)
+	}
+
+	contexts, err = f.Contexts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(contexts, ",") != "ews-a,ews-b,other-exec,static" {
+		t.Errorf("Got contexts %v", contexts)
+	}
+
+	clusters, err := f.Clusters()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(clusters, ",") != "dev" {
+		t.Errorf("Got clusters %v instead of [dev]", clusters)
+	}
+}
+
+func TestContextInfos(t *testing.T) {
+	f, err := ParseConnectionFlags([]string{"--kubeconfig", writeTestKubeconfig(t)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	contexts, err := f.ContextInfos()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []ContextInfo{
+		{Name: "dev", Cluster: "dev", Server: "https://dev.example.com:6443", User: "alice", Namespace: "dev-ns", Current: true},
+		{Name: "prod", Cluster: "prod", Server: "https://prod.example.com:6443", User: "alice"},
+	}
+	if len(contexts) != len(want) {
+		t.Fatalf("Got contexts %+v instead of %+v", contexts, want)
+	}
+	for i := range want {
+		if contexts[i] != want[i] {
+			t.Errorf("Got context %+v instead of %+v", contexts[i], want[i])
+		}
+	}
+
+	f.Overrides.CurrentContext = "prod"
+	contexts, err = f.ContextInfos()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if contexts[0].Current || !contexts[1].Current {
+		t.Errorf("Expected --context to select the current context, got %+v", contexts)
+	}
+}
@@ -0,0 +1,108 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// Manifest maps file names to their sha256 checksum. It is serialized in
+// the format of `sha256sum`, one "<checksum>  <name>" line per file
+type Manifest map[string]string
+
+// Parse reads a manifest in the format of `sha256sum`. Empty lines and
+// lines starting with '#' are ignored
+func Parse(data []byte) (Manifest, error) {
+	m := Manifest{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid checksum manifest line %d: %q", n, line)
+		}
+		checksum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid checksum manifest line %d: %q is not a sha256 checksum", n, fields[0])
+		}
+		// `sha256sum -b` marks the names with a '*'
+		m[strings.TrimPrefix(fields[1], "*")] = checksum
+	}
+	return m, scanner.Err()
+}
+
+// Bytes serializes the manifest, entries are sorted by name
+func (m Manifest) Bytes() []byte {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", m[name], name)
+	}
+	return b.Bytes()
+}
+
+// KubectlEntry returns the name of the kubectl binary with the given
+// version, os and architecture inside of a manifest,
+// e.g. v1.22.3/linux/amd64/kubectl
+func KubectlEntry(version semver.Version, goos, goarch string) string {
+	return Entry("kubectl", version, goos, goarch)
+}
+
+// Entry works like KubectlEntry for any binary, e.g. v3.10.2/linux/amd64/helm
+func Entry(binary string, version semver.Version, goos, goarch string) string {
+	ext := ""
+	if goos == "windows" {
+		ext = ".exe"
+	}
+	return fmt.Sprintf("v%d.%d.%d/%s/%s/%s%s", version.Major, version.Minor, version.Patch, goos, goarch, binary, ext)
+}
+
+// LookupKubectl returns the checksum of the given kubectl binary. Entries
+// without the version prefix, like linux/amd64/kubectl, are accepted as
+// well since manifests are often published per version
+func (m Manifest) LookupKubectl(version semver.Version, goos, goarch string) (string, bool) {
+	return m.Lookup("kubectl", version, goos, goarch)
+}
+
+// Lookup works like LookupKubectl for any binary
+func (m Manifest) Lookup(binary string, version semver.Version, goos, goarch string) (string, bool) {
+	entry := Entry(binary, version, goos, goarch)
+	if checksum, found := m[entry]; found {
+		return checksum, true
+	}
+	checksum, found := m[entry[strings.Index(entry, "/")+1:]]
+	return checksum, found
+}
+
+// FileChecksum returns the hex encoded sha256 checksum of a file
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
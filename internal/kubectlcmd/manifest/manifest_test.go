@@ -0,0 +1,73 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+const testChecksum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestParse(t *testing.T) {
+	data := `# kubectl v1.22.3
+` + testChecksum + `  v1.22.3/linux/amd64/kubectl
+
+` + strings.ToUpper(testChecksum) + ` *darwin/arm64/kubectl
+`
+	m, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	v := semver.MustParse("1.22.3")
+	for _, platform := range [][2]string{{"linux", "amd64"}, {"darwin", "arm64"}} {
+		checksum, found := m.LookupKubectl(v, platform[0], platform[1])
+		if !found || checksum != testChecksum {
+			t.Errorf("Got %q, %v for %v", checksum, found, platform)
+		}
+	}
+	if _, found := m.LookupKubectl(v, "windows", "amd64"); found {
+		t.Error("Unexpected windows entry")
+	}
+	if KubectlEntry(v, "windows", "amd64") != "v1.22.3/windows/amd64/kubectl.exe" {
+		t.Errorf("Unexpected windows entry name %s", KubectlEntry(v, "windows", "amd64"))
+	}
+
+	reparsed, err := Parse(m.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reparsed) != 2 || reparsed["darwin/arm64/kubectl"] != testChecksum {
+		t.Errorf("Unexpected manifest after a round trip: %v", reparsed)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, data := range []string{
+		"not-a-checksum  linux/amd64/kubectl",
+		testChecksum,
+		testChecksum + " two names",
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestFileChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksum, err := FileChecksum(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if checksum != testChecksum {
+		t.Errorf("Got %s instead of %s", checksum, testChecksum)
+	}
+}
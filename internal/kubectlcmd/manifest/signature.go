@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Verifier checks the signature of manifests against a set of trusted
+// public keys. Supported keys are:
+//   - ed25519 keys, either PEM encoded or as the base64 encoding of the
+//     raw 32 bytes key; signatures are made on the manifest itself
+//   - ECDSA keys, PEM encoded, as generated by `cosign generate-key-pair`;
+//     signatures are made on the sha256 digest of the manifest, as done
+//     by `cosign sign-blob --key`
+//
+// Signatures are either raw or base64 encoded
+type Verifier struct {
+	keys []crypto.PublicKey
+}
+
+// NewVerifier returns a Verifier trusting the given public keys
+func NewVerifier(publicKeys []string) (*Verifier, error) {
+	v := &Verifier{}
+	for i, k := range publicKeys {
+		key, err := ParsePublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key #%d: %v", i+1, err)
+		}
+		v.keys = append(v.keys, key)
+	}
+	return v, nil
+}
+
+// ParsePublicKey parses a PEM encoded ed25519 or ECDSA public key, or a
+// base64 encoded raw ed25519 key
+func ParsePublicKey(s string) (crypto.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("neither a PEM nor a base64 encoded key")
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("raw ed25519 keys are %d bytes long, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// HasKeys returns true when at least one key is trusted
+func (v *Verifier) HasKeys() bool {
+	return v != nil && len(v.keys) > 0
+}
+
+// Verify checks that signature has been made on data by one of the
+// trusted keys
+func (v *Verifier) Verify(data, signature []byte) error {
+	if !v.HasKeys() {
+		return errors.New("no public key configured to verify signatures")
+	}
+
+	sig := signature
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature))); err == nil {
+		sig = decoded
+	}
+
+	digest := sha256.Sum256(data)
+	for _, key := range v.keys {
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, data, sig) {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], sig) {
+				return nil
+			}
+		}
+	}
+	return errors.New("signature does not match any of the trusted public keys")
+}
@@ -0,0 +1,97 @@
+package manifest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+)
+
+func pemPublicKey(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerifier(t *testing.T) {
+	data := []byte(testChecksum + "  v1.22.3/linux/amd64/kubectl\n")
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecPriv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSig := ed25519.Sign(edPriv, data)
+
+	tests := []struct {
+		name      string
+		key       string
+		signature []byte
+	}{
+		{"raw ed25519 key, raw signature", base64.StdEncoding.EncodeToString(edPub), edSig},
+		{"pem ed25519 key, base64 signature", pemPublicKey(t, edPub), []byte(base64.StdEncoding.EncodeToString(edSig) + "\n")},
+		{"cosign ecdsa key", pemPublicKey(t, &ecPriv.PublicKey), []byte(base64.StdEncoding.EncodeToString(ecSig))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := NewVerifier([]string{base64.StdEncoding.EncodeToString(otherPub), tt.key})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if err := v.Verify(data, tt.signature); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			tampered := append([]byte{}, data...)
+			tampered[0] = 'f'
+			if err := v.Verify(tampered, tt.signature); err == nil {
+				t.Error("Expected the signature of a tampered manifest to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifierWithoutKeys(t *testing.T) {
+	v, err := NewVerifier(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := v.Verify([]byte("data"), []byte("signature")); err == nil {
+		t.Error("Expected verification to fail without keys")
+	}
+}
+
+func TestInvalidPublicKeys(t *testing.T) {
+	for _, key := range []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte("too short")),
+		"-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n",
+	} {
+		if _, err := NewVerifier([]string{key}); err == nil {
+			t.Errorf("Expected %q to be rejected", key)
+		}
+	}
+}
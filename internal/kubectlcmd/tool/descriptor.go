@@ -0,0 +1,171 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/finder"
+	"eke/internal/kubectlcmd/osexec"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+// KubectlName is the name of the built-in kubectl tool, it is handled by
+// the kubectl version-matching stack instead of a descriptor
+const KubectlName = "kubectl"
+
+// Descriptor is a parsed cmdconfig.ToolDescriptor
+type Descriptor struct {
+	Name         string
+	Binary       string
+	NamingScheme string
+	Mirrors      []cmdconfig.KubectlMirror
+	ArchivePath  string
+	VersionArgs  []string
+	ContextFlag  string
+	// Builtin is set for kubectl, the other fields are not used
+	Builtin bool
+	rules   []compatibilityRule
+}
+
+// compatibilityRule is a parsed cmdconfig.ToolCompatibilityRule
+type compatibilityRule struct {
+	rule     cmdconfig.ToolCompatibilityRule
+	server   semver.Range
+	version  semver.Range
+	download *semver.Version
+}
+
+// NewDescriptor validates the given tool configuration and fills in the
+// defaults
+func NewDescriptor(c cmdconfig.ToolDescriptor) (*Descriptor, error) {
+	if c.Name == "" {
+		return nil, errors.New("invalid tool: name must be set")
+	}
+	if c.Name == KubectlName {
+		return nil, fmt.Errorf("invalid tool %s: kubectl is built in, it is configured with ekeKubectlConfig", c.Name)
+	}
+	if strings.ContainsAny(c.Name, `/\`) {
+		return nil, fmt.Errorf("invalid tool %s: the name cannot contain path separators", c.Name)
+	}
+
+	d := &Descriptor{
+		Name:         c.Name,
+		Binary:       c.Binary,
+		NamingScheme: c.NamingScheme,
+		Mirrors:      c.Mirrors,
+		ArchivePath:  c.ArchivePath,
+		VersionArgs:  c.VersionArgs,
+		ContextFlag:  strings.TrimLeft(c.ContextFlag, "-"),
+	}
+	if d.Binary == "" {
+		d.Binary = d.Name
+	}
+	if d.NamingScheme == "" {
+		d.NamingScheme = d.Binary + common.KubectlLocalNamingScheme[len("kubectl"):]
+	}
+	if _, err := d.inferVersion(d.LocalName(semver.MustParse("1.2.3"))); err != nil {
+		return nil, fmt.Errorf("invalid tool %s: the naming scheme %q must hold the major, minor and patch versions, like %s%%d.%%d.%%d",
+			d.Name, d.NamingScheme, d.Binary)
+	}
+
+	for _, r := range c.Compatibility {
+		rule := compatibilityRule{rule: r}
+		var err error
+		if rule.server, _, err = finder.ParseVersionConstraint(r.Server); err != nil {
+			return nil, fmt.Errorf("invalid tool %s: server %q: %v", d.Name, r.Server, err)
+		}
+		if rule.version, _, err = finder.ParseVersionConstraint(r.Version); err != nil {
+			return nil, fmt.Errorf("invalid tool %s: version %q: %v", d.Name, r.Version, err)
+		}
+		if r.Download != "" {
+			v, err := semver.ParseTolerant(r.Download)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool %s: download %q: %v", d.Name, r.Download, err)
+			}
+			if !rule.version(v) {
+				return nil, fmt.Errorf("invalid tool %s: download %s does not satisfy the version %q", d.Name, v, r.Version)
+			}
+			rule.download = &v
+		}
+		d.rules = append(d.rules, rule)
+	}
+	return d, nil
+}
+
+// Lookup returns the descriptor of the tool called name, kubectl is
+// always available
+func Lookup(config cmdconfig.EkeCmdConfig, name string) (*Descriptor, error) {
+	if name == KubectlName {
+		return &Descriptor{Name: KubectlName, Binary: KubectlName, Builtin: true}, nil
+	}
+
+	for _, c := range config.Tools {
+		if c.Name == name {
+			return NewDescriptor(c)
+		}
+	}
+	return nil, fmt.Errorf("unknown tool %s, tools are described in the tools section of eke.cmd.yaml", name)
+}
+
+// LocalName returns how the binary of the tool with the given version is
+// named once downloaded
+func (d *Descriptor) LocalName(v semver.Version) string {
+	return fmt.Sprintf(d.NamingScheme+osexec.Ext, v.Major, v.Minor, v.Patch)
+}
+
+// LocalDir returns where the binaries of the tool are downloaded to
+func (d *Descriptor) LocalDir() string {
+	return common.LocalToolDownloadDir(d.Name)
+}
+
+// inferVersion returns the version held by the name of a downloaded binary
+func (d *Descriptor) inferVersion(filename string) (semver.Version, error) {
+	var major, minor, patch uint64
+	n, err := fmt.Sscanf(osexec.TrimExt(filename), d.NamingScheme, &major, &minor, &patch)
+	if n != 3 || err != nil {
+		return semver.Version{}, errors.New("not parsable")
+	}
+
+	v := semver.Version{Major: major, Minor: minor, Patch: patch}
+	// Sscanf ignores trailing characters, e.g. helm3.10.2.bak
+	if d.LocalName(v) != filename {
+		return semver.Version{}, errors.New("not parsable")
+	}
+	return v, nil
+}
+
+// ruleFor returns the first compatibility rule matching the server version
+func (d *Descriptor) ruleFor(server semver.Version) (*compatibilityRule, error) {
+	for i := range d.rules {
+		if d.rules[i].server(server) {
+			return &d.rules[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no compatibility rule of %s covers kubernetes %s", d.Name, server)
+}
+
+// ServerArgs translates the context flag of the tool to the kubectl one,
+// so that the server the tool talks to can be found
+func (d *Descriptor) ServerArgs(args []string) []string {
+	if d.ContextFlag == "" || d.ContextFlag == "context" {
+		return args
+	}
+
+	res := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--"+d.ContextFlag {
+			arg = "--context"
+		} else if strings.HasPrefix(arg, "--"+d.ContextFlag+"=") {
+			arg = "--context=" + strings.TrimPrefix(arg, "--"+d.ContextFlag+"=")
+		}
+		res = append(res, arg)
+	}
+	return res
+}
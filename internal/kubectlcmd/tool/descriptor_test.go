@@ -0,0 +1,125 @@
+package tool
+
+import (
+	"strings"
+	"testing"
+
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestNewDescriptorDefaults(t *testing.T) {
+	d, err := NewDescriptor(cmdconfig.ToolDescriptor{Name: "helm"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.Binary != "helm" {
+		t.Errorf("Got binary %s instead of helm", d.Binary)
+	}
+
+	v, err := d.inferVersion(d.LocalName(semver.MustParse("3.10.2")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !v.Equals(semver.MustParse("3.10.2")) {
+		t.Errorf("Got %s instead of 3.10.2", v)
+	}
+	for _, name := range []string{"helm", "helm3.10", "helm3.10.2.bak", "kubectl1.22.3"} {
+		if _, err := d.inferVersion(name); err == nil {
+			t.Errorf("Expected %s not to be a downloaded helm binary", name)
+		}
+	}
+}
+
+func TestNewDescriptorInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config cmdconfig.ToolDescriptor
+		errMsg string
+	}{
+		{"missing name", cmdconfig.ToolDescriptor{}, "name must be set"},
+		{"kubectl", cmdconfig.ToolDescriptor{Name: "kubectl"}, "built in"},
+		{"path in name", cmdconfig.ToolDescriptor{Name: "../helm"}, "path separators"},
+		{"naming scheme", cmdconfig.ToolDescriptor{Name: "helm", NamingScheme: "helm-%d"}, "naming scheme"},
+		{
+			"invalid server",
+			cmdconfig.ToolDescriptor{Name: "helm", Compatibility: []cmdconfig.ToolCompatibilityRule{{Server: "nope", Version: "3.x"}}},
+			"server",
+		},
+		{
+			"download outside of version",
+			cmdconfig.ToolDescriptor{Name: "helm", Compatibility: []cmdconfig.ToolCompatibilityRule{{Server: ">=1.25.0", Version: "3.11.x", Download: "3.10.3"}}},
+			"does not satisfy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDescriptor(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	config := cmdconfig.EkeCmdConfig{Tools: []cmdconfig.ToolDescriptor{{Name: "helm"}}}
+
+	d, err := Lookup(config, "kubectl")
+	if err != nil || !d.Builtin {
+		t.Errorf("Expected kubectl to be built in, got %+v, %v", d, err)
+	}
+	d, err = Lookup(config, "helm")
+	if err != nil || d.Builtin || d.Name != "helm" {
+		t.Errorf("Expected the helm descriptor, got %+v, %v", d, err)
+	}
+	if _, err := Lookup(config, "kustomize"); err == nil {
+		t.Error("Expected an error for an unknown tool")
+	}
+}
+
+func TestRuleFor(t *testing.T) {
+	d, err := NewDescriptor(cmdconfig.ToolDescriptor{
+		Name: "helm",
+		Compatibility: []cmdconfig.ToolCompatibilityRule{
+			{Server: ">=1.25.0", Version: ">=3.11.0"},
+			{Server: ">=1.22.0", Version: ">=3.8.0 <3.11.0"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rule, err := d.ruleFor(semver.MustParse("1.26.1"))
+	if err != nil || rule.rule.Version != ">=3.11.0" {
+		t.Errorf("Expected the first rule to match, got %+v, %v", rule, err)
+	}
+	rule, err = d.ruleFor(semver.MustParse("1.23.4"))
+	if err != nil || rule.rule.Version != ">=3.8.0 <3.11.0" {
+		t.Errorf("Expected the second rule to match, got %+v, %v", rule, err)
+	}
+	if _, err := d.ruleFor(semver.MustParse("1.20.0")); err == nil {
+		t.Error("Expected no rule to match")
+	}
+}
+
+func TestServerArgs(t *testing.T) {
+	d := &Descriptor{Name: "helm", ContextFlag: "kube-context"}
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"list", "--kube-context", "prod"}, []string{"list", "--context", "prod"}},
+		{[]string{"--kube-context=prod", "-n", "web"}, []string{"--context=prod", "-n", "web"}},
+		{[]string{"install", "--", "--kube-context", "prod"}, []string{"install"}},
+	}
+	for _, tt := range tests {
+		actual := d.ServerArgs(tt.args)
+		if strings.Join(actual, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Got %v instead of %v", actual, tt.expected)
+		}
+	}
+}
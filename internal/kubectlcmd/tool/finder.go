@@ -0,0 +1,97 @@
+package tool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"eke/internal/kubectlcmd/osexec"
+
+	"github.com/blang/semver/v4"
+)
+
+// Binary describes a binary of a tool
+type Binary struct {
+	Path    string
+	Version semver.Version
+}
+
+// Finder looks for the binaries of a tool
+type Finder struct {
+	Tool            *Descriptor
+	LocalBinaryPath string
+	// SearchPATH enables the search of binaries in the PATH directories
+	SearchPATH bool
+	// Prober finds out the version of the binaries named after the tool,
+	// they are ignored when nil or when the tool has no version arguments
+	Prober versionProber
+}
+
+// NewFinder returns a Finder looking for the binaries of tool in its
+// download directory
+func NewFinder(tool *Descriptor) *Finder {
+	return &Finder{
+		Tool:            tool,
+		LocalBinaryPath: tool.LocalDir(),
+	}
+}
+
+// Binaries returns the binaries of the tool, the most recent first.
+// Downloaded binaries come before the other ones with the same version
+func (f *Finder) Binaries() []Binary {
+	// the download directory is known to hold versioned binaries only
+	bins := f.binariesIn(f.LocalBinaryPath, false)
+
+	if f.SearchPATH {
+		seen := map[string]bool{filepath.Clean(f.LocalBinaryPath): true}
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			if dir == "" || seen[filepath.Clean(dir)] {
+				continue
+			}
+			seen[filepath.Clean(dir)] = true
+			bins = append(bins, f.binariesIn(dir, true)...)
+		}
+	}
+
+	sort.SliceStable(bins, func(i, j int) bool {
+		return bins[i].Version.GT(bins[j].Version)
+	})
+	return bins
+}
+
+func (f *Finder) binariesIn(dir string, probe bool) []Binary {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var bins []Binary
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		v, err := f.Tool.inferVersion(entry.Name())
+		if err != nil && probe && f.canProbe() && osexec.TrimExt(entry.Name()) == f.Tool.Binary {
+			v, err = f.probeVersion(path)
+		}
+		if err != nil {
+			continue
+		}
+		bins = append(bins, Binary{Path: path, Version: v})
+	}
+	return bins
+}
+
+func (f *Finder) canProbe() bool {
+	return f.Prober != nil && len(f.Tool.VersionArgs) > 0
+}
+
+func (f *Finder) probeVersion(path string) (semver.Version, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return semver.Version{}, os.ErrInvalid
+	}
+	return f.Prober.ProbeVersion(path, info, f.Tool.VersionArgs)
+}
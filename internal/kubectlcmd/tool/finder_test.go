@@ -0,0 +1,108 @@
+package tool
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eke/internal/kubectlcmd/osexec"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+type mockProber struct {
+	versions map[string]string
+}
+
+func (m *mockProber) ProbeVersion(path string, info os.FileInfo, args []string) (semver.Version, error) {
+	v, found := m.versions[path]
+	if !found {
+		return semver.Version{}, errors.New("unknown binary")
+	}
+	return semver.MustParse(v), nil
+}
+
+func writeFakeBinary(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFinderBinaries(t *testing.T) {
+	d, err := NewDescriptor(cmdconfig.ToolDescriptor{Name: "helm", VersionArgs: []string{"version"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	pathDir := t.TempDir()
+	writeFakeBinary(t, local, d.LocalName(semver.MustParse("3.10.2")))
+	writeFakeBinary(t, local, d.LocalName(semver.MustParse("3.11.0")))
+	writeFakeBinary(t, local, "notes.txt")
+	probed := writeFakeBinary(t, pathDir, "helm"+osexec.Ext)
+	writeFakeBinary(t, pathDir, "kustomize"+osexec.Ext)
+
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", pathDir)
+	defer os.Setenv("PATH", oldPath)
+
+	f := &Finder{
+		Tool:            d,
+		LocalBinaryPath: local,
+		SearchPATH:      true,
+		Prober:          &mockProber{versions: map[string]string{probed: "3.10.9"}},
+	}
+	bins := f.Binaries()
+
+	expected := []string{"3.11.0", "3.10.9", "3.10.2"}
+	if len(bins) != len(expected) {
+		t.Fatalf("Got %d binaries instead of %d: %+v", len(bins), len(expected), bins)
+	}
+	for i, v := range expected {
+		if !bins[i].Version.Equals(semver.MustParse(v)) {
+			t.Errorf("Got %s instead of %s at position %d", bins[i].Version, v, i)
+		}
+	}
+	if bins[1].Path != probed {
+		t.Errorf("Got %s instead of %s", bins[1].Path, probed)
+	}
+
+	f.SearchPATH = false
+	if bins := f.Binaries(); len(bins) != 2 {
+		t.Errorf("Expected only the downloaded binaries, got %+v", bins)
+	}
+}
+
+func TestParseVersionOutput(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{"v3.10.2+g50f003e", "3.10.2"},
+		{"{Version:kustomize/v4.5.7 GitCommit:56d82a8 BuildDate:2022-08-02}", "4.5.7"},
+		{"v5.0.1\n", "5.0.1"},
+	}
+	for _, tt := range tests {
+		actual, err := parseVersionOutput([]byte(tt.output))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !actual.Equals(semver.MustParse(tt.expected)) {
+			t.Errorf("Got %s instead of %s", actual, tt.expected)
+		}
+	}
+
+	if _, err := parseVersionOutput([]byte("unknown")); err == nil {
+		t.Error("Expected an error when no version is printed")
+	}
+}
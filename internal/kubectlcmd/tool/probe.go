@@ -0,0 +1,92 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"time"
+
+	"eke/internal/kubectlcmd/cache"
+
+	"github.com/blang/semver/v4"
+)
+
+// VersionCacheFile is the name of the file, inside of the cache directory,
+// holding the versions reported by the tool binaries
+const VersionCacheFile = "tool-versions.json"
+
+// probeTimeout bounds the time a binary has to report its version
+var probeTimeout = 5 * time.Second
+
+var versionPattern = regexp.MustCompile(`v?\d+\.\d+\.\d+`)
+
+type versionProber interface {
+	ProbeVersion(path string, info os.FileInfo, args []string) (semver.Version, error)
+}
+
+type binaryVersionCache interface {
+	Get(path string, info os.FileInfo) (cache.BinaryVersion, bool)
+	Set(path string, info os.FileInfo, version semver.Version) error
+	SetError(path string, info os.FileInfo, probeErr error) error
+}
+
+// Prober finds out the version of tool binaries that do not have it in
+// their name by running them with the version arguments of the tool.
+// Answers and failures are cached until the binary changes
+type Prober struct {
+	cache binaryVersionCache
+	run   func(path string, args []string) ([]byte, error)
+}
+
+// NewProber returns a Prober caching the versions in the given file
+func NewProber(cachePath string) *Prober {
+	return &Prober{
+		cache: cache.NewBinaryVersions(cachePath),
+		run:   runVersion,
+	}
+}
+
+// ProbeVersion returns the version printed by the binary at path when run
+// with args, info describes the binary
+func (p *Prober) ProbeVersion(path string, info os.FileInfo, args []string) (semver.Version, error) {
+	if entry, found := p.cache.Get(path, info); found {
+		if entry.Error != "" {
+			return semver.Version{}, fmt.Errorf("cannot get the version of %s: %s", path, entry.Error)
+		}
+		return entry.Version, nil
+	}
+
+	out, err := p.run(path, args)
+	version := semver.Version{}
+	if err == nil {
+		version, err = parseVersionOutput(out)
+	}
+	// failing to cache only means probing again next time. Failures are
+	// cached as well, a broken binary is not run on every lookup
+	if err != nil {
+		_ = p.cache.SetError(path, info, err)
+		return semver.Version{}, fmt.Errorf("cannot get the version of %s: %v", path, err)
+	}
+	_ = p.cache.Set(path, info, version)
+	return version, nil
+}
+
+func runVersion(path string, args []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, path, args...).Output()
+}
+
+// parseVersionOutput returns the first version printed by a tool, only
+// the major, minor and patch versions are kept
+func parseVersionOutput(out []byte) (semver.Version, error) {
+	match := versionPattern.Find(out)
+	if match == nil {
+		return semver.Version{}, errors.New("no version found in the output")
+	}
+	return semver.ParseTolerant(string(match))
+}
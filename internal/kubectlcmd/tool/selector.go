@@ -0,0 +1,169 @@
+package tool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/downloader"
+	"eke/internal/pkg/archive"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+type serverVersioner interface {
+	KubectlVersionToUse(timeout int64) (semver.Version, error)
+}
+
+type binaryFinder interface {
+	Binaries() []Binary
+}
+
+type downloadHelper interface {
+	GetKubectlBinary(version semver.Version, destination string) error
+}
+
+// Selector picks the binary of a tool compatible with the cluster
+type Selector struct {
+	tool       *Descriptor
+	finder     binaryFinder
+	server     serverVersioner
+	downloader downloadHelper
+	localDir   string
+}
+
+// NewSelector returns a Selector picking among the binaries found by f.
+// server provides the version of the cluster, downloaded binaries are
+// checked with the given verification settings
+func NewSelector(tool *Descriptor, f binaryFinder, server serverVersioner, verification cmdconfig.KubectlVerification) *Selector {
+	d := downloader.NewDownloder(tool.Mirrors)
+	d.Binary = tool.Binary
+	d.Verification = verification
+
+	return &Selector{
+		tool:       tool,
+		finder:     f,
+		server:     server,
+		downloader: d,
+		localDir:   tool.LocalDir(),
+	}
+}
+
+// BinaryToUse returns the binary of the tool to run against the cluster,
+// downloading it when needed and allowed. Tools without compatibility
+// rules use their most recent binary
+func (s *Selector) BinaryToUse(timeout int64, allowDownload bool) (Binary, error) {
+	bins := s.finder.Binaries()
+	if len(s.tool.rules) == 0 {
+		if len(bins) == 0 {
+			return Binary{}, fmt.Errorf("no %s binary found in %s or in the PATH", s.tool.Name, s.localDir)
+		}
+		return bins[0], nil
+	}
+
+	server, err := s.server.KubectlVersionToUse(timeout)
+	if err != nil {
+		return Binary{}, fmt.Errorf("cannot find out the version of the cluster: %v", err)
+	}
+	rule, err := s.tool.ruleFor(server)
+	if err != nil {
+		return Binary{}, err
+	}
+	for _, b := range bins {
+		if rule.version(b.Version) {
+			return b, nil
+		}
+	}
+
+	missing := fmt.Sprintf("no %s binary satisfies the version %q required by kubernetes %s", s.tool.Name, rule.rule.Version, server)
+	switch {
+	case rule.download == nil:
+		return Binary{}, errors.New(missing)
+	case !allowDownload:
+		return Binary{}, fmt.Errorf("%s, binary downloads are disabled", missing)
+	case len(s.tool.Mirrors) == 0:
+		return Binary{}, fmt.Errorf("%s, the tool has no mirror to download it from", missing)
+	}
+
+	destination := filepath.Join(s.localDir, s.tool.LocalName(*rule.download))
+	fmt.Fprintf(os.Stderr, "Right %s missing, downloading version %s\n", s.tool.Name, rule.download)
+	if err := s.download(*rule.download, destination); err != nil {
+		return Binary{}, err
+	}
+	return Binary{Path: destination, Version: *rule.download}, nil
+}
+
+// download fetches the binary with the given version, extracting it from
+// its archive when the tool is distributed as tar.gz archives
+func (s *Selector) download(version semver.Version, destination string) error {
+	if s.tool.ArchivePath == "" {
+		return s.downloader.GetKubectlBinary(version, destination)
+	}
+
+	member, err := s.archiveMember(version)
+	if err != nil {
+		return err
+	}
+	archivePath := destination + ".tar.gz"
+	if err := s.downloader.GetKubectlBinary(version, archivePath); err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+
+	return extractBinary(archivePath, member, destination)
+}
+
+// archiveMember renders the location of the binary inside of the archive
+// of the given version
+func (s *Selector) archiveMember(version semver.Version) (string, error) {
+	t, err := template.New("archivePath").Option("missingkey=error").Parse(s.tool.ArchivePath)
+	if err != nil {
+		return "", fmt.Errorf("invalid archive path template %q: %v", s.tool.ArchivePath, err)
+	}
+
+	p := common.HostPlatform()
+	data := map[string]string{
+		"Version": fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch),
+		"OS":      p.OS,
+		"Arch":    p.Arch,
+		"Ext":     p.Ext(),
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid archive path template %q: %v", s.tool.ArchivePath, err)
+	}
+	return b.String(), nil
+}
+
+func extractBinary(archivePath, member, destination string) error {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := destination + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if err := archive.ExtractFile(in, member, out); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, destination); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package tool
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/pkg/config/cmdconfig"
+
+	"github.com/blang/semver/v4"
+)
+
+type mockFinder struct {
+	bins []Binary
+}
+
+func (m *mockFinder) Binaries() []Binary {
+	return m.bins
+}
+
+type mockServer struct {
+	version string
+	err     error
+}
+
+func (m *mockServer) KubectlVersionToUse(timeout int64) (semver.Version, error) {
+	if m.err != nil {
+		return semver.Version{}, m.err
+	}
+	return semver.MustParse(m.version), nil
+}
+
+type mockDownloader struct {
+	getKubectlBinary func(version semver.Version, destination string) error
+	downloaded       []string
+}
+
+func (m *mockDownloader) GetKubectlBinary(version semver.Version, destination string) error {
+	m.downloaded = append(m.downloaded, version.String())
+	return m.getKubectlBinary(version, destination)
+}
+
+func newHelmDescriptor(t *testing.T, archivePath string) *Descriptor {
+	t.Helper()
+
+	d, err := NewDescriptor(cmdconfig.ToolDescriptor{
+		Name:        "helm",
+		Mirrors:     []cmdconfig.KubectlMirror{{URL: "https://get.example.com/helm-v{{.Version}}.tar.gz"}},
+		ArchivePath: archivePath,
+		Compatibility: []cmdconfig.ToolCompatibilityRule{
+			{Server: ">=1.25.0", Version: ">=3.11.0", Download: "3.11.3"},
+			{Server: ">=1.22.0 <1.25.0", Version: ">=3.8.0 <3.11.0"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newTestSelector(t *testing.T, d *Descriptor, bins []Binary, server string, dl *mockDownloader) *Selector {
+	return &Selector{
+		tool:       d,
+		finder:     &mockFinder{bins: bins},
+		server:     &mockServer{version: server},
+		downloader: dl,
+		localDir:   t.TempDir(),
+	}
+}
+
+func writeArchive(t *testing.T, path, member, content string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	if err := tw.WriteHeader(&tar.Header{Name: member, Mode: 0755, Typeflag: tar.TypeReg, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBinaryToUseInstalled(t *testing.T) {
+	bins := []Binary{
+		{Path: "/bin/helm3.11.1", Version: semver.MustParse("3.11.1")},
+		{Path: "/bin/helm3.10.2", Version: semver.MustParse("3.10.2")},
+		{Path: "/bin/helm3.7.0", Version: semver.MustParse("3.7.0")},
+	}
+
+	tests := []struct {
+		server   string
+		expected string
+	}{
+		{"1.26.0", "/bin/helm3.11.1"},
+		{"1.24.3", "/bin/helm3.10.2"},
+	}
+	for _, tt := range tests {
+		s := newTestSelector(t, newHelmDescriptor(t, ""), bins, tt.server, nil)
+		bin, err := s.BinaryToUse(5, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if bin.Path != tt.expected {
+			t.Errorf("Got %s instead of %s for kubernetes %s", bin.Path, tt.expected, tt.server)
+		}
+	}
+}
+
+func TestBinaryToUseMissing(t *testing.T) {
+	bins := []Binary{{Path: "/bin/helm3.7.0", Version: semver.MustParse("3.7.0")}}
+
+	tests := []struct {
+		name          string
+		server        string
+		allowDownload bool
+		errMsg        string
+	}{
+		{"no rule", "1.20.0", true, "no compatibility rule"},
+		{"no download version", "1.23.0", true, "no helm binary satisfies"},
+		{"downloads disabled", "1.25.0", false, "downloads are disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSelector(t, newHelmDescriptor(t, ""), bins, tt.server, nil)
+			_, err := s.BinaryToUse(5, tt.allowDownload)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestBinaryToUseUnknownServer(t *testing.T) {
+	s := newTestSelector(t, newHelmDescriptor(t, ""), nil, "", nil)
+	s.server = &mockServer{err: errors.New("unreachable")}
+	if _, err := s.BinaryToUse(5, true); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("Expected the server error, got %v", err)
+	}
+}
+
+func TestBinaryToUseWithoutRules(t *testing.T) {
+	d, err := NewDescriptor(cmdconfig.ToolDescriptor{Name: "kustomize"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bins := []Binary{{Path: "/bin/kustomize", Version: semver.MustParse("5.0.1")}}
+
+	s := newTestSelector(t, d, bins, "1.20.0", nil)
+	s.server = &mockServer{err: errors.New("the server is not needed")}
+	bin, err := s.BinaryToUse(5, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bin.Path != "/bin/kustomize" {
+		t.Errorf("Got %s instead of /bin/kustomize", bin.Path)
+	}
+
+	s.finder = &mockFinder{}
+	if _, err := s.BinaryToUse(5, true); err == nil {
+		t.Error("Expected an error without any binary")
+	}
+}
+
+func TestBinaryToUseDownloadsFromArchive(t *testing.T) {
+	p := common.HostPlatform()
+	dl := &mockDownloader{}
+	dl.getKubectlBinary = func(version semver.Version, destination string) error {
+		writeArchive(t, destination, p.OS+"-"+p.Arch+"/helm"+p.Ext(), "helm "+version.String())
+		return nil
+	}
+
+	s := newTestSelector(t, newHelmDescriptor(t, "{{.OS}}-{{.Arch}}/helm{{.Ext}}"), nil, "1.25.2", dl)
+	bin, err := s.BinaryToUse(5, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := filepath.Join(s.localDir, s.tool.LocalName(semver.MustParse("3.11.3")))
+	if bin.Path != expected || !bin.Version.Equals(semver.MustParse("3.11.3")) {
+		t.Errorf("Got %+v instead of %s", bin, expected)
+	}
+	data, err := ioutil.ReadFile(expected)
+	if err != nil {
+		t.Fatalf("Expected the binary to be extracted: %v", err)
+	}
+	if string(data) != "helm 3.11.3" {
+		t.Errorf("Got unexpected content %q", string(data))
+	}
+	if _, err := os.Stat(expected + ".tar.gz"); !os.IsNotExist(err) {
+		t.Error("Expected the archive to be removed")
+	}
+}
+
+func TestBinaryToUseArchiveWithoutBinary(t *testing.T) {
+	dl := &mockDownloader{}
+	dl.getKubectlBinary = func(version semver.Version, destination string) error {
+		writeArchive(t, destination, "README.md", "no binary here")
+		return nil
+	}
+
+	s := newTestSelector(t, newHelmDescriptor(t, "helm"), nil, "1.25.2", dl)
+	if _, err := s.BinaryToUse(5, true); err == nil || !strings.Contains(err.Error(), "not found in archive") {
+		t.Errorf("Expected a missing binary error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.localDir, s.tool.LocalName(semver.MustParse("3.11.3")))); !os.IsNotExist(err) {
+		t.Error("Nothing should be installed")
+	}
+}
@@ -81,3 +81,89 @@ func Extract(input io.Reader, dst string) error {
 
 	return nil
 }
+
+// Create writes the content of the src directory to the given output as a
+// tar.gz archive. Directories are stored too, so that the archive can be
+// unpacked by Extract
+func Create(output io.Writer, src string) error {
+	gzw := gzip.NewWriter(output)
+	tarWriter := tar.NewWriter(gzw)
+
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == src {
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return fmt.Errorf("%s: cannot archive non regular files", path)
+		}
+
+		name, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", name, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(tarWriter, f); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// ExtractFile copies the regular file called name out of the given tar.gz
+// archive to output
+func ExtractFile(input io.Reader, name string, output io.Writer) error {
+	gzr, err := gzip.NewReader(input)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tarReader := tar.NewReader(gzr)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not found in archive", name)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Clean(header.Name) != filepath.Clean(name) {
+			continue
+		}
+
+		if _, err := io.Copy(output, tarReader); err != nil {
+			return fmt.Errorf("failed to decompress %s from archive: %w", header.Name, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package debug
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+)
+
+// UnixPrefix prefixes the path of the unix socket the server listens on
+const UnixPrefix = "unix:"
+
+// Server exposes pprof, the expvar counters and the timeline of the run
+// while a command runs with --debug
+type Server struct {
+	// Addr is a host:port or the path to a unix socket prefixed with unix:
+	Addr string
+	// Token, when set, has to be given as a bearer token or as the token
+	// query parameter
+	Token string
+}
+
+// Listen listens on the address of the server. Unix sockets are only
+// accessible to the user, a stale socket is replaced but other files are
+// left alone
+func (s Server) Listen() (net.Listener, error) {
+	if strings.HasPrefix(s.Addr, UnixPrefix) {
+		path := strings.TrimPrefix(s.Addr, UnixPrefix)
+		info, err := os.Lstat(path)
+		switch {
+		case err == nil && info.Mode()&os.ModeSocket == 0:
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		case err == nil:
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+		return listenUnix(path)
+	}
+	return net.Listen("tcp", s.Addr)
+}
+
+// Loopback tells whether the server is only reachable from this host
+func (s Server) Loopback() bool {
+	if strings.HasPrefix(s.Addr, UnixPrefix) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Handler returns the handler of the debug endpoints: the runtime profiles
+// under /debug/pprof/, the expvar counters under /debug/vars and the
+// timeline of the run, as JSON, under /trace
+func (s Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/trace", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(CurrentRun())
+	})
+
+	if s.Token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Start serves the debug endpoints in the background, it returns the
+// address listened on
+func (s Server) Start() (string, error) {
+	l, err := s.Listen()
+	if err != nil {
+		return "", fmt.Errorf("cannot start the debug server: %v", err)
+	}
+	go http.Serve(l, s.Handler())
+
+	if l.Addr().Network() == "unix" {
+		return UnixPrefix + l.Addr().String(), nil
+	}
+	return l.Addr().String(), nil
+}
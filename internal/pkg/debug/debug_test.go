@@ -0,0 +1,195 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandlerToken(t *testing.T) {
+	server := httptest.NewServer(Server{Token: "secret"}.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		status int
+	}{
+		{name: "no token", url: "/debug/vars", status: http.StatusUnauthorized},
+		{name: "wrong token", url: "/debug/vars", header: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "bearer token", url: "/debug/vars", header: "Bearer secret", status: http.StatusOK},
+		{name: "query token", url: "/trace?token=secret", status: http.StatusOK},
+		{name: "pprof", url: "/debug/pprof/?token=secret", status: http.StatusOK},
+		{name: "default mux not exposed", url: "/debug/requests?token=secret", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, server.URL+tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestCounters(t *testing.T) {
+	server := httptest.NewServer(Server{}.Handler())
+	defer server.Close()
+
+	EWSRequests.Add(2)
+	CountingWriter(DownloadBytes).Write([]byte("kubectl"))
+
+	resp, err := http.Get(server.URL + "/debug/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var vars map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	for name, min := range map[string]float64{"ewsRequests": 2, "downloadBytes": 7, "cacheHits": 0, "cacheMisses": 0} {
+		if v, ok := vars[name].(float64); !ok || v < min {
+			t.Errorf("%s: got %v, want at least %v", name, vars[name], min)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	client := &http.Client{Transport: Transport(StepEWS, nil, nil)}
+	resp, err := client.Get(backend.URL + "/a/?a=ckc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	StartStep(StepExec, "/usr/bin/kubectl")
+
+	server := httptest.NewServer(Server{}.Handler())
+	defer server.Close()
+	resp, err = http.Get(server.URL + "/trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var run Run
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+
+	n := len(run.Steps)
+	if n < 2 {
+		t.Fatalf("got steps %+v, want at least 2", run.Steps)
+	}
+	ews, exec := run.Steps[n-2], run.Steps[n-1]
+	if ews.Name != StepEWS || ews.Detail != "GET "+backend.URL+"/a/" || ews.Running {
+		t.Errorf("unexpected ews step %+v", ews)
+	}
+	if exec.Name != StepExec || !exec.Running || exec.Offset < ews.Offset {
+		t.Errorf("unexpected exec step %+v", exec)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.sock")
+	// other files are not replaced
+	if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := Server{Addr: UnixPrefix + path}
+	if _, err := s.Start(); err == nil {
+		t.Error("expected a regular file not to be replaced")
+	}
+	if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("expected the regular file to be kept, got %v", err)
+	}
+
+	// a stale socket is replaced
+	os.Remove(path)
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	if !s.Loopback() {
+		t.Error("expected a unix socket to be local")
+	}
+	addr, err := s.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != UnixPrefix+path {
+		t.Errorf("got address %s, want %s", addr, UnixPrefix+path)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm()&0077 != 0 {
+		t.Errorf("expected a socket only accessible to the user, got %v", info.Mode())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://eke/trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(data), `"steps"`) {
+		t.Errorf("unexpected trace %s", data)
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"localhost:6060": true,
+		"127.0.0.1:6060": true,
+		"[::1]:6060":     true,
+		":6060":          false,
+		"0.0.0.0:6060":   false,
+		"10.0.0.1:6060":  false,
+	} {
+		if got := (Server{Addr: addr}).Loopback(); got != want {
+			t.Errorf("%s: got %v, want %v", addr, got, want)
+		}
+	}
+}
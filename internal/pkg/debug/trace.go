@@ -0,0 +1,182 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package debug
+
+import (
+	"expvar"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Counters of the run, exposed under /debug/vars
+var (
+	// EWSRequests counts the requests sent to EWS
+	EWSRequests = expvar.NewInt("ewsRequests")
+	// DownloadBytes counts the bytes of the binaries downloaded
+	DownloadBytes = expvar.NewInt("downloadBytes")
+	// CacheHits and CacheMisses count the lookups of the server versions
+	// cache
+	CacheHits   = expvar.NewInt("cacheHits")
+	CacheMisses = expvar.NewInt("cacheMisses")
+)
+
+// Steps of the run recorded in the timeline
+const (
+	StepDiscovery = "discovery"
+	StepDownload  = "download"
+	StepEndpoint  = "endpoint"
+	StepEWS       = "ews"
+	StepExec      = "exec"
+	StepLookup    = "lookup"
+)
+
+// Step is a step of the timeline. Offset and Duration are in milliseconds,
+// Offset being counted from the start of the run. Running steps have no
+// duration yet, exec never ends as kubectl replaces eke
+type Step struct {
+	Name     string  `json:"name"`
+	Detail   string  `json:"detail,omitempty"`
+	Offset   float64 `json:"offset"`
+	Duration float64 `json:"duration,omitempty"`
+	Running  bool    `json:"running,omitempty"`
+}
+
+// Run is the timeline of the current eke process
+type Run struct {
+	PID   int       `json:"pid"`
+	Args  []string  `json:"args"`
+	Start time.Time `json:"start"`
+	Steps []Step    `json:"steps"`
+}
+
+// Tracer records the steps in a tracing system, on top of the timeline
+type Tracer interface {
+	StartStep(name, detail string) TracedStep
+	// Flush ends the steps still running and exports the recorded ones
+	Flush()
+}
+
+// TracedStep is a step recorded by a Tracer
+type TracedStep interface {
+	End()
+	// Inject writes the context of the step to the headers of a request,
+	// for the server to continue the trace
+	Inject(header http.Header)
+}
+
+var (
+	mu     sync.Mutex
+	start  = time.Now()
+	steps  []*Step
+	tracer Tracer
+)
+
+// SetTracer records the steps started from now on with t as well, nil
+// stops recording them
+func SetTracer(t Tracer) {
+	mu.Lock()
+	defer mu.Unlock()
+	tracer = t
+}
+
+// Flush exports the steps recorded by the tracer, if any. It is called
+// before eke exits or is replaced by kubectl, the steps running then are
+// ended
+func Flush() {
+	mu.Lock()
+	t := tracer
+	mu.Unlock()
+	if t != nil {
+		t.Flush()
+	}
+}
+
+// Span is a step being recorded
+type Span struct {
+	step   *Step
+	start  time.Time
+	traced TracedStep
+}
+
+// StartStep records the start of a step, End records its end
+func StartStep(name, detail string) *Span {
+	now := time.Now()
+	step := &Step{Name: name, Detail: detail, Offset: milliseconds(now.Sub(start)), Running: true}
+
+	mu.Lock()
+	steps = append(steps, step)
+	t := tracer
+	mu.Unlock()
+
+	span := &Span{step: step, start: now}
+	if t != nil {
+		span.traced = t.StartStep(name, detail)
+	}
+	return span
+}
+
+// End records the end of the step
+func (s *Span) End() {
+	d := time.Since(s.start)
+	if s.traced != nil {
+		s.traced.End()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.step.Duration = milliseconds(d)
+	s.step.Running = false
+}
+
+// Inject writes the trace context of the step to header, when it is traced
+func (s *Span) Inject(header http.Header) {
+	if s.traced != nil {
+		s.traced.Inject(header)
+	}
+}
+
+// CurrentRun returns the timeline of the run
+func CurrentRun() Run {
+	mu.Lock()
+	defer mu.Unlock()
+
+	run := Run{PID: os.Getpid(), Args: os.Args, Start: start, Steps: []Step{}}
+	for _, s := range steps {
+		run.Steps = append(run.Steps, *s)
+	}
+	return run
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// CountingWriter adds the number of bytes written to counter
+func CountingWriter(counter *expvar.Int) io.Writer {
+	return countingWriter{counter}
+}
+
+type countingWriter struct {
+	counter *expvar.Int
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	w.counter.Add(int64(len(p)))
+	return len(p), nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package debug
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Transport records the requests sent through base as steps of the
+// timeline, and counts them when counter is set. The steps are detailed by
+// the method and the URL without query. The requests carry the trace
+// context of their step when a Tracer is set
+func Transport(step string, counter *expvar.Int, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{step: step, counter: counter, base: base}
+}
+
+type transport struct {
+	step    string
+	counter *expvar.Int
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.counter != nil {
+		t.counter.Add(1)
+	}
+	u := *req.URL
+	u.RawQuery = ""
+	u.User = nil
+	span := StartStep(t.step, req.Method+" "+u.String())
+	defer span.End()
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	span.Inject(req.Header)
+	return t.base.RoundTrip(req)
+}
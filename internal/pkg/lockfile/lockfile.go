@@ -0,0 +1,108 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"eke/internal/pkg/random"
+)
+
+var (
+	// Timeout bounds the time spent waiting for a lock
+	Timeout = 10 * time.Minute
+	// PollInterval is the time between two attempts to take a lock
+	PollInterval = 500 * time.Millisecond
+	// StaleAge is the age after which a lock is considered abandoned, the
+	// owner of a lock refreshes it more often than that
+	StaleAge = 2 * time.Minute
+)
+
+// Lock is an exclusive lock shared by the eke processes, it is held as
+// long as the file exists. The lock is refreshed in the background so that
+// the lock of a process that died can be detected and taken over
+type Lock struct {
+	path string
+	done chan struct{}
+}
+
+// Acquire waits until the lock at path is free and takes it
+func Acquire(path string) (*Lock, error) {
+	deadline := time.Now().Add(Timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+
+			l := &Lock{path: path, done: make(chan struct{})}
+			go l.keepAlive()
+			return l, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > StaleAge {
+			// the owner died without releasing the lock
+			breakStaleLock(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the lock %s, remove it if no other eke process is running", path)
+		}
+		time.Sleep(PollInterval)
+	}
+}
+
+// breakStaleLock removes the abandoned lock at path. It is renamed to a
+// unique name first so that, of the processes finding it stale, a single
+// one removes it; the lock is put back when it turns out to have been
+// broken and taken by another process in the meantime
+func breakStaleLock(path string) {
+	stale := fmt.Sprintf("%s.%d-%s.stale", path, os.Getpid(), random.String(8))
+	if err := os.Rename(path, stale); err != nil {
+		return
+	}
+	if info, err := os.Stat(stale); err == nil && time.Since(info.ModTime()) <= StaleAge {
+		// the lock has been taken since it was found stale
+		os.Link(stale, path)
+	}
+	os.Remove(stale)
+}
+
+func (l *Lock) keepAlive() {
+	ticker := time.NewTicker(StaleAge / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Release frees the lock
+func (l *Lock) Release() error {
+	close(l.done)
+	return os.Remove(l.path)
+}
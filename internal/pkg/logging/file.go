@@ -0,0 +1,112 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Defaults of the rotation of the log files
+const (
+	DefaultMaxSize  = 10
+	DefaultMaxFiles = 3
+)
+
+// File appends the messages to a file which is rotated by size, it is
+// shared by the concurrent eke processes. Rotated files are named after
+// Path followed by a numeric suffix, the higher the older
+type File struct {
+	Path string
+	// MaxSize is the size, in bytes, above which the file is rotated. The
+	// file is never rotated when zero
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int
+}
+
+// NewFile returns a File writing to path. maxSize is expressed in MiB, the
+// defaults are used for the values lower than one
+func NewFile(path string, maxSize, maxFiles int) *File {
+	if maxSize < 1 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles < 1 {
+		maxFiles = DefaultMaxFiles
+	}
+	return &File{
+		Path:     path,
+		MaxSize:  int64(maxSize) * 1024 * 1024,
+		MaxFiles: maxFiles,
+	}
+}
+
+// Name returns the path of the nth rotated file, Path when n is zero
+func (f *File) Name(n int) string {
+	if n == 0 {
+		return f.Path
+	}
+	return fmt.Sprintf("%s.%d", f.Path, n)
+}
+
+// Write appends p to the file, rotating the file first when it is too big.
+// Each message being written at once, the messages of concurrent processes
+// are kept apart
+func (f *File) Write(p []byte) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return 0, err
+	}
+	if err := f.rotate(); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := file.Write(p)
+	if err != nil {
+		file.Close()
+		return n, err
+	}
+	return n, file.Close()
+}
+
+// rotate shifts the files by one when the current one exceeds MaxSize.
+// Processes racing to rotate at worst drop an older file early
+func (f *File) rotate() error {
+	if f.MaxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(f.Name(0))
+	if os.IsNotExist(err) || (err == nil && info.Size() < f.MaxSize) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(f.Name(f.MaxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := f.MaxFiles - 1; n >= 0; n-- {
+		if err := os.Rename(f.Name(n), f.Name(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Subsystems whose level can be set apart from the other ones
+const (
+	EWS     = "ews"
+	Kubectl = "kubectl"
+	Config  = "config"
+)
+
+// Log formats
+const (
+	Text = "text"
+	JSON = "json"
+)
+
+// ExecPluginAnnotation marks the commands run by kubectl as an exec
+// credential plugin, they only log warnings and errors unless asked
+// otherwise, kubectl showing their messages to its own user
+const ExecPluginAnnotation = "eke.io/exec-plugin"
+
+// defaultLevels are the levels of the subsystems when none is set, the
+// ews and kubectl messages tell what eke is doing on behalf of the user
+var defaultLevels = map[string]logrus.Level{
+	EWS:     logrus.InfoLevel,
+	Kubectl: logrus.InfoLevel,
+	Config:  logrus.WarnLevel,
+}
+
+// Options configure the loggers
+type Options struct {
+	// Format is text, the default, or json
+	Format string
+	// File receives the messages instead of stderr when set, errors are
+	// written to stderr as well
+	File string
+	// MaxSize, in MiB, and MaxFiles configure the rotation of File
+	MaxSize  int
+	MaxFiles int
+	// Level is the level of all the subsystems, the default ones are used
+	// when empty
+	Level string
+	// Levels are the levels of some subsystems, they take precedence over
+	// Level
+	Levels map[string]string
+	// Quiet lowers the default levels to warning
+	Quiet bool
+}
+
+var (
+	mu      sync.Mutex
+	loggers = map[string]*logrus.Logger{}
+	// current is the configuration applied to the loggers created later
+	current = configuration{out: os.Stderr, formatter: formatter(Text, false), levels: map[string]logrus.Level{}}
+)
+
+type configuration struct {
+	out       io.Writer
+	formatter logrus.Formatter
+	hooks     logrus.LevelHooks
+	level     *logrus.Level
+	levels    map[string]logrus.Level
+	quiet     bool
+}
+
+func (c configuration) levelOf(subsystem string) logrus.Level {
+	if level, ok := c.levels[subsystem]; ok {
+		return level
+	}
+	if c.level != nil {
+		return *c.level
+	}
+	if c.quiet {
+		return logrus.WarnLevel
+	}
+	if level, ok := defaultLevels[subsystem]; ok {
+		return level
+	}
+	return logrus.WarnLevel
+}
+
+func (c configuration) apply(l *logrus.Logger, subsystem string) {
+	l.SetOutput(c.out)
+	l.SetFormatter(c.formatter)
+	l.ReplaceHooks(c.hooks)
+	l.SetLevel(c.levelOf(subsystem))
+}
+
+// For returns the logger of a subsystem, its messages carry the name of
+// the subsystem
+func For(subsystem string) *logrus.Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	l, ok := loggers[subsystem]
+	if !ok {
+		l = logrus.New()
+		current.apply(l, subsystem)
+		loggers[subsystem] = l
+	}
+	return l.WithField("subsystem", subsystem)
+}
+
+// Setup configures the loggers of the subsystems and the standard logrus
+// logger, which is used by the messages of no subsystem
+func Setup(opts Options) error {
+	c := configuration{out: os.Stderr, levels: map[string]logrus.Level{}, quiet: opts.Quiet, hooks: logrus.LevelHooks{}}
+
+	switch opts.Format {
+	case "", Text, JSON:
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", opts.Format, Text, JSON)
+	}
+	if opts.Level != "" {
+		level, err := logrus.ParseLevel(opts.Level)
+		if err != nil {
+			return err
+		}
+		c.level = &level
+	}
+	for subsystem, name := range opts.Levels {
+		if _, ok := defaultLevels[subsystem]; !ok {
+			return fmt.Errorf("unknown log subsystem %q, expected one of %s", subsystem, strings.Join(Subsystems(), ", "))
+		}
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", subsystem, err)
+		}
+		c.levels[subsystem] = level
+	}
+
+	c.formatter = formatter(opts.Format, opts.File != "")
+	if opts.File != "" {
+		c.out = NewFile(opts.File, opts.MaxSize, opts.MaxFiles)
+		c.hooks.Add(&stderrHook{formatter: formatter(Text, false)})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+	for subsystem, l := range loggers {
+		c.apply(l, subsystem)
+	}
+	c.apply(logrus.StandardLogger(), "")
+	return nil
+}
+
+// Subsystems returns the names of the subsystems, sorted
+func Subsystems() []string {
+	names := make([]string, 0, len(defaultLevels))
+	for name := range defaultLevels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseLevels parses the value of --log-level, a level optionally followed
+// by subsystem=level pairs, all separated by commas, e.g. "info,ews=debug"
+func ParseLevels(spec string) (string, map[string]string, error) {
+	level, levels := "", map[string]string{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.Index(item, "=")
+		if i < 0 {
+			if level != "" {
+				return "", nil, fmt.Errorf("invalid log level %q: several default levels", spec)
+			}
+			level = item
+			continue
+		}
+		levels[item[:i]] = item[i+1:]
+	}
+	return level, levels, nil
+}
+
+// formatter returns the formatter of the format. Timestamps are only
+// written to the log files, they clutter the terminal
+func formatter(format string, file bool) logrus.Formatter {
+	if format == JSON {
+		return &logrus.JSONFormatter{}
+	}
+	if file {
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true, TimestampFormat: "2006-01-02 15:04:05"}
+	}
+	return &logrus.TextFormatter{DisableTimestamp: true}
+}
+
+// stderrHook copies the errors to stderr when the messages go to a file
+type stderrHook struct {
+	formatter logrus.Formatter
+}
+
+func (h *stderrHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (h *stderrHook) Fire(entry *logrus.Entry) error {
+	data, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stderr.Write(data)
+	return err
+}
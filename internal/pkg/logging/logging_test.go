@@ -0,0 +1,159 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package logging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		spec   string
+		level  string
+		levels map[string]string
+		errMsg string
+	}{
+		{spec: "", levels: map[string]string{}},
+		{spec: "debug", level: "debug", levels: map[string]string{}},
+		{spec: "info, ews=debug,kubectl=warn", level: "info", levels: map[string]string{"ews": "debug", "kubectl": "warn"}},
+		{spec: "config=error", levels: map[string]string{"config": "error"}},
+		{spec: "info,debug", errMsg: "several default levels"},
+	}
+	for _, tt := range tests {
+		level, levels, err := ParseLevels(tt.spec)
+		if tt.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("%q: got error %v, want %q", tt.spec, err, tt.errMsg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.spec, err)
+			continue
+		}
+		if level != tt.level || !reflect.DeepEqual(levels, tt.levels) {
+			t.Errorf("%q: got %q %v, want %q %v", tt.spec, level, levels, tt.level, tt.levels)
+		}
+	}
+}
+
+func TestSetupLevels(t *testing.T) {
+	defer Setup(Options{})
+
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]logrus.Level
+	}{
+		{
+			name: "defaults",
+			want: map[string]logrus.Level{EWS: logrus.InfoLevel, Kubectl: logrus.InfoLevel, Config: logrus.WarnLevel},
+		},
+		{
+			name: "quiet",
+			opts: Options{Quiet: true},
+			want: map[string]logrus.Level{EWS: logrus.WarnLevel, Kubectl: logrus.WarnLevel, Config: logrus.WarnLevel},
+		},
+		{
+			name: "subsystem levels take precedence",
+			opts: Options{Quiet: true, Level: "debug", Levels: map[string]string{EWS: "error"}},
+			want: map[string]logrus.Level{EWS: logrus.ErrorLevel, Kubectl: logrus.DebugLevel, Config: logrus.DebugLevel},
+		},
+	}
+	for _, tt := range tests {
+		if err := Setup(tt.opts); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for subsystem, want := range tt.want {
+			if got := For(subsystem).Logger.GetLevel(); got != want {
+				t.Errorf("%s: %s got level %s, want %s", tt.name, subsystem, got, want)
+			}
+		}
+	}
+}
+
+func TestSetupInvalid(t *testing.T) {
+	defer Setup(Options{})
+
+	for _, opts := range []Options{
+		{Format: "xml"},
+		{Level: "loud"},
+		{Levels: map[string]string{"etcd": "info"}},
+		{Levels: map[string]string{EWS: "loud"}},
+	} {
+		if err := Setup(opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	defer Setup(Options{})
+
+	path := filepath.Join(t.TempDir(), "logs", "eke.log")
+	if err := Setup(Options{Format: JSON, File: path}); err != nil {
+		t.Fatal(err)
+	}
+	For(Kubectl).Info("downloading kubectl")
+	For(Kubectl).Debug("not logged")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %s", len(lines), data)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "downloading kubectl" || entry["subsystem"] != Kubectl || entry["level"] != "info" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "eke.log"), 0, 2)
+	f.MaxSize = 10
+
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n, want := range []string{"fourth line\n", "third line\n", "second line\n"} {
+		data, err := ioutil.ReadFile(f.Name(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("file %d: got %q, want %q", n, data, want)
+		}
+	}
+	if _, err := ioutil.ReadFile(f.Name(3)); err == nil {
+		t.Error("expected the oldest file to be removed")
+	}
+}
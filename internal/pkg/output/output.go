@@ -0,0 +1,162 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats selected with the global -o flag. JSONPath and GoTemplate
+// are followed by = and the template, like with kubectl
+const (
+	Table      = "table"
+	JSON       = "json"
+	YAML       = "yaml"
+	JSONPath   = "jsonpath"
+	GoTemplate = "go-template"
+)
+
+// Formats lists the output formats, for the help and the shell completion
+var Formats = []string{Table, JSON, YAML, JSONPath + "=", GoTemplate + "="}
+
+// Texter is implemented by the results printing themselves in the table
+// format, when they are not a plain table
+type Texter interface {
+	WriteText(w io.Writer) error
+}
+
+// Tabler is implemented by the results printed as a table in the table
+// format
+type Tabler interface {
+	Table() (header table.Row, rows []table.Row)
+}
+
+// Printer prints the results of a command. The JSON field names of the
+// results are the ones the other formats, templates included, refer to
+type Printer struct {
+	Format   string
+	template string
+}
+
+// New returns the printer of the format, as given to the -o flag
+func New(format string) (*Printer, error) {
+	name, tmpl := format, ""
+	if i := strings.Index(format, "="); i >= 0 {
+		name, tmpl = format[:i], format[i+1:]
+	}
+
+	switch name {
+	case Table, JSON, YAML:
+		if tmpl != "" {
+			return nil, fmt.Errorf("output format %s takes no template", name)
+		}
+	case JSONPath, GoTemplate:
+		if tmpl == "" {
+			return nil, fmt.Errorf("output format %s needs a template, e.g. -o %s=<template>", name, name)
+		}
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+	return &Printer{Format: name, template: tmpl}, nil
+}
+
+// Print writes result to w in the format of the printer. In the table
+// format results implementing neither Texter nor Tabler are printed as YAML
+func (p *Printer) Print(w io.Writer, result interface{}) error {
+	switch p.Format {
+	case Table:
+		switch r := result.(type) {
+		case Texter:
+			return r.WriteText(w)
+		case Tabler:
+			header, rows := r.Table()
+			t := table.NewWriter()
+			t.SetOutputMirror(w)
+			t.AppendHeader(header)
+			t.AppendRows(rows)
+			t.Render()
+			return nil
+		}
+		return printYAML(w, result)
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case YAML:
+		return printYAML(w, result)
+	case JSONPath:
+		data, err := generic(result)
+		if err != nil {
+			return err
+		}
+		// missing keys print nothing, like with kubectl
+		jp := jsonpath.New("output").AllowMissingKeys(true)
+		if err := jp.Parse(p.template); err != nil {
+			return fmt.Errorf("invalid jsonpath template: %v", err)
+		}
+		return jp.Execute(w, data)
+	case GoTemplate:
+		data, err := generic(result)
+		if err != nil {
+			return err
+		}
+		t, err := template.New("output").Parse(p.template)
+		if err != nil {
+			return fmt.Errorf("invalid go-template: %v", err)
+		}
+		return t.Execute(w, data)
+	}
+	return fmt.Errorf("unknown output format %q", p.Format)
+}
+
+func printYAML(w io.Writer, result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.JSONToYAML(data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// generic returns result as decoded from its JSON representation, so that
+// the templates refer to the JSON field names
+func generic(result interface{}) (interface{}, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
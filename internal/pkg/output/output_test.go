@@ -0,0 +1,113 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type item struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type items []item
+
+func (l items) Table() (table.Row, []table.Row) {
+	rows := []table.Row{}
+	for _, i := range l {
+		rows = append(rows, table.Row{i.Name, i.Size})
+	}
+	return table.Row{"Name", "Size"}, rows
+}
+
+type text struct {
+	Value string `json:"value"`
+}
+
+func (t text) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintln(w, "value is", t.Value)
+	return err
+}
+
+func TestPrint(t *testing.T) {
+	list := items{{Name: "a", Size: 1}, {Name: "b", Size: 2000000000}}
+
+	tests := []struct {
+		format string
+		result interface{}
+		want   string
+	}{
+		{"table", list, "| NAME |       SIZE |\n+------+------------+\n| a    |          1 |"},
+		{"table", text{Value: "x"}, "value is x\n"},
+		{"table", item{Name: "a"}, "name: a\nsize: 0\n"},
+		{"json", list, "[\n  {\n    \"name\": \"a\",\n    \"size\": 1\n  },\n  {\n    \"name\": \"b\",\n    \"size\": 2000000000\n  }\n]\n"},
+		{"json", text{Value: "<x>"}, "{\n  \"value\": \"<x>\"\n}\n"},
+		{"yaml", list, "- name: a\n  size: 1\n- name: b\n  size: 2000000000\n"},
+		{"jsonpath={[*].name}", list, "a b"},
+		{"jsonpath={[1].size}", list, "2000000000"},
+		{"go-template={{range .}}{{.name}}={{.size}};{{end}}", list, "a=1;b=2000000000;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := New(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := p.Print(&buf, tt.result); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := map[string]string{
+		"wide":          "unknown output format",
+		"json=x":        "takes no template",
+		"jsonpath":      "needs a template",
+		"go-template=":  "needs a template",
+		"custom-column": "unknown output format",
+	}
+	for format, errMsg := range tests {
+		if _, err := New(format); err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("%s: got error %v, want %q", format, err, errMsg)
+		}
+	}
+}
+
+func TestPrintInvalidTemplate(t *testing.T) {
+	for _, format := range []string{"jsonpath={.[", "go-template={{.name"} {
+		p, err := New(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Print(&bytes.Buffer{}, items{}); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/osexec"
+)
+
+// Prefix starts the names of the plugin executables, eke-foo runs as
+// eke foo
+const Prefix = "eke-"
+
+// Environment variables describing the context of eke to the plugins
+const (
+	// ProfileEnv is the name of the active profile, empty when none is
+	ProfileEnv = "EKE_PLUGIN_PROFILE"
+	// IdentityCacheEnv is the directory holding the EWS credentials of the
+	// profile
+	IdentityCacheEnv = "EKE_PLUGIN_IDENTITY_CACHE"
+	// KubeconfigEnv is the kubeconfig kubectl uses, a list of files like
+	// KUBECONFIG
+	KubeconfigEnv = "EKE_PLUGIN_KUBECONFIG"
+	// KubectlEnv is the installed kubectl binary matching the last known
+	// version of the cluster of the current context, empty when none is
+	KubectlEnv = "EKE_PLUGIN_KUBECTL"
+	// EkeEnv is the eke binary running the plugin
+	EkeEnv = "EKE_PLUGIN_EKE"
+)
+
+// Plugin is an eke-<name> executable found in the plugin directories
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Warnings tell why the plugin does not run as eke <name>
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Dirs returns the directories the plugins are looked for in, in order:
+// ~/.eke/plugins, then the directories of PATH
+func Dirs() []string {
+	dirs := []string{common.PluginDir()}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Discover returns the plugins found in dirs, in search order. A plugin
+// is shadowed by a command of eke, as told by builtin, or by the plugin
+// of the same name found before it. The plugins whose file cannot be run
+// are reported as well
+func Discover(dirs []string, builtin func(name string) bool) []Plugin {
+	var plugins []Plugin
+	found := map[string]string{}
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		// the same directory may appear several times in PATH
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name, ok := nameOf(f.Name())
+			if !ok || f.IsDir() {
+				continue
+			}
+			p := Plugin{Name: name, Path: filepath.Join(dir, f.Name())}
+			if !executable(p.Path) {
+				p.Warnings = append(p.Warnings, "not executable")
+			} else if first, ok := found[name]; ok {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("shadowed by %s", first))
+			} else {
+				found[name] = p.Path
+			}
+			if builtin(name) {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("shadowed by the built-in command eke %s", name))
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// Find returns the path of the plugin called name, the first executable
+// eke-<name> of dirs
+func Find(dirs []string, name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, Prefix+name+osexec.Ext)
+		if executable(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// nameOf returns the name of the plugin run by the file called filename
+func nameOf(filename string) (string, bool) {
+	if !strings.HasPrefix(filename, Prefix) {
+		return "", false
+	}
+	if osexec.Ext != "" && !strings.HasSuffix(filename, osexec.Ext) {
+		return "", false
+	}
+	name := osexec.TrimExt(strings.TrimPrefix(filename, Prefix))
+	return name, name != ""
+}
+
+// executable tells whether path is a file which can be run, the extension
+// of the binaries tells on Windows
+func executable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if osexec.Ext != "" {
+		return strings.HasSuffix(path, osexec.Ext)
+	}
+	return info.Mode()&0111 != 0
+}
+
+// Context describes eke to the plugins
+type Context struct {
+	Profile       string
+	IdentityCache string
+	Kubeconfig    string
+	Kubectl       string
+	Eke           string
+}
+
+// Env returns environ, which has the form of os.Environ, along with the
+// variables describing the context. They replace the ones of environ
+func (c Context) Env(environ []string) []string {
+	vars := map[string]string{
+		ProfileEnv:       c.Profile,
+		IdentityCacheEnv: c.IdentityCache,
+		KubeconfigEnv:    c.Kubeconfig,
+		KubectlEnv:       c.Kubectl,
+		EkeEnv:           c.Eke,
+	}
+	env := make([]string, 0, len(environ)+len(vars))
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if _, ok := vars[name]; !ok {
+			env = append(env, kv)
+		}
+	}
+	for _, name := range []string{ProfileEnv, IdentityCacheEnv, KubeconfigEnv, KubectlEnv, EkeEnv} {
+		env = append(env, name+"="+vars[name])
+	}
+	return env
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the plugins are .exe files on windows")
+	}
+	home := filepath.Join(t.TempDir(), "plugins")
+	bin := filepath.Join(t.TempDir(), "bin")
+	writeFile(t, filepath.Join(home, "eke-login"), 0755)
+	writeFile(t, filepath.Join(home, "eke-kubectl"), 0755)
+	writeFile(t, filepath.Join(home, "eke-broken"), 0644)
+	writeFile(t, filepath.Join(home, "README"), 0644)
+	writeFile(t, filepath.Join(bin, "eke-login"), 0755)
+	writeFile(t, filepath.Join(bin, "eke-"), 0755)
+	if err := os.MkdirAll(filepath.Join(bin, "eke-dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	builtin := func(name string) bool { return name == "kubectl" }
+	// bin appears twice, like a directory repeated in PATH
+	got := Discover([]string{home, bin, filepath.Join(t.TempDir(), "missing"), bin}, builtin)
+	want := []Plugin{
+		{Name: "broken", Path: filepath.Join(home, "eke-broken"), Warnings: []string{"not executable"}},
+		{Name: "kubectl", Path: filepath.Join(home, "eke-kubectl"), Warnings: []string{"shadowed by the built-in command eke kubectl"}},
+		{Name: "login", Path: filepath.Join(home, "eke-login")},
+		{Name: "login", Path: filepath.Join(bin, "eke-login"), Warnings: []string{"shadowed by " + filepath.Join(home, "eke-login")}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got plugins %+v, want %+v", got, want)
+	}
+
+	for name, want := range map[string]string{
+		"login":     filepath.Join(home, "eke-login"),
+		"broken":    "",
+		"missing":   "",
+		"../eke-up": "",
+	} {
+		path, _ := Find([]string{home, bin}, name)
+		if path != want {
+			t.Errorf("%s: got path %q, want %q", name, path, want)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	ctx := Context{
+		Profile:       "prod",
+		IdentityCache: "/home/u/.eke/profiles/prod",
+		Kubeconfig:    "/home/u/.kube/prod",
+		Eke:           "/usr/local/bin/eke",
+	}
+	got := ctx.Env([]string{"PATH=/usr/bin", KubectlEnv + "=/stale/kubectl"})
+	want := []string{
+		"PATH=/usr/bin",
+		ProfileEnv + "=prod",
+		IdentityCacheEnv + "=/home/u/.eke/profiles/prod",
+		KubeconfigEnv + "=/home/u/.kube/prod",
+		KubectlEnv + "=",
+		EkeEnv + "=/usr/local/bin/eke",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got environment %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package selfupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"eke/internal/kubectlcmd/manifest"
+
+	"github.com/blang/semver/v4"
+)
+
+// BinaryName is the name of the eke binaries in the checksum manifests,
+// e.g. v1.3.0/linux/amd64/eke
+const BinaryName = "eke"
+
+// RollbackSuffix is appended to the path of the executable to name the
+// copy of the replaced binary
+const RollbackSuffix = ".old"
+
+// Index lists the released versions of eke
+//
+//	{"releases": [{
+//	  "version": "v1.3.0",
+//	  "manifestURL": "v1.3.0/SHA256SUMS",
+//	  "binaries": {"linux/amd64": "v1.3.0/eke-linux-amd64"}
+//	}]}
+//
+// The URLs are relative to the one of the index
+type Index struct {
+	Releases []Release `json:"releases"`
+}
+
+// Release is a released version of eke. Binaries map os/arch platforms to
+// the URL of their build. ManifestURL points to a checksum manifest listing
+// the builds, signed with one of the trusted keys. SignatureURL defaults to
+// the manifest URL followed by ".sig"
+type Release struct {
+	Version      string            `json:"version"`
+	Date         string            `json:"date,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+	ManifestURL  string            `json:"manifestURL"`
+	SignatureURL string            `json:"signatureURL,omitempty"`
+	Binaries     map[string]string `json:"binaries"`
+}
+
+// Client reads the release index and downloads the builds of eke
+type Client struct {
+	// IndexURL is the URL of the release index
+	IndexURL string
+	// Verifier checks the signature of the checksum manifests
+	Verifier *manifest.Verifier
+	// HTTPClient defaults to a client with a one minute timeout
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client reading the release index at indexURL and
+// trusting the builds signed with one of publicKeys
+func NewClient(indexURL string, publicKeys []string) (*Client, error) {
+	verifier, err := manifest.NewVerifier(publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{IndexURL: indexURL, Verifier: verifier}, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: time.Minute}
+}
+
+// Index returns the release index, the URLs of its releases are resolved
+func (c *Client) Index() (*Index, error) {
+	if c.IndexURL == "" {
+		return nil, errors.New("no release index configured, set releases.indexURL in eke.cmd.yaml")
+	}
+	data, err := c.get(c.IndexURL)
+	if err != nil {
+		return nil, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid release index %s: %v", c.IndexURL, err)
+	}
+
+	base, err := url.Parse(c.IndexURL)
+	if err != nil {
+		return nil, err
+	}
+	for i := range index.Releases {
+		r := &index.Releases[i]
+		if r.SignatureURL == "" && r.ManifestURL != "" {
+			r.SignatureURL = r.ManifestURL + ".sig"
+		}
+		for _, u := range []*string{&r.ManifestURL, &r.SignatureURL} {
+			if *u, err = resolve(base, *u); err != nil {
+				return nil, err
+			}
+		}
+		for platform, u := range r.Binaries {
+			if r.Binaries[platform], err = resolve(base, u); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &index, nil
+}
+
+func resolve(base *url.URL, ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL in the release index: %v", err)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// Latest returns the most recent release, pre-releases are only
+// considered when prerelease is set
+func (i *Index) Latest(prerelease bool) (Release, semver.Version, error) {
+	var latest Release
+	var latestVersion *semver.Version
+	for _, r := range i.Releases {
+		v, err := semver.ParseTolerant(r.Version)
+		if err != nil || (len(v.Pre) > 0 && !prerelease) {
+			continue
+		}
+		if latestVersion == nil || v.GT(*latestVersion) {
+			latest, latestVersion = r, &v
+		}
+	}
+	if latestVersion == nil {
+		return Release{}, semver.Version{}, errors.New("no release found in the release index")
+	}
+	return latest, *latestVersion, nil
+}
+
+// Find returns the release with the given version
+func (i *Index) Find(version semver.Version) (Release, error) {
+	for _, r := range i.Releases {
+		if v, err := semver.ParseTolerant(r.Version); err == nil && v.Equals(version) {
+			return r, nil
+		}
+	}
+	return Release{}, fmt.Errorf("version %s not found in the release index", version)
+}
+
+// Download writes the build of the release for the goos/goarch platform to
+// destination, once its checksum has been checked against the signed
+// manifest of the release
+func (c *Client) Download(r Release, goos, goarch, destination string) error {
+	version, err := semver.ParseTolerant(r.Version)
+	if err != nil {
+		return fmt.Errorf("invalid release version %q: %v", r.Version, err)
+	}
+	binaryURL, ok := r.Binaries[goos+"/"+goarch]
+	if !ok {
+		return fmt.Errorf("eke %s has no build for %s/%s", r.Version, goos, goarch)
+	}
+
+	checksum, err := c.signedChecksum(r, version, goos, goarch)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient().Get(binaryURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot download %s: %s", binaryURL, resp.Status)
+	}
+
+	f, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hasher), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("cannot download %s: %v", binaryURL, err)
+	}
+	if got := hex.EncodeToString(hasher.Sum(nil)); got != checksum {
+		return fmt.Errorf("checksum mismatch for %s: got %s, the signed manifest has %s", binaryURL, got, checksum)
+	}
+	return nil
+}
+
+// signedChecksum returns the checksum of the build of the release, read
+// from its manifest once the signature of the manifest has been checked
+func (c *Client) signedChecksum(r Release, version semver.Version, goos, goarch string) (string, error) {
+	if !c.Verifier.HasKeys() {
+		return "", errors.New("no public key configured to verify the releases, set releases.publicKeys in eke.cmd.yaml")
+	}
+	if r.ManifestURL == "" {
+		return "", fmt.Errorf("eke %s has no checksum manifest", r.Version)
+	}
+
+	data, err := c.get(r.ManifestURL)
+	if err != nil {
+		return "", err
+	}
+	signature, err := c.get(r.SignatureURL)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Verifier.Verify(data, signature); err != nil {
+		return "", fmt.Errorf("invalid signature of %s: %v", r.ManifestURL, err)
+	}
+
+	m, err := manifest.Parse(data)
+	if err != nil {
+		return "", err
+	}
+	checksum, ok := m.Lookup(BinaryName, version, goos, goarch)
+	if !ok {
+		return "", fmt.Errorf("%s has no checksum for %s", r.ManifestURL, manifest.Entry(BinaryName, version, goos, goarch))
+	}
+	return checksum, nil
+}
+
+func (c *Client) get(u string) ([]byte, error) {
+	resp, err := c.httpClient().Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get %s: %s", u, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// the file operations of Replace, replaced by the tests
+var (
+	link   = os.Link
+	rename = os.Rename
+)
+
+// Replace replaces the executable at path with the binary at update,
+// which has to be on the same file system. The replaced binary is kept at
+// path followed by RollbackSuffix. The executable is replaced by a single
+// rename, there is always a binary at path
+func Replace(path, update string) error {
+	rollback := path + RollbackSuffix
+	if err := os.Remove(rollback); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := link(path, rollback); err != nil {
+		if err := copyFile(path, rollback); err != nil {
+			return fmt.Errorf("cannot keep a rollback copy of %s: %v", path, err)
+		}
+	}
+	if err := rename(update, path); err != nil {
+		return fmt.Errorf("cannot replace %s: %v", path, err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst, with the permissions of src
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// Rollback puts back the binary replaced by the last update of the
+// executable at path
+func Rollback(path string) error {
+	rollback := path + RollbackSuffix
+	if _, err := os.Stat(rollback); err != nil {
+		return fmt.Errorf("no rollback copy of %s: %v", path, err)
+	}
+	return rename(rollback, path)
+}
+
+// Executable returns the path of the running executable, symbolic links
+// resolved
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
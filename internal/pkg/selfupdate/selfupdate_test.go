@@ -0,0 +1,233 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package selfupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eke/internal/kubectlcmd/manifest"
+
+	"github.com/blang/semver/v4"
+)
+
+var fakeEke = []byte("#!/bin/sh\necho eke v1.3.0\n")
+
+func newSigningKey(t *testing.T) (string, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), priv
+}
+
+// newReleaseServer serves an index of the releases v1.2.0, v1.3.0 and
+// v1.4.0-rc.1, v1.3.0 having a linux/amd64 build served as binary and
+// listed in a manifest signed with priv
+func newReleaseServer(t *testing.T, priv ed25519.PrivateKey, binary []byte) *httptest.Server {
+	sum := sha256.Sum256(fakeEke)
+	m := manifest.Manifest{
+		manifest.Entry(BinaryName, semver.MustParse("1.3.0"), "linux", "amd64"): hex.EncodeToString(sum[:]),
+	}
+	data := m.Bytes()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/index.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"releases": [
+  {"version": "v1.2.0", "manifestURL": "v1.2.0/SHA256SUMS", "binaries": {}},
+  {"version": "v1.3.0", "notes": "faster kubeconfig init", "manifestURL": "v1.3.0/SHA256SUMS",
+   "binaries": {"linux/amd64": "/builds/eke-linux-amd64"}},
+  {"version": "v1.4.0-rc.1", "manifestURL": "v1.4.0-rc.1/SHA256SUMS", "binaries": {}}
+]}`)
+	})
+	mux.HandleFunc("/releases/v1.3.0/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+	mux.HandleFunc("/releases/v1.3.0/SHA256SUMS.sig", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)))
+	})
+	mux.HandleFunc("/builds/eke-linux-amd64", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	})
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestIndex(t *testing.T) {
+	_, priv := newSigningKey(t)
+	s := newReleaseServer(t, priv, fakeEke)
+
+	index, err := (&Client{IndexURL: s.URL + "/releases/index.json"}).Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, version, err := index.Latest(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "v1.3.0" || !version.Equals(semver.MustParse("1.3.0")) {
+		t.Errorf("got latest release %s, want v1.3.0", release.Version)
+	}
+	if release.ManifestURL != s.URL+"/releases/v1.3.0/SHA256SUMS" ||
+		release.SignatureURL != s.URL+"/releases/v1.3.0/SHA256SUMS.sig" ||
+		release.Binaries["linux/amd64"] != s.URL+"/builds/eke-linux-amd64" {
+		t.Errorf("the URLs of the release are not resolved: %+v", release)
+	}
+
+	if release, _, _ := index.Latest(true); release.Version != "v1.4.0-rc.1" {
+		t.Errorf("got latest pre-release %s, want v1.4.0-rc.1", release.Version)
+	}
+	if release, err := index.Find(semver.MustParse("1.2.0")); err != nil || release.Version != "v1.2.0" {
+		t.Errorf("got release %+v, %v, want v1.2.0", release, err)
+	}
+	if _, err := index.Find(semver.MustParse("0.9.0")); err == nil {
+		t.Error("expected an error for a version missing from the index")
+	}
+
+	if _, err := (&Client{}).Index(); err == nil {
+		t.Error("expected an error without index URL")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	pub, priv := newSigningKey(t)
+	otherPub, _ := newSigningKey(t)
+
+	tests := []struct {
+		name    string
+		binary  []byte
+		keys    []string
+		goarch  string
+		success bool
+	}{
+		{"trusted signature", fakeEke, []string{otherPub, pub}, "amd64", true},
+		{"untrusted signature", fakeEke, []string{otherPub}, "amd64", false},
+		{"no trusted key", fakeEke, nil, "amd64", false},
+		{"tampered build", []byte("#!/bin/sh\nrm -rf ~\n"), []string{pub}, "amd64", false},
+		{"missing build", fakeEke, []string{pub}, "arm64", false},
+	}
+	for _, tt := range tests {
+		s := newReleaseServer(t, priv, tt.binary)
+		client, err := NewClient(s.URL+"/releases/index.json", tt.keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := client.Index()
+		if err != nil {
+			t.Fatal(err)
+		}
+		release, _, err := index.Latest(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		destination := filepath.Join(t.TempDir(), "eke")
+		err = client.Download(release, "linux", tt.goarch, destination)
+		if tt.success != (err == nil) {
+			t.Errorf("%s: got error %v, want success %v", tt.name, err, tt.success)
+			continue
+		}
+		if tt.success {
+			data, err := ioutil.ReadFile(destination)
+			if err != nil || string(data) != string(fakeEke) {
+				t.Errorf("%s: unexpected build %q, %v", tt.name, data, err)
+			}
+		}
+	}
+}
+
+func TestReplaceAndRollback(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "eke")
+	update := filepath.Join(dir, ".eke.update")
+	for path, content := range map[string]string{exe: "v1.2.0", update: "v1.3.0", exe + RollbackSuffix: "v1.1.0"} {
+		if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertContent := func(path, want string) {
+		t.Helper()
+		data, err := ioutil.ReadFile(path)
+		if err != nil || string(data) != want {
+			t.Errorf("%s: got %q, %v, want %q", path, data, err, want)
+		}
+	}
+
+	// the executable is never missing, whatever step fails
+	assertExists := func() {
+		t.Helper()
+		if _, err := os.Stat(exe); err != nil {
+			t.Errorf("the executable is missing: %v", err)
+		}
+	}
+	link = func(oldname, newname string) error {
+		assertExists()
+		return os.Link(oldname, newname)
+	}
+	rename = func(oldname, newname string) error {
+		assertExists()
+		return os.Rename(oldname, newname)
+	}
+	t.Cleanup(func() { link, rename = os.Link, os.Rename })
+
+	if err := Replace(exe, update); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(exe, "v1.3.0")
+	assertContent(exe+RollbackSuffix, "v1.2.0")
+	assertExists()
+
+	if err := Rollback(exe); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(exe, "v1.2.0")
+	if err := Rollback(exe); err == nil {
+		t.Error("expected an error without rollback copy")
+	}
+
+	// a failed update leaves the executable in place
+	if err := Replace(exe, filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing update")
+	}
+	assertContent(exe, "v1.2.0")
+
+	// the rollback copy is copied when it cannot be hard linked
+	link = func(string, string) error { return errors.New("cross-device link") }
+	if err := ioutil.WriteFile(update, []byte("v1.3.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replace(exe, update); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(exe, "v1.3.0")
+	assertContent(exe+RollbackSuffix, "v1.2.0")
+	if info, err := os.Stat(exe + RollbackSuffix); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("the rollback copy is not executable: %v, %v", info, err)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"eke/internal/pkg/debug"
+	"eke/pkg/build"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of the traces
+const (
+	// OTLP sends the traces to a collector over OTLP/HTTP
+	OTLP = "otlp"
+	// File appends the traces, as JSON, to a file
+	File = "file"
+)
+
+// Attributes of the spans
+const (
+	// DetailKey holds the detail of the step, e.g. the URL of a request
+	DetailKey = attribute.Key("eke.detail")
+	// RunningKey marks the steps still running when the traces were
+	// exported, e.g. kubectl replacing eke
+	RunningKey = attribute.Key("eke.running")
+)
+
+// flushTimeout bounds the time spent exporting the traces, eke does not
+// wait for an unreachable collector
+const flushTimeout = 5 * time.Second
+
+// Options configure the export of the traces
+type Options struct {
+	// Exporter is otlp or file, tracing is disabled when empty
+	Exporter string
+	// Endpoint is the host:port, or the URL, of the OTLP/HTTP collector
+	Endpoint string
+	// Insecure sends the traces over plain HTTP
+	Insecure bool
+	// Headers are added to the requests sent to the collector
+	Headers map[string]string
+	// File receives the traces of the file exporter
+	File string
+}
+
+// Enabled tells whether the traces are exported
+func (o Options) Enabled() bool {
+	return o.Exporter != ""
+}
+
+// Tracer records the steps of the debug timeline as the spans of a trace
+// covering the whole command. The trace context of the steps is
+// propagated with the W3C trace context headers
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	root     trace.Span
+	ctx      context.Context
+	closer   io.Closer
+
+	mu      sync.Mutex
+	running map[*step]bool
+	flush   sync.Once
+}
+
+// New returns a tracer exporting the spans with exporter, the root span is
+// named after the command
+func New(exporter sdktrace.SpanExporter, command string) *Tracer {
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String("eke"),
+		semconv.ServiceVersionKey.String(build.Version),
+	)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	tracer := provider.Tracer("eke")
+	ctx, root := tracer.Start(context.Background(), command)
+	return &Tracer{
+		provider: provider,
+		tracer:   tracer,
+		root:     root,
+		ctx:      ctx,
+		running:  map[*step]bool{},
+	}
+}
+
+// Setup records the steps of the command with the exporter of opts, until
+// debug.Flush is called. The traces are exported as well when a logger
+// exits eke
+func Setup(opts Options, command string) error {
+	exporter, closer, err := newExporter(opts)
+	if err != nil {
+		return fmt.Errorf("cannot export the traces: %v", err)
+	}
+	t := New(exporter, command)
+	t.closer = closer
+	debug.SetTracer(t)
+	logrus.RegisterExitHandler(debug.Flush)
+	return nil
+}
+
+func newExporter(opts Options) (sdktrace.SpanExporter, io.Closer, error) {
+	switch opts.Exporter {
+	case OTLP:
+		httpOpts, err := otlpOptions(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := otlptracehttp.New(context.Background(), httpOpts...)
+		return exporter, nil, err
+	case File:
+		if opts.File == "" {
+			return nil, nil, fmt.Errorf("the %s exporter needs a file", File)
+		}
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown exporter %q, expected %s or %s", opts.Exporter, OTLP, File)
+	}
+}
+
+// otlpOptions returns the options of the OTLP/HTTP exporter. The endpoint
+// is a host:port or a URL whose scheme and path are honored
+func otlpOptions(opts Options) ([]otlptracehttp.Option, error) {
+	if opts.Endpoint == "" {
+		return nil, fmt.Errorf("the %s exporter needs an endpoint", OTLP)
+	}
+	httpOpts := []otlptracehttp.Option{
+		otlptracehttp.WithTimeout(flushTimeout),
+		// eke exits soon after, retrying would only delay it
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+	}
+	if len(opts.Headers) > 0 {
+		httpOpts = append(httpOpts, otlptracehttp.WithHeaders(opts.Headers))
+	}
+
+	endpoint, insecure := opts.Endpoint, opts.Insecure
+	u, err := url.Parse(endpoint)
+	if err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https") {
+		endpoint = u.Host
+		insecure = insecure || u.Scheme == "http"
+		if u.Path != "" && u.Path != "/" {
+			httpOpts = append(httpOpts, otlptracehttp.WithURLPath(u.Path))
+		}
+	} else if err == nil && u.Scheme != "" && u.Opaque == "" {
+		return nil, fmt.Errorf("invalid endpoint %q, expected host:port or an http(s) URL", opts.Endpoint)
+	}
+	httpOpts = append(httpOpts, otlptracehttp.WithEndpoint(endpoint))
+	if insecure {
+		httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+	}
+	return httpOpts, nil
+}
+
+// StartStep starts the span of a step, a child of the span of the command
+func (t *Tracer) StartStep(name, detail string) debug.TracedStep {
+	opts := []trace.SpanStartOption{trace.WithAttributes(DetailKey.String(detail))}
+	if name == debug.StepEWS {
+		opts = append(opts, trace.WithSpanKind(trace.SpanKindClient))
+	}
+	ctx, span := t.tracer.Start(t.ctx, name, opts...)
+	s := &step{tracer: t, ctx: ctx, span: span}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running[s] = true
+	return s
+}
+
+// Flush ends the running steps and the span of the command, then exports
+// the spans. Later steps are not recorded
+func (t *Tracer) Flush() {
+	t.flush.Do(func() {
+		t.mu.Lock()
+		for s := range t.running {
+			s.span.SetAttributes(RunningKey.Bool(true))
+			s.span.End()
+		}
+		t.running = map[*step]bool{}
+		t.mu.Unlock()
+		t.root.End()
+
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		if err := t.provider.Shutdown(ctx); err != nil {
+			logrus.Warnf("cannot export the traces: %v", err)
+		}
+		if t.closer != nil {
+			t.closer.Close()
+		}
+	})
+}
+
+// step is the span of a step
+type step struct {
+	tracer *Tracer
+	ctx    context.Context
+	span   trace.Span
+}
+
+func (s *step) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	// the step may have been ended by Flush
+	if s.tracer.running[s] {
+		delete(s.tracer.running, s)
+		s.span.End()
+	}
+}
+
+func (s *step) Inject(header http.Header) {
+	propagation.TraceContext{}.Inject(s.ctx, propagation.HeaderCarrier(header))
+}
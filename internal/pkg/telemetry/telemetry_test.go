@@ -0,0 +1,165 @@
+/*
+Copyright 2022 eke authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package telemetry
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"eke/internal/pkg/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// memoryExporter keeps the spans once shut down
+type memoryExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (memoryExporter) Shutdown(context.Context) error { return nil }
+
+func TestTracer(t *testing.T) {
+	var traceparent string
+	ews := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer ews.Close()
+
+	exporter := memoryExporter{tracetest.NewInMemoryExporter()}
+	tracer := New(exporter, "eke kubectl")
+	debug.SetTracer(tracer)
+	defer debug.SetTracer(nil)
+
+	debug.StartStep(debug.StepLookup, "1.23.5").End()
+	client := &http.Client{Transport: debug.Transport(debug.StepEWS, nil, nil)}
+	resp, err := client.Get(ews.URL + "/a/?w=ae")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	debug.StartStep(debug.StepExec, "/usr/bin/kubectl")
+	debug.Flush()
+	// the steps started once flushed are not exported
+	debug.StartStep(debug.StepDownload, "https://dl.k8s.io").End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+
+	root, ok := byName["eke kubectl"]
+	if !ok || root.Parent.IsValid() {
+		t.Fatalf("unexpected root span %+v", root)
+	}
+	for _, name := range []string{debug.StepLookup, debug.StepEWS, debug.StepExec} {
+		span, ok := byName[name]
+		if !ok {
+			t.Fatalf("no span for the %s step", name)
+		}
+		if span.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("the %s span is not a child of the root span", name)
+		}
+	}
+
+	if kind := byName[debug.StepEWS].SpanKind; kind != trace.SpanKindClient {
+		t.Errorf("got kind %v for the ews span, want client", kind)
+	}
+	if detail := attributeOf(byName[debug.StepEWS], DetailKey); detail != "GET "+ews.URL+"/a/" {
+		t.Errorf("unexpected ews detail %q", detail)
+	}
+	if running := attributeOf(byName[debug.StepExec], RunningKey); running != "true" {
+		t.Errorf("the exec step is not marked as running: %q", running)
+	}
+
+	ewsSpan := byName[debug.StepEWS].SpanContext
+	want := "00-" + ewsSpan.TraceID().String() + "-" + ewsSpan.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("got traceparent %q, want %q", traceparent, want)
+	}
+}
+
+func attributeOf(span tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.json")
+	if err := Setup(Options{Exporter: File, File: file}, "eke kubeconfig init"); err != nil {
+		t.Fatal(err)
+	}
+	defer debug.SetTracer(nil)
+	debug.StartStep(debug.StepEndpoint, "cluster1").End()
+	debug.Flush()
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{`"Name":"eke kubeconfig init"`, `"Name":"endpoint"`, `"Value":"cluster1"`} {
+		if !strings.Contains(string(data), name) {
+			t.Errorf("%s not found in %s", name, data)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+" "+r.Header.Get("X-Tenant"))
+	}))
+	defer collector.Close()
+
+	opts := Options{Exporter: OTLP, Endpoint: collector.URL + "/otlp/v1/traces", Headers: map[string]string{"X-Tenant": "eke"}}
+	if err := Setup(opts, "eke kubectl"); err != nil {
+		t.Fatal(err)
+	}
+	defer debug.SetTracer(nil)
+	debug.StartStep(debug.StepDiscovery, "https://cluster1:6443").End()
+	debug.Flush()
+
+	if len(paths) != 1 || paths[0] != "/otlp/v1/traces eke" {
+		t.Errorf("unexpected requests %v", paths)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{Exporter: "jaeger"},
+		{Exporter: OTLP},
+		{Exporter: OTLP, Endpoint: "ftp://collector"},
+		{Exporter: File},
+	} {
+		if err := Setup(opts, "eke"); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
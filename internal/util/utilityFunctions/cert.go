@@ -0,0 +1,56 @@
+package utilityFunctions
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// ClientCertFile is the name of the cached client certificate, inside of
+// the directory of a profile
+const ClientCertFile = "k8s_client.crt"
+
+// CertInfo describes a cached client certificate
+type CertInfo struct {
+	Path      string    `json:"path"`
+	User      string    `json:"user"`
+	Groups    []string  `json:"groups"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	Expired   bool      `json:"expired"`
+}
+
+// InspectCert describes the PEM client certificate at path, as seen at now.
+// The user and the groups are the common name and the organizations of the
+// subject, as read by the API servers
+func InspectCert(path string, now time.Time) (CertInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return CertInfo{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return CertInfo{}, fmt.Errorf("%s: no PEM certificate found", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return CertInfo{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	groups := cert.Subject.Organization
+	if groups == nil {
+		groups = []string{}
+	}
+	return CertInfo{
+		Path:      path,
+		User:      cert.Subject.CommonName,
+		Groups:    groups,
+		Issuer:    cert.Issuer.String(),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		Expired:   now.After(cert.NotAfter),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package utilityFunctions
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInspectCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "eabc", Organization: []string{"dev"}},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), ClientCertFile)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := InspectCert(path, notAfter.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.User != "eabc" || len(info.Groups) != 1 || info.Groups[0] != "dev" || info.Issuer != "CN=eabc,O=dev" || !info.NotAfter.Equal(notAfter) || info.Expired {
+		t.Errorf("unexpected certificate info %+v", info)
+	}
+
+	if info, _ := InspectCert(path, notAfter.Add(time.Hour)); !info.Expired {
+		t.Error("expected the certificate to be expired")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InspectCert(path, notAfter); err == nil {
+		t.Error("expected an error for an invalid certificate")
+	}
+}
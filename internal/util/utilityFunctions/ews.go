@@ -0,0 +1,100 @@
+package utilityFunctions
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/pkg/debug"
+	"eke/pkg/config/cmdconfig"
+)
+
+// DefaultEwsURL is the base URL of the EWS API used without profile, or
+// when the profile does not set one
+const DefaultEwsURL = "https://ews.rnd.gic.ericsson.se/a/"
+
+// Ews locates the EWS instance the credentials and the clusters are
+// requested from
+type Ews struct {
+	// URL is the base URL of the EWS API
+	URL string
+	// CA holds the PEM certificates trusted on top of the system ones,
+	// the built in EWS root CA is used when empty
+	CA []byte
+	// User is the user id used instead of prompting for one
+	User string
+}
+
+// EwsFor returns the EWS instance of the profile, the default one when
+// profile is nil
+func EwsFor(profile *cmdconfig.Profile) (Ews, error) {
+	ews := Ews{URL: DefaultEwsURL}
+	if profile == nil {
+		return ews, nil
+	}
+
+	if profile.EwsURL != "" {
+		ews.URL = profile.EwsURL
+	}
+	if profile.CA != "" {
+		ca, err := ioutil.ReadFile(common.ExpandHome(profile.CA))
+		if err != nil {
+			return Ews{}, fmt.Errorf("cannot read the CA of the profile: %v", err)
+		}
+		ews.CA = ca
+	}
+	ews.User = profile.User
+	return ews, nil
+}
+
+// ProfileEws returns the name of the profile selected by config, empty when
+// none is, along with its EWS instance. A config that cannot be loaded,
+// nil, falls back to the default EWS instance unless a profile has been
+// requested with --profile or EKE_PROFILE: kubectl runs the credential
+// plugin on every request, which must not break on a typo in eke.cmd.yaml
+func ProfileEws(config *cmdconfig.EkeCmdConfig, requested bool) (string, Ews, error) {
+	if config == nil {
+		if requested {
+			return "", Ews{}, errors.New("cannot load eke.cmd.yaml")
+		}
+		logger.Warnln("cannot load eke.cmd.yaml, using the default EWS instance")
+		return "", Ews{URL: DefaultEwsURL}, nil
+	}
+	profile, err := config.ActiveProfile()
+	if err != nil {
+		return "", Ews{}, err
+	}
+	ews, err := EwsFor(profile)
+	return config.Profile, ews, err
+}
+
+// Client returns the HTTP client trusting the CA of the EWS instance
+func (e Ews) Client() (*http.Client, error) {
+	if len(e.CA) == 0 {
+		return &http.Client{Transport: debug.Transport(debug.StepEWS, debug.EWSRequests, nil)}, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(e.CA) {
+		return nil, errors.New("no certificate found in the CA of the profile")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: debug.Transport(debug.StepEWS, debug.EWSRequests, transport)}, nil
+}
+
+// userSignum returns the user id of the EWS instance, prompting for it
+// when it has none
+func (e Ews) userSignum() (string, error) {
+	if e.User != "" {
+		return e.User, nil
+	}
+	return GetUserSignum()
+}
@@ -0,0 +1,101 @@
+package utilityFunctions
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eke/pkg/config/cmdconfig"
+)
+
+func TestEwsFor(t *testing.T) {
+	ews, err := EwsFor(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ews.URL != DefaultEwsURL || ews.CA != nil || ews.User != "" {
+		t.Errorf("got %+v, want the default EWS", ews)
+	}
+
+	ews, err = EwsFor(&cmdconfig.Profile{User: "eabc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ews.URL != DefaultEwsURL || ews.User != "eabc" {
+		t.Errorf("got %+v, want the default EWS URL and the user of the profile", ews)
+	}
+
+	if _, err := EwsFor(&cmdconfig.Profile{CA: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("expected an error for a missing CA")
+	}
+}
+
+func TestProfileEws(t *testing.T) {
+	// an unloadable configuration falls back to the default EWS unless a
+	// profile has been requested
+	profile, ews, err := ProfileEws(nil, false)
+	if err != nil || profile != "" || ews.URL != DefaultEwsURL {
+		t.Errorf("got %q, %+v, %v, want the default EWS", profile, ews, err)
+	}
+	if _, _, err := ProfileEws(nil, true); err == nil {
+		t.Error("expected an error for a requested profile")
+	}
+
+	config := &cmdconfig.EkeCmdConfig{
+		Profile:  "prod",
+		Profiles: map[string]cmdconfig.Profile{"prod": {EwsURL: "https://ews.example.com/a/"}},
+	}
+	profile, ews, err = ProfileEws(config, true)
+	if err != nil || profile != "prod" || ews.URL != "https://ews.example.com/a/" {
+		t.Errorf("got %q, %+v, %v, want the EWS of the prod profile", profile, ews, err)
+	}
+}
+
+func TestEwsClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("https://api.example.com:6443"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "eke-ews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server is only trusted through the CA of the profile
+	if _, err := mustClient(t, Ews{URL: server.URL}).Get(server.URL); err == nil {
+		t.Error("expected the server not to be trusted without the CA")
+	}
+
+	ews, err := EwsFor(&cmdconfig.Profile{EwsURL: server.URL, CA: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := mustClient(t, ews).Get(ews.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := (Ews{CA: []byte("not a certificate")}).Client(); err == nil {
+		t.Error("expected an error for an invalid CA")
+	}
+}
+
+func mustClient(t *testing.T, e Ews) *http.Client {
+	client, err := e.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
@@ -7,16 +7,20 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"eke/internal/pkg/logging"
+
 	"golang.org/x/term"
 )
 
+// logger writes the messages of the ews subsystem
+var logger = logging.For(logging.EWS)
+
 // get user password
 func GetUserPassword() (string, error) {
 
@@ -56,68 +60,68 @@ func GetUserSignum() (string, error) {
 }
 
 // This method gets user .crt and .key and returns them as string
-func GetCertAndKey(cache_location string) (string, string) {
+func GetCertAndKey(ews Ews, cache_location string) (string, string) {
 
 	_, err := os.Stat(cache_location + "k8s_client.crt")
 
 	// if certification does not already exist, get it from EWS
 	if err != nil {
 
-		log.Println("certificate was not found in local cache! Requesting it from EWS...")
+		logger.Infoln("certificate was not found in local cache! Requesting it from EWS...")
 
 		// Get signum and password from the user
-		signum, err := GetUserSignum()
+		signum, err := ews.userSignum()
 		if err != nil {
-			log.Fatalln("error occured while prompting for credentials:", err)
+			logger.Fatalln("error occured while prompting for credentials:", err)
 		}
 
 		pass, err := GetUserPassword()
 		if err != nil {
-			log.Fatalln("error occured while prompting for credentials:", err)
+			logger.Fatalln("error occured while prompting for credentials:", err)
 		}
 
-		return RequestCertAndKeyFromEWS(cache_location, signum, pass, false)
+		return RequestCertAndKeyFromEWS(ews, cache_location, signum, pass, false)
 
 	} else { // Certificate exists but needs to be checked for expiration
 
 		userCertBytes, err := ioutil.ReadFile(cache_location + "k8s_client.crt")
 		if err != nil {
-			log.Fatalln("failed to read existing client certificate file")
+			logger.Fatalln("failed to read existing client certificate file")
 		}
 
 		userKeyBytes, err := ioutil.ReadFile(cache_location + "k8s_client.key")
 		if err != nil {
-			log.Fatalln("failed to read existing client key file")
+			logger.Fatalln("failed to read existing client key file")
 		}
 
 		// Decode the PEM
 		block, _ := pem.Decode(userCertBytes)
 		if block == nil {
-			log.Fatalln("failed to parse existing client certificate")
+			logger.Fatalln("failed to parse existing client certificate")
 		}
 
 		// Now parse the certificate
 		cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
-			log.Fatalln("failed to parse existing client certificate:" + err.Error())
+			logger.Fatalln("failed to parse existing client certificate:" + err.Error())
 		}
 
 		// if expired, contact EWS
 		if (time.Now()).After(cert.NotAfter) {
-			log.Println("certificate has expired on:", cert.NotAfter, " Requesting it from EWS...")
+			logger.Infoln("certificate has expired on:", cert.NotAfter, " Requesting it from EWS...")
 
 			// Get signum and password from the user
-			signum, err := GetUserSignum()
+			signum, err := ews.userSignum()
 			if err != nil {
-				log.Fatalln("error occured while prompting for credentials!")
+				logger.Fatalln("error occured while prompting for credentials!")
 			}
 
 			pass, err := GetUserPassword()
 			if err != nil {
-				log.Fatalln("error occured while prompting for credentials!")
+				logger.Fatalln("error occured while prompting for credentials!")
 			}
 
-			return RequestCertAndKeyFromEWS(cache_location, signum, pass, false)
+			return RequestCertAndKeyFromEWS(ews, cache_location, signum, pass, false)
 
 		} else {
 			// Certification exists and is valid
@@ -131,7 +135,7 @@ func GetCertAndKey(cache_location string) (string, string) {
 // This method requests user .crt and .key from EWS, saves them in specified location,
 // and then returns them as string to later output on stdout for kubectl.
 // forceRenew determines whether to force renew the cert and key
-func RequestCertAndKeyFromEWS(cache_location string, signum string, pass string, forceRenew bool) (string, string) {
+func RequestCertAndKeyFromEWS(ews Ews, cache_location string, signum string, pass string, forceRenew bool) (string, string) {
 
 	// Now make a POST request to EWS
 	creds := url.Values{
@@ -142,26 +146,30 @@ func RequestCertAndKeyFromEWS(cache_location string, signum string, pass string,
 	// TODO: add some timeout logic
 	var url string
 	if forceRenew {
-		url = "https://ews.rnd.gic.ericsson.se/a/?a=ckc&f=yes"
+		url = ews.URL + "?a=ckc&f=yes"
 	} else {
-		url = "https://ews.rnd.gic.ericsson.se/a/?a=ckc"
+		url = ews.URL + "?a=ckc"
 	}
 
-	resp, err := http.PostForm(url, creds)
+	client, err := ews.Client()
+	if err != nil {
+		logger.Fatalln(err)
+	}
+	resp, err := client.PostForm(url, creds)
 	if err != nil {
-		log.Println(err)
+		logger.Fatalln(err)
 	}
 
 	// the response is a map of strings to "ANY" aribtrary type(i.e. empty interface)
 	var res map[string]interface{}
 
 	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		log.Println(err)
+		logger.Warnln(err)
 	}
 
 	// Check if the result is as expected
 	if len(res) == 0 {
-		log.Fatalln("signum or password incorrect!")
+		logger.Fatalln("signum or password incorrect!")
 	}
 
 	// Type assert the "status" part of the response(should be map[string]interface{})
@@ -175,15 +183,15 @@ func RequestCertAndKeyFromEWS(cache_location string, signum string, pass string,
 	// Save the received certificate and key
 	err = ioutil.WriteFile(cache_location+"k8s_client.crt", userCertBytes, 0600)
 	if err != nil {
-		log.Fatalln(err)
+		logger.Fatalln(err)
 	}
 
 	err = ioutil.WriteFile(cache_location+"k8s_client.key", userKeyBytes, 0600)
 	if err != nil {
-		log.Fatalln(err)
+		logger.Fatalln(err)
 	}
 
-	log.Println("successfully retrieved and cached user cert and key in:", cache_location)
+	logger.Infoln("successfully retrieved and cached user cert and key in:", cache_location)
 
 	return string(userCertBytes), string(userKeyBytes)
 
@@ -205,7 +213,7 @@ func CreateOutput(userCert string, userKey string) string {
 
 	jsonData, err := json.Marshal(output)
 	if err != nil {
-		log.Fatalln(err.Error())
+		logger.Fatalln(err.Error())
 	}
 
 	// return the proper output format for kubectl
@@ -219,7 +227,7 @@ func Get_eke_path() string {
 	// create the path for eke cache
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 
 	// check the path for EKE_CACHE
@@ -227,19 +235,37 @@ func Get_eke_path() string {
 	if _, err := os.Stat(eke_cache); os.IsNotExist(err) {
 		err := os.MkdirAll(eke_cache, 0755)
 		if err != nil {
-			log.Fatal("error creating path for eke cache:", err)
+			logger.Fatal("error creating path for eke cache:", err)
 		}
 	}
 	return eke_cache
 }
 
+// checks whether path for caching the cert and key of a profile
+// (~/.eke/profiles/<profile>/) exists or not, if not it creates it and
+// returns the path. Without profile it is the eke cache
+func Get_profile_path(profile string) string {
+	if profile == "" {
+		return Get_eke_path()
+	}
+
+	profile_cache := filepath.Join(Get_eke_path(), "profiles", profile) + "/"
+	if _, err := os.Stat(profile_cache); os.IsNotExist(err) {
+		err := os.MkdirAll(profile_cache, 0700)
+		if err != nil {
+			logger.Fatal("error creating path for profile cache:", err)
+		}
+	}
+	return profile_cache
+}
+
 // checks whether default path for kubeconfig (~/.kube/) exists
 // or not, if not it creates it and returns the path
 func Get_kubeconfig_path() string {
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(err)
 	}
 
 	// check the kubeconfig path
@@ -247,7 +273,7 @@ func Get_kubeconfig_path() string {
 	if _, err := os.Stat(kubeconfig_path); os.IsNotExist(err) {
 		err := os.MkdirAll(kubeconfig_path, 0755)
 		if err != nil {
-			log.Fatal("error creating path for kubeconfig path:", err)
+			logger.Fatal("error creating path for kubeconfig path:", err)
 		}
 	}
 	return kubeconfig_path
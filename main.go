@@ -17,18 +17,12 @@ package main
 
 import (
 	"eke/cmd"
-	"os"
-
-	"github.com/sirupsen/logrus"
+	"eke/internal/pkg/logging"
 )
 
 func init() {
-	logrus.SetOutput(os.Stderr)
-	logrus.SetLevel(logrus.WarnLevel)
-	customFormatter := new(logrus.TextFormatter)
-	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
-	customFormatter.FullTimestamp = true
-	logrus.SetFormatter(customFormatter)
+	// the commands apply the --log- flags and eke.cmd.yaml
+	logging.Setup(logging.Options{})
 }
 
 func main() {
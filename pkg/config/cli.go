@@ -20,13 +20,20 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	k8s "k8s.io/client-go/kubernetes"
 	cloudprovider "k8s.io/cloud-provider"
 
+	"eke/internal/kubectlcmd/common"
+	"eke/internal/kubectlcmd/complete"
+	"eke/internal/pkg/logging"
+	"eke/internal/pkg/output"
+	"eke/internal/pkg/telemetry"
 	"eke/pkg/apis/eke/v1beta1"
 
 	"eke/pkg/constant"
@@ -37,9 +44,15 @@ import (
 var (
 	CfgFile        string
 	CmdCfgFile     string
+	ProfileName    string
+	OutputFormat   string
+	LogFormat      string
+	LogFile        string
+	LogLevel       string
 	DataDir        string
 	Debug          bool
 	DebugListenOn  string
+	DebugToken     string
 	StatusSocket   string
 	EkeVars        constant.CfgVars
 	workerOpts     WorkerOptions
@@ -58,6 +71,7 @@ type CLIOptions struct {
 	CmdConfig        *cmdconfig.EkeCmdConfig
 	Debug            bool
 	DebugListenOn    string
+	DebugToken       string
 	DefaultLogLevels map[string]string
 	EkeVars          constant.CfgVars
 	KubeClient       k8s.Interface
@@ -114,8 +128,13 @@ func GetPersistentFlagSet() *pflag.FlagSet {
 	flagset.BoolVarP(&Verbose, "verbose", "v", false, "Verbose logging (default: false)")
 	flagset.StringVar(&DataDir, "data-dir", "", "Data Directory for eke (default: /var/lib/eke). DO NOT CHANGE for an existing setup, things will break!")
 	flagset.StringVar(&CmdCfgFile, "cmd-config", "", "the directory for eke commands config file eke.cmd.yaml")
+	flagset.StringVar(&ProfileName, "profile", "", "the profile of eke.cmd.yaml to use, overrides EKE_PROFILE")
+	flagset.StringVar(&LogFormat, "log-format", "", "format of the messages, text or json, overrides logging.format of eke.cmd.yaml")
+	flagset.StringVar(&LogFile, "log-file", "", "file the messages are written to instead of stderr, rotated by size, overrides logging.file of eke.cmd.yaml")
+	flagset.StringVar(&LogLevel, "log-level", "", "level of the messages, optionally followed by subsystem=level pairs for the ews, kubectl and config subsystems, e.g. info,ews=debug")
 	flagset.StringVar(&StatusSocket, "status-socket", filepath.Join(EkeVars.RunDir, "status.sock"), "Full file path to the socket file.")
-	flagset.StringVar(&DebugListenOn, "debugListenOn", ":6060", "Http listenOn for Debug pprof handler")
+	flagset.StringVar(&DebugListenOn, "debugListenOn", "localhost:6060", "address of the debug server started with --debug, host:port or unix:<socket path>")
+	flagset.StringVar(&DebugToken, "debug-token", "", "token required by the debug server, as a bearer token or the token query parameter, defaults to EKE_DEBUG_TOKEN")
 	return flagset
 }
 
@@ -230,6 +249,7 @@ func GetCmdOpts() CLIOptions {
 		DefaultLogLevels: DefaultLogLevels(),
 		EkeVars:          EkeVars,
 		DebugListenOn:    DebugListenOn,
+		DebugToken:       debugToken(),
 	}
 	return opts
 }
@@ -260,16 +280,118 @@ func getClusterConfig(ekeVars constant.CfgVars) *v1beta1.ClusterConfig {
 	return cfg
 }
 
-func getEkeCmdConfig() *cmdconfig.EkeCmdConfig {
+// AddOutputFlag adds -o/--output to cmd, a command printing data. It is
+// not a persistent flag of eke, kubectl and the tools run by eke have their
+// own -o
+func AddOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&OutputFormat, "output", "o", output.Table, "output format: table, json, yaml, jsonpath=<template> or go-template=<template>")
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// the templates follow jsonpath= and go-template=
+		return complete.Matching(output.Formats, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	})
+}
+
+// Printer returns the printer of the format selected with -o
+func Printer() (*output.Printer, error) {
+	return output.New(OutputFormat)
+}
+
+// debugToken returns the token of the debug server, the environment
+// keeps it out of the process list
+func debugToken() string {
+	if DebugToken != "" {
+		return DebugToken
+	}
+	return os.Getenv("EKE_DEBUG_TOKEN")
+}
+
+// TracingOptions returns the configuration of the traces, from the tracing
+// section of eke.cmd.yaml
+func TracingOptions(cmdConfig *cmdconfig.EkeCmdConfig) telemetry.Options {
+	if cmdConfig == nil {
+		return telemetry.Options{}
+	}
+	tracing := cmdConfig.Tracing
+	return telemetry.Options{
+		Exporter: tracing.Exporter,
+		Endpoint: tracing.Endpoint,
+		Insecure: tracing.Insecure,
+		Headers:  tracing.Headers,
+		File:     common.ExpandHome(tracing.File),
+	}
+}
+
+// LoggingOptions returns the configuration of the loggers, the --log- flags,
+// then --debug and --verbose, take precedence over the logging section of
+// eke.cmd.yaml. quiet lowers the default levels, for the exec plugins
+func LoggingOptions(cmdConfig *cmdconfig.EkeCmdConfig, debug, verbose, quiet bool) (logging.Options, error) {
+	opts := logging.Options{Levels: map[string]string{}, Quiet: quiet}
+	if cmdConfig != nil {
+		opts.Format = cmdConfig.Logging.Format
+		opts.File = common.ExpandHome(cmdConfig.Logging.File)
+		opts.MaxSize = cmdConfig.Logging.MaxSize
+		opts.MaxFiles = cmdConfig.Logging.MaxFiles
+		opts.Level = cmdConfig.Logging.Level
+		for subsystem, level := range cmdConfig.Logging.Levels {
+			opts.Levels[subsystem] = level
+		}
+	}
+
+	if LogFormat != "" {
+		opts.Format = LogFormat
+	}
+	if LogFile != "" {
+		opts.File = LogFile
+	}
+	if verbose {
+		opts.Level = "info"
+	}
+	if debug {
+		opts.Level = "debug"
+	}
+	level, levels, err := logging.ParseLevels(LogLevel)
+	if err != nil {
+		return opts, err
+	}
+	if level != "" {
+		opts.Level = level
+	}
+	for subsystem, level := range levels {
+		opts.Levels[subsystem] = level
+	}
+	return opts, nil
+}
+
+// CmdConfigLoader returns the loader of eke.cmd.yaml, including the
+// directory given with --cmd-config
+func CmdConfigLoader() *cmdconfig.ConfigLoader {
 	configLoader := cmdconfig.NewConfigLoader()
 
 	if CmdCfgFile != "" {
 		configLoader.Paths = append(configLoader.Paths, CmdCfgFile)
 	}
+	return configLoader
+}
+
+// ProfileRequested tells whether a profile has been requested explicitly,
+// with --profile or EKE_PROFILE, rather than set in eke.cmd.yaml
+func ProfileRequested() bool {
+	return ProfileName != "" || os.Getenv(cmdconfig.EnvPrefix+"PROFILE") != ""
+}
 
-	cfg, err := configLoader.Load()
+// cmdConfigError reports the configuration errors once, getEkeCmdConfig
+// being called several times by each command
+var cmdConfigError sync.Once
+
+func getEkeCmdConfig() *cmdconfig.EkeCmdConfig {
+	cfg, err := CmdConfigLoader().Load()
 	if err != nil {
 		return nil
 	}
+	// an unknown profile must not fall back to the default credentials
+	if _, err := cfg.SelectProfile(ProfileName); err != nil {
+		cmdConfigError.Do(func() { logging.For(logging.Config).Error(err) })
+		return nil
+	}
 	return cfg
 }
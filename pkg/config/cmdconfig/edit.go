@@ -0,0 +1,196 @@
+package cmdconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes of the configuration files edited by SetValue and UnsetValue
+const (
+	ScopeUser   = "user"
+	ScopeSystem = "system"
+)
+
+// ScopeDir returns the directory of the eke.cmd.yaml file of the scope
+func ScopeDir(scope string) (string, error) {
+	switch scope {
+	case ScopeUser:
+		return userConfigDir, nil
+	case ScopeSystem:
+		return systemConfigDir, nil
+	}
+	return "", fmt.Errorf("unknown scope %q, expected %s or %s", scope, ScopeUser, ScopeSystem)
+}
+
+// SetValue sets the dotted key to value, which is parsed as YAML, in the
+// eke.cmd.yaml file of dir. The file is created when missing, comments
+// are preserved
+func SetValue(dir, key, value string) error {
+	parts, t, err := resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""}
+	if len(parsed.Content) > 0 {
+		valueNode = parsed.Content[0]
+	}
+	var errs []*ValidationError
+	validateNode(valueNode, t, strings.Join(parts, "."), &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid value for %s: %s", errs[0].Key, errs[0].Message)
+	}
+
+	path := filepath.Join(dir, "eke.cmd.yaml")
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+
+	node := doc.Content[0]
+	for i, part := range parts {
+		_, child := lookupKey(node, part)
+		if i == len(parts)-1 {
+			if child != nil {
+				valueNode.LineComment = child.LineComment
+				*child = *valueNode
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, valueNode)
+			}
+			break
+		}
+		if child == nil || child.Kind != yaml.MappingNode {
+			mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if child != nil {
+				*child = *mapping
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, mapping)
+				child = mapping
+			}
+		}
+		node = child
+	}
+	return writeDocument(path, doc)
+}
+
+// UnsetValue removes the dotted key from the eke.cmd.yaml file of dir, the
+// mappings left empty are removed as well
+func UnsetValue(dir, key string) error {
+	parts, _, err := resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, "eke.cmd.yaml")
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+	if !removeKey(doc.Content[0], parts) {
+		return fmt.Errorf("%s is not set in %s", key, path)
+	}
+	return writeDocument(path, doc)
+}
+
+func removeKey(node *yaml.Node, parts []string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !strings.EqualFold(node.Content[i].Value, parts[0]) {
+			continue
+		}
+		child := node.Content[i+1]
+		if len(parts) > 1 {
+			if !removeKey(child, parts[1:]) {
+				return false
+			}
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		return true
+	}
+	return false
+}
+
+// lookupKey returns the key and value nodes of the mapping for key, keys
+// are case insensitive
+func lookupKey(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// readDocument parses the YAML file at path, a missing or empty file is
+// an empty mapping
+func readDocument(path string) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping", path)
+	}
+	return doc, nil
+}
+
+// writeDocument atomically replaces the file at path, keeping its mode
+func writeDocument(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
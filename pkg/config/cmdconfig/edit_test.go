@@ -0,0 +1,165 @@
+package cmdconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		key    string
+		value  string
+		want   string
+		errMsg string
+	}{
+		{
+			name:  "creates the file",
+			key:   "ekeKubectlConfig.timeout",
+			value: "3",
+			want:  "ekeKubectlConfig:\n  timeout: 3\n",
+		},
+		{
+			name:   "keeps comments and the case of existing keys",
+			config: "# eke settings\nEKEKUBECTLCONFIG:\n  # seconds\n  timeout: 8 # was 5\n  systemPath: /usr/bin\n",
+			key:    "ekeKubectlConfig.timeout",
+			value:  "3",
+			want:   "# eke settings\nEKEKUBECTLCONFIG:\n  # seconds\n  timeout: 3 # was 5\n  systemPath: /usr/bin\n",
+		},
+		{
+			name:   "creates the parent mappings",
+			config: "aliases:\n  a: get pods\n",
+			key:    "ekeKubectlConfig.policy.mode",
+			value:  "exact-minor",
+			want:   "aliases:\n  a: get pods\nekeKubectlConfig:\n  policy:\n    mode: exact-minor\n",
+		},
+		{
+			name:  "sets a list",
+			key:   "ekeKubectlConfig.searchPaths",
+			value: "[/opt/bin, /usr/local/bin]",
+			want:  "ekeKubectlConfig:\n  searchPaths: [/opt/bin, /usr/local/bin]\n",
+		},
+		{
+			name:  "sets a map entry",
+			key:   "aliases.podsof",
+			value: "get pods -l app=$1",
+			want:  "aliases:\n  podsof: get pods -l app=$1\n",
+		},
+		{
+			name:   "unknown key",
+			key:    "ekeKubectlConfig.timeouts",
+			value:  "3",
+			errMsg: "unknown key",
+		},
+		{
+			name:   "invalid value",
+			key:    "ekeKubectlConfig.timeout",
+			value:  "soon",
+			errMsg: "invalid value for ekeKubectlConfig.timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "eke-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			if tt.config != "" {
+				if err := writeConfig(dir, tt.config); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = SetValue(dir, tt.key, tt.value)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("got error %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "eke.cmd.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		key    string
+		want   string
+		errMsg string
+	}{
+		{
+			name:   "removes the key",
+			config: "ekeKubectlConfig:\n  # seconds\n  timeout: 3\n  systemPath: /usr/bin\n",
+			key:    "EKEKUBECTLCONFIG.TIMEOUT",
+			want:   "ekeKubectlConfig:\n  systemPath: /usr/bin\n",
+		},
+		{
+			name:   "removes the empty parents",
+			config: "aliases:\n  a: get pods\nekeKubectlConfig:\n  policy:\n    mode: skew\n",
+			key:    "ekeKubectlConfig.policy.mode",
+			want:   "aliases:\n  a: get pods\n",
+		},
+		{
+			name:   "not set",
+			config: "ekeKubectlConfig:\n  timeout: 3\n",
+			key:    "ekeKubectlConfig.systemPath",
+			errMsg: "is not set",
+		},
+		{
+			name:   "unknown key",
+			key:    "nothing",
+			errMsg: "unknown key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "eke-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			if err := writeConfig(dir, tt.config); err != nil {
+				t.Fatal(err)
+			}
+
+			err = UnsetValue(dir, tt.key)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("got error %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "eke.cmd.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
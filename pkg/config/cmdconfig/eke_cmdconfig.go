@@ -3,24 +3,47 @@ package cmdconfig
 import (
 	"bytes"
 	"embed"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// ConfigLoader merges the configuration of the eke commands, from the
+// lowest to the highest precedence:
+//  1. the embedded default configuration
+//  2. the eke.cmd.yaml files of Paths, in order
+//  3. the project files, ProjectConfigFile, found from the root of the
+//     filesystem down to ProjectDir. They can only set the ProjectKeys,
+//     their guardrails are added after the ones already configured
+//  4. the EnvPrefix environment variables of Environ
 type ConfigLoader struct {
 	Paths []string
+	// ProjectDir is where the lookup of the project files starts, none
+	// are read when it is empty
+	ProjectDir string
+	// Environ holds the environment variables, in the form of os.Environ
+	Environ []string
 }
 
 const DEFAULT_CONFIG = "defaultconfig/eke.cmd.default.yaml"
 
+// ProjectConfigFile is the name of the project configuration files, which
+// let a repository pin kubectl versions or add guardrails
+const ProjectConfigFile = ".eke.cmd.yaml"
+
 //go:embed defaultconfig/eke.cmd.default.yaml
 var df embed.FS
 
 func NewConfigLoader() *ConfigLoader {
+	wd, _ := os.Getwd()
 	return &ConfigLoader{
-		Paths: configPaths,
+		Paths:      configPaths,
+		ProjectDir: wd,
+		Environ:    os.Environ(),
 	}
 }
 
@@ -42,12 +65,38 @@ func (c *ConfigLoader) Load() (*EkeCmdConfig, error) {
 func (c *ConfigLoader) preload() (*viper.Viper, error) {
 	v, _ := c.setDefault()
 
-	if len(c.Paths) == 0 {
-		return v, nil
+	files, err := c.userFiles()
+	if err != nil {
+		return viper.New(), err
+	}
+	for _, file := range files {
+		v.SetConfigFile(file)
+		if err := v.MergeInConfig(); err != nil {
+			return viper.New(), err
+		}
 	}
 
-	for _, path := range c.Paths {
-		if err := mergeConfig(v, path); err != nil {
+	projectFiles, err := c.projectFiles()
+	if err != nil {
+		return viper.New(), err
+	}
+	for _, file := range projectFiles {
+		guardrails, _ := v.Get("ekeKubectlConfig.guardrails").([]interface{})
+		values, err := readProjectFile(file, guardrails)
+		if err != nil {
+			return viper.New(), err
+		}
+		if err := v.MergeConfigMap(values); err != nil {
+			return viper.New(), err
+		}
+	}
+
+	overrides, err := EnvOverrides(c.Environ)
+	if err != nil {
+		return viper.New(), err
+	}
+	for _, o := range overrides {
+		if err := v.MergeConfigMap(o.Map()); err != nil {
 			return viper.New(), err
 		}
 	}
@@ -64,17 +113,114 @@ func (c *ConfigLoader) setDefault() (*viper.Viper, error) {
 	return v, nil
 }
 
-func mergeConfig(v *viper.Viper, extraConfigPath string) error {
-	cfgFile := filepath.Join(extraConfigPath, "eke.cmd.yaml")
+// Files returns the existing configuration files, in the order they are
+// merged
+func (c *ConfigLoader) Files() ([]string, error) {
+	files, err := c.userFiles()
+	if err != nil {
+		return nil, err
+	}
+	projectFiles, err := c.projectFiles()
+	if err != nil {
+		return nil, err
+	}
+	return append(files, projectFiles...), nil
+}
+
+// userFiles returns the existing eke.cmd.yaml files of Paths
+func (c *ConfigLoader) userFiles() ([]string, error) {
+	var files []string
+	for _, path := range c.Paths {
+		cfgFile := filepath.Join(path, "eke.cmd.yaml")
+		ok, err := exists(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = append(files, cfgFile)
+		}
+	}
+	return files, nil
+}
+
+// projectFiles returns the project files found in ProjectDir and its
+// parents, the farthest first
+func (c *ConfigLoader) projectFiles() ([]string, error) {
+	if c.ProjectDir == "" {
+		return nil, nil
+	}
+	dir, err := filepath.Abs(c.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for {
+		cfgFile := filepath.Join(dir, ProjectConfigFile)
+		ok, err := exists(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = append([]string{cfgFile}, files...)
+		}
 
-	_, err := os.Stat(cfgFile)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return files, nil
+		}
+		dir = parent
+	}
+}
+
+// IsProjectFile tells whether path is a project file rather than an
+// eke.cmd.yaml file
+func IsProjectFile(path string) bool {
+	return filepath.Base(path) == ProjectConfigFile
+}
+
+// readProjectFile returns the settings of the project file at path, once
+// checked with ValidateProject. Its guardrails are appended to the given
+// ones instead of replacing them
+func readProjectFile(path string, guardrails []interface{}) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+		return nil, err
+	}
+	errs, err := ValidateProject(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %v", path, errs[0])
+	}
+	values, err := parseLayer(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for name, value := range values {
+		kubectl, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range kubectl {
+			if added, ok := value.([]interface{}); ok && strings.EqualFold(key, "guardrails") {
+				kubectl[key] = append(append([]interface{}{}, guardrails...), added...)
+			}
 		}
-		return err
+		values[name] = kubectl
 	}
-	v.SetConfigFile(cfgFile)
+	return values, nil
+}
 
-	return v.MergeInConfig()
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
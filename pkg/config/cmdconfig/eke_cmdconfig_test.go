@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -179,3 +181,164 @@ ekeKubectlConfig:
 			v.EkeKubectlConfig.SystemPath, "global")
 	}
 }
+
+func TestMirrorsConfig(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer teardown(td)
+
+	var data = `
+ekeKubectlConfig:
+  mirrors:
+  - url: https://artifacts.example.com/kubectl/v{{.Version}}/{{.OS}}/{{.Arch}}/kubectl{{.Ext}}
+    stableURL: https://artifacts.example.com/kubectl/stable.txt
+    token: ${ARTIFACTS_TOKEN}
+  - url: file:///srv/kubectl/{{.Version}}/kubectl
+    checksumURL: file:///srv/kubectl/{{.Version}}/SHA256SUMS
+`
+	err = writeConfig(td.FakeHome, data)
+	if err != nil {
+		t.Error(err)
+	}
+
+	c := ConfigLoader{
+		Paths: []string{td.FakeUsrEtc, td.FakeEtc, td.FakeHome},
+	}
+
+	v, err := c.Load()
+	if err != nil {
+		t.Errorf("Unexpected error loading config: %v", err)
+	}
+
+	mirrors := v.EkeKubectlConfig.Mirrors
+	if len(mirrors) != 2 {
+		t.Fatalf("Expected 2 mirrors, got %d", len(mirrors))
+	}
+	if mirrors[0].Token != "${ARTIFACTS_TOKEN}" {
+		t.Errorf("Wrong value for Token: got %v", mirrors[0].Token)
+	}
+	if mirrors[1].ChecksumURL != "file:///srv/kubectl/{{.Version}}/SHA256SUMS" {
+		t.Errorf("Wrong value for ChecksumURL: got %v", mirrors[1].ChecksumURL)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown(td)
+
+	// project files in the home directory and in a repository below it
+	repo := filepath.Join(td.FakeHome, "repo")
+	subdir := filepath.Join(repo, "deploy")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join(td.FakeEtc, "eke.cmd.yaml"): `
+ekeKubectlConfig:
+  systemPath: /etc/bin
+  timeout: 1
+  policy:
+    mode: exact-patch
+  guardrails:
+  - context: prod-*
+    action: block
+aliases:
+  a: get pods
+`,
+		filepath.Join(td.FakeHome, ProjectConfigFile): `
+ekeKubectlConfig:
+  guardrails:
+  - context: staging-*
+`,
+		filepath.Join(repo, ProjectConfigFile): `
+ekeKubectlConfig:
+  pins:
+  - context: prod-*
+    version: 1.21.14
+  guardrails:
+  - context: "*"
+    verbs: [delete]
+`,
+	}
+	for path, data := range files {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader := ConfigLoader{
+		Paths:      []string{td.FakeEtc},
+		ProjectDir: subdir,
+		Environ:    []string{"EKE_KUBECTL_TIMEOUT=4", "EKE_KUBECTL_POLICY_MODE=skew"},
+	}
+
+	gotFiles, err := loader.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the files above the temporary directory, if any, come first
+	wantFiles := []string{
+		filepath.Join(td.FakeEtc, "eke.cmd.yaml"),
+		filepath.Join(td.FakeHome, ProjectConfigFile),
+		filepath.Join(repo, ProjectConfigFile),
+	}
+	if len(gotFiles) < 3 || gotFiles[0] != wantFiles[0] || !reflect.DeepEqual(gotFiles[len(gotFiles)-2:], wantFiles[1:]) {
+		t.Fatalf("got files %v, want %v", gotFiles, wantFiles)
+	}
+
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubectl := config.EkeKubectlConfig
+	if kubectl.SystemPath != "/etc/bin" {
+		t.Errorf("got systemPath %q, want /etc/bin", kubectl.SystemPath)
+	}
+	if kubectl.Timeout != 4 {
+		t.Errorf("got timeout %d, want 4", kubectl.Timeout)
+	}
+	if kubectl.Policy.Mode != "skew" {
+		t.Errorf("got policy mode %q, want skew", kubectl.Policy.Mode)
+	}
+	if len(kubectl.Pins) != 1 || kubectl.Pins[0].Version != "1.21.14" {
+		t.Errorf("got pins %v", kubectl.Pins)
+	}
+	// the guardrails of the project files are added after the ones of the
+	// user, which match first
+	var contexts []string
+	for _, g := range kubectl.Guardrails {
+		contexts = append(contexts, g.Context)
+	}
+	if !reflect.DeepEqual(contexts, []string{"prod-*", "staging-*", "*"}) || kubectl.Guardrails[0].Action != "block" {
+		t.Errorf("got guardrails %+v", kubectl.Guardrails)
+	}
+
+	loader.Environ = []string{"EKE_KUBECTL_TIMEOUT=soon"}
+	if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "EKE_KUBECTL_TIMEOUT") {
+		t.Errorf("got error %v, want an error about EKE_KUBECTL_TIMEOUT", err)
+	}
+
+	// a project file cannot change anything else
+	for _, data := range []string{
+		"ekeKubectlConfig:\n  verification:\n    requireSignature: false\n",
+		"ekeKubectlConfig:\n  mirrors:\n  - url: https://evil.example.com/kubectl\n",
+		"profiles:\n  prod:\n    ewsURL: https://evil.example.com\n",
+		"releases:\n  indexURL: https://evil.example.com/index.json\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(repo, ProjectConfigFile), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "not allowed in a project file") {
+			t.Errorf("%q: got error %v, want a project file error", data, err)
+		}
+		if _, err := loader.Layers(); err == nil {
+			t.Errorf("%q: expected an error from Layers", data)
+		}
+	}
+}
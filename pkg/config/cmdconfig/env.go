@@ -0,0 +1,141 @@
+package cmdconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the names of the environment variables overriding the
+// configuration, e.g. EKE_KUBECTL_TIMEOUT for ekeKubectlConfig.timeout
+const EnvPrefix = "EKE_"
+
+// EnvOverride is a configuration value set by an environment variable
+type EnvOverride struct {
+	// Name is the name of the environment variable
+	Name string
+	// Key is the dotted key it overrides
+	Key   string
+	Value interface{}
+}
+
+// Map returns the override as a configuration to merge
+func (o EnvOverride) Map() map[string]interface{} {
+	parts := strings.Split(o.Key, ".")
+	values := map[string]interface{}{parts[len(parts)-1]: o.Value}
+	for i := len(parts) - 2; i >= 0; i-- {
+		values = map[string]interface{}{parts[i]: values}
+	}
+	return values
+}
+
+// EnvKeys maps the names of the environment variables to the keys they
+// override. Every key of the schema outside of lists and maps has one, its
+// name is EnvPrefix followed by the upper snake case key, the parts of
+// which are separated by _. The env struct tag renames a part
+func EnvKeys() map[string]string {
+	keys := map[string]string{}
+	collectEnvKeys(configType, strings.TrimSuffix(EnvPrefix, "_"), "", keys)
+	return keys
+}
+
+func collectEnvKeys(t reflect.Type, prefix, key string, keys map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldKey := joinKey(key, field.Tag.Get("mapstructure"))
+		name := field.Tag.Get("env")
+		if name == "" {
+			name = envName(field.Tag.Get("mapstructure"))
+		}
+		name = prefix + "_" + name
+
+		keys[name] = fieldKey
+		if field.Type.Kind() == reflect.Struct {
+			collectEnvKeys(field.Type, name, fieldKey, keys)
+		}
+	}
+}
+
+// envName turns a camel case key into upper snake case, serverVersionCacheTTL
+// becomes SERVER_VERSION_CACHE_TTL
+func envName(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// EnvOverrides returns the overrides set in environ, which has the form of
+// os.Environ. Values are parsed as YAML, except for the ones of string keys,
+// and checked against the schema. Empty variables and the variables which
+// are not part of the schema are ignored. The overrides are sorted by key,
+// a mapping comes before the keys it holds
+func EnvOverrides(environ []string) ([]EnvOverride, error) {
+	keys := EnvKeys()
+
+	var overrides []EnvOverride
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || i == len(kv)-1 {
+			continue
+		}
+		name, value := kv[:i], kv[i+1:]
+		key, ok := keys[name]
+		if !ok {
+			continue
+		}
+
+		o, err := parseOverride(name, key, value)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Key < overrides[j].Key
+	})
+	return overrides, nil
+}
+
+func parseOverride(name, key, value string) (EnvOverride, error) {
+	_, t, err := resolveKey(key)
+	if err != nil {
+		return EnvOverride{}, err
+	}
+	if t.Kind() == reflect.String {
+		return EnvOverride{Name: name, Key: key, Value: value}, nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &node); err != nil {
+		return EnvOverride{}, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(node.Content) == 0 {
+		return EnvOverride{}, fmt.Errorf("%s: no value", name)
+	}
+	var errs []*ValidationError
+	validateNode(node.Content[0], t, key, &errs)
+	if len(errs) > 0 {
+		return EnvOverride{}, fmt.Errorf("%s: %s: %s", name, errs[0].Key, errs[0].Message)
+	}
+
+	var parsed interface{}
+	if err := node.Content[0].Decode(&parsed); err != nil {
+		return EnvOverride{}, fmt.Errorf("%s: %v", name, err)
+	}
+	return EnvOverride{Name: name, Key: key, Value: parsed}, nil
+}
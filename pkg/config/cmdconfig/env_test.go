@@ -0,0 +1,124 @@
+package cmdconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"timeout":               "TIMEOUT",
+		"allowDownload":         "ALLOW_DOWNLOAD",
+		"serverVersionCacheTTL": "SERVER_VERSION_CACHE_TTL",
+		"searchPATH":            "SEARCH_PATH",
+		"searchPaths":           "SEARCH_PATHS",
+		"checksumURL":           "CHECKSUM_URL",
+	}
+	for key, want := range tests {
+		if got := envName(key); got != want {
+			t.Errorf("envName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEnvKeys(t *testing.T) {
+	keys := EnvKeys()
+	for name, key := range map[string]string{
+		"EKE_KUBECTL":                "ekeKubectlConfig",
+		"EKE_KUBECTL_TIMEOUT":        "ekeKubectlConfig.timeout",
+		"EKE_KUBECTL_POLICY_MODE":    "ekeKubectlConfig.policy.mode",
+		"EKE_KUBECTL_AUDIT_MAX_SIZE": "ekeKubectlConfig.audit.maxSize",
+		"EKE_KUBECTL_SEARCH_PATHS":   "ekeKubectlConfig.searchPaths",
+		"EKE_KUBECTL_SEARCH_PATH":    "ekeKubectlConfig.searchPATH",
+		"EKE_TOOLS":                  "tools",
+		"EKE_ALIASES":                "aliases",
+	} {
+		if keys[name] != key {
+			t.Errorf("%s: got %q, want %q", name, keys[name], key)
+		}
+	}
+
+	// every key has its own variable
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			t.Errorf("%s has several variables", key)
+		}
+		seen[key] = true
+		if _, _, err := resolveKey(key); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		want    []EnvOverride
+		errMsg  string
+	}{
+		{
+			name: "parsed values",
+			environ: []string{
+				"EKE_KUBECTL_TIMEOUT=3",
+				"EKE_KUBECTL_SYSTEM_PATH=1.20",
+				"EKE_KUBECTL_SEARCH_PATHS=[/opt/bin, /usr/local/bin]",
+				"EKE_KUBECTL_PINS=[{context: prod-*, version: 1.21.14}]",
+				"EKE_KUBECTL_POLICY={mode: exact-minor}",
+			},
+			want: []EnvOverride{
+				{Name: "EKE_KUBECTL_PINS", Key: "ekeKubectlConfig.pins", Value: []interface{}{map[string]interface{}{"context": "prod-*", "version": "1.21.14"}}},
+				{Name: "EKE_KUBECTL_POLICY", Key: "ekeKubectlConfig.policy", Value: map[string]interface{}{"mode": "exact-minor"}},
+				{Name: "EKE_KUBECTL_SEARCH_PATHS", Key: "ekeKubectlConfig.searchPaths", Value: []interface{}{"/opt/bin", "/usr/local/bin"}},
+				{Name: "EKE_KUBECTL_SYSTEM_PATH", Key: "ekeKubectlConfig.systemPath", Value: "1.20"},
+				{Name: "EKE_KUBECTL_TIMEOUT", Key: "ekeKubectlConfig.timeout", Value: 3},
+			},
+		},
+		{
+			name:    "ignored variables",
+			environ: []string{"EKE_CACHE=/tmp", "EKE_KUBECTL_TIMEOUT=", "PATH=/bin", "EKE_KUBECTL_ALLOW_DOWNLOAD"},
+		},
+		{
+			name:    "invalid value",
+			environ: []string{"EKE_KUBECTL_TIMEOUT=soon"},
+			errMsg:  `EKE_KUBECTL_TIMEOUT: ekeKubectlConfig.timeout: expected an integer, got "soon"`,
+		},
+		{
+			name:    "unknown key",
+			environ: []string{"EKE_KUBECTL_POLICY={modes: skew}"},
+			errMsg:  "EKE_KUBECTL_POLICY: ekeKubectlConfig.policy.modes: unknown key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EnvOverrides(tt.environ)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("got error %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvOverrideMap(t *testing.T) {
+	o := EnvOverride{Key: "ekeKubectlConfig.policy.mode", Value: "skew"}
+	want := map[string]interface{}{
+		"ekeKubectlConfig": map[string]interface{}{
+			"policy": map[string]interface{}{"mode": "skew"},
+		},
+	}
+	if got := o.Map(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -9,8 +9,13 @@ import (
 	"eke/internal/common"
 )
 
+var (
+	systemConfigDir = "/etc/"
+	userConfigDir   = filepath.Join(common.HomeDir(), ".eke")
+)
+
 var configPaths = []string{
 	"/usr/etc/",
-	"/etc/",
-	filepath.Join(common.HomeDir(), ".eke"),
+	systemConfigDir,
+	userConfigDir,
 }
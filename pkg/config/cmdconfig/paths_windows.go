@@ -9,8 +9,13 @@ import (
 	"path/filepath"
 )
 
+var (
+	systemConfigDir = filepath.Join(os.Getenv("PROGRAMDATA"), "eke")
+	userConfigDir   = filepath.Join(common.HomeDir(), ".eke")
+)
+
 var configPaths = []string{
 	filepath.Join(os.Getenv("APPDATA"), "eke"),
-	filepath.Join(os.Getenv("PROGRAMDATA"), "eke"),
-	filepath.Join(common.HomeDir(), ".eke"),
+	systemConfigDir,
+	userConfigDir,
 }
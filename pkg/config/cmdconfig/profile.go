@@ -0,0 +1,54 @@
+package cmdconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SelectProfile selects the profile called name, or the one of the
+// configuration when name is empty, and applies its kubectl policy. It
+// returns the selected profile, nil when none is. Profile names are case
+// insensitive, Profile is lower case once selected
+func (c *EkeCmdConfig) SelectProfile(name string) (*Profile, error) {
+	if name != "" {
+		c.Profile = name
+	}
+	p, err := c.ActiveProfile()
+	if err != nil || p == nil {
+		return p, err
+	}
+	// the keys of the profiles are lower case once loaded, so are the
+	// directories of the profiles
+	c.Profile = strings.ToLower(c.Profile)
+
+	if p.Policy.Mode != "" || len(p.Policy.Allow) > 0 || len(p.Policy.Deny) > 0 {
+		c.EkeKubectlConfig.Policy = p.Policy
+	}
+	return p, nil
+}
+
+// ActiveProfile returns the profile named by Profile, nil when none is
+// selected. Profile names are case insensitive
+func (c *EkeCmdConfig) ActiveProfile() (*Profile, error) {
+	if c.Profile == "" {
+		return nil, nil
+	}
+	for name, p := range c.Profiles {
+		if strings.EqualFold(name, c.Profile) {
+			p := p
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown profile %q, the profiles are: %s", c.Profile, strings.Join(c.ProfileNames(), ", "))
+}
+
+// ProfileNames returns the sorted names of the profiles
+func (c *EkeCmdConfig) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
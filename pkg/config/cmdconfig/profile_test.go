@@ -0,0 +1,83 @@
+package cmdconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectProfile(t *testing.T) {
+	skew := KubectlPolicy{Mode: "skew"}
+	profiles := map[string]Profile{
+		"prod":    {EwsURL: "https://ews.example.com/a/"},
+		"staging": {EwsURL: "https://ews-staging.example.com/a/", Policy: KubectlPolicy{Mode: "exact-minor", Deny: []string{"1.22.0"}}},
+	}
+
+	tests := []struct {
+		name        string
+		configured  string
+		selected    string
+		wantProfile string
+		wantURL     string
+		wantPolicy  KubectlPolicy
+		errMsg      string
+	}{
+		{
+			name:       "no profile",
+			wantPolicy: skew,
+		},
+		{
+			name:        "configured profile",
+			configured:  "prod",
+			wantProfile: "prod",
+			wantURL:     "https://ews.example.com/a/",
+			wantPolicy:  skew,
+		},
+		{
+			name:        "selected profile wins and replaces the policy",
+			configured:  "prod",
+			selected:    "STAGING",
+			wantProfile: "staging",
+			wantURL:     "https://ews-staging.example.com/a/",
+			wantPolicy:  profiles["staging"].Policy,
+		},
+		{
+			name:     "unknown profile",
+			selected: "dev",
+			errMsg:   `unknown profile "dev", the profiles are: prod, staging`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := EkeCmdConfig{
+				EkeKubectlConfig: EkeKubectlConfig{Policy: skew},
+				Profile:          tt.configured,
+				Profiles:         profiles,
+			}
+			p, err := c.SelectProfile(tt.selected)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("got error %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.Profile != tt.wantProfile {
+				t.Errorf("got profile %q, want %q", c.Profile, tt.wantProfile)
+			}
+			if tt.wantURL == "" && p != nil {
+				t.Errorf("got %v, want no profile", p)
+			}
+			if tt.wantURL != "" && (p == nil || p.EwsURL != tt.wantURL) {
+				t.Errorf("got %v, want the profile of %s", p, tt.wantURL)
+			}
+			if !reflect.DeepEqual(c.EkeKubectlConfig.Policy, tt.wantPolicy) {
+				t.Errorf("got policy %v, want %v", c.EkeKubectlConfig.Policy, tt.wantPolicy)
+			}
+		})
+	}
+}
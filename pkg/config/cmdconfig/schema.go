@@ -0,0 +1,206 @@
+package cmdconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a value of an eke.cmd.yaml file that does not
+// match the EkeCmdConfig schema
+type ValidationError struct {
+	Line    int
+	Key     string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Key, e.Message)
+}
+
+var configType = reflect.TypeOf(EkeCmdConfig{})
+
+// Validate checks data, the content of an eke.cmd.yaml file, against the
+// EkeCmdConfig schema. Keys are case insensitive, like viper's
+func Validate(data []byte) ([]*ValidationError, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var errs []*ValidationError
+	validateNode(doc.Content[0], configType, "", &errs)
+	return errs, nil
+}
+
+// ProjectKeys are the keys of ekeKubectlConfig a project file may set. A
+// repository must not change where kubectl is downloaded from, where the
+// credentials are sent or what is audited, it can only pin kubectl
+// versions and add guardrails
+var ProjectKeys = []string{"pins", "policy", "guardrails"}
+
+// ValidateProject checks data, the content of a project file, like
+// Validate and rejects the keys other than the ProjectKeys of
+// ekeKubectlConfig
+func ValidateProject(data []byte) ([]*ValidationError, error) {
+	errs, err := Validate(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return errs, nil
+	}
+
+	// the unknown keys have been reported by Validate
+	notAllowed := func(node *yaml.Node, key string) {
+		if _, _, err := resolveKey(key); err != nil {
+			return
+		}
+		errs = append(errs, &ValidationError{Line: node.Line, Key: key, Message: "not allowed in a project file, only ekeKubectlConfig." + strings.Join(ProjectKeys, ", ") + " are"})
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		name, value := root.Content[i], root.Content[i+1]
+		if !strings.EqualFold(name.Value, "ekeKubectlConfig") {
+			notAllowed(name, name.Value)
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			if key := value.Content[j]; !containsFold(ProjectKeys, key.Value) {
+				notAllowed(key, joinKey(name.Value, key.Value))
+			}
+		}
+	}
+	return errs, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateNode(node *yaml.Node, t reflect.Type, key string, errs *[]*ValidationError) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, &ValidationError{Line: node.Line, Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			fail("expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, ok := structField(t, name)
+			if !ok {
+				*errs = append(*errs, &ValidationError{Line: node.Content[i].Line, Key: joinKey(key, name), Message: "unknown key"})
+				continue
+			}
+			validateNode(node.Content[i+1], field.Type, joinKey(key, name), errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			fail("expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			validateNode(node.Content[i+1], t.Elem(), joinKey(key, node.Content[i].Value), errs)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			fail("expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			validateNode(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i), errs)
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			fail("expected a %s", t.Kind())
+			return
+		}
+		if err := checkScalar(node.Value, t.Kind()); err != nil {
+			fail("%v", err)
+		}
+	}
+}
+
+func checkScalar(value string, kind reflect.Kind) error {
+	switch kind {
+	case reflect.Bool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expected a boolean, got %q", value)
+		}
+	case reflect.Int, reflect.Int64:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+	}
+	return nil
+}
+
+// structField returns the field of t decoded from the given key
+func structField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.EqualFold(field.Tag.Get("mapstructure"), key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// resolveKey returns the canonical names of the parts of the dotted key and
+// the type of its value, the key must be part of the schema
+func resolveKey(key string) ([]string, reflect.Type, error) {
+	t := configType
+	var parts []string
+	for _, part := range strings.Split(key, ".") {
+		if part == "" {
+			return nil, nil, fmt.Errorf("invalid key %q", key)
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := structField(t, part)
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown key %q", key)
+			}
+			parts = append(parts, field.Tag.Get("mapstructure"))
+			t = field.Type
+		case reflect.Map:
+			parts = append(parts, part)
+			t = t.Elem()
+		default:
+			return nil, nil, fmt.Errorf("unknown key %q, %s is not a mapping", key, strings.Join(parts, "."))
+		}
+	}
+	return parts, t, nil
+}
+
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
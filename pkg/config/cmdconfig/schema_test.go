@@ -0,0 +1,118 @@
+package cmdconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		errors []string
+	}{
+		{
+			name: "valid",
+			data: `
+ekeKubectlConfig:
+  allowDownload: false
+  timeout: 3
+  searchPaths: [/opt/bin]
+  policy:
+    mode: exact-minor
+aliases:
+  podsof: get pods -l app=$1
+`,
+		},
+		{
+			name: "keys are case insensitive",
+			data: `
+EKEKUBECTLCONFIG:
+  TIMEOUT: 3
+`,
+		},
+		{
+			name:   "unknown key",
+			data:   "ekeKubectlConfig:\n  timeouts: 3\n",
+			errors: []string{"line 2: ekeKubectlConfig.timeouts: unknown key"},
+		},
+		{
+			name:   "invalid int",
+			data:   "ekeKubectlConfig:\n  timeout: soon\n",
+			errors: []string{"line 2: ekeKubectlConfig.timeout: "},
+		},
+		{
+			name:   "invalid bool",
+			data:   "ekeKubectlConfig:\n  allowDownload: sometimes\n",
+			errors: []string{"line 2: ekeKubectlConfig.allowDownload: "},
+		},
+		{
+			name:   "list expected",
+			data:   "ekeKubectlConfig:\n  searchPaths: /opt/bin\n",
+			errors: []string{"line 2: ekeKubectlConfig.searchPaths: expected a list"},
+		},
+		{
+			name:   "mapping expected",
+			data:   "ekeKubectlConfig: true\naliases: [a]\n",
+			errors: []string{"ekeKubectlConfig: expected a mapping", "aliases: expected a mapping"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := Validate([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errs) != len(tt.errors) {
+				t.Fatalf("got %v, want %d errors", errs, len(tt.errors))
+			}
+			for i, e := range errs {
+				if !strings.Contains(e.Error(), tt.errors[i]) {
+					t.Errorf("got %q, want %q", e.Error(), tt.errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateInvalidYAML(t *testing.T) {
+	if _, err := Validate([]byte("a: [")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestValidateProject(t *testing.T) {
+	data := `
+ekeKubectlConfig:
+  pins:
+  - context: prod-*
+    version: 1.21.14
+  policy:
+    mode: skew
+  GUARDRAILS:
+  - verbs: [delete]
+  mirrors:
+  - url: https://evil.example.com/kubectl
+  timeouts: 3
+audit:
+  disabled: true
+`
+	errs, err := ValidateProject([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"line 12: ekeKubectlConfig.timeouts: unknown key",
+		"line 13: audit: unknown key",
+		"line 10: ekeKubectlConfig.mirrors: not allowed in a project file",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %v, want %d errors", errs, len(want))
+	}
+	for i, e := range errs {
+		if !strings.Contains(e.Error(), want[i]) {
+			t.Errorf("got %q, want %q", e.Error(), want[i])
+		}
+	}
+}
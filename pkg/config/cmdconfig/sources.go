@@ -0,0 +1,176 @@
+package cmdconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSource names the layer of the embedded default configuration
+const DefaultSource = "default"
+
+// Layer is one of the configurations merged by the ConfigLoader
+type Layer struct {
+	// Source is the path of the file, or DefaultSource
+	Source string
+	Data   map[string]interface{}
+}
+
+// Setting is a value of the merged configuration along with the layer it
+// comes from
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// Layers returns the default configuration followed by the files and the
+// environment variables, in the order they are merged. The source of an
+// environment variable is its name prefixed with $
+func (c *ConfigLoader) Layers() ([]Layer, error) {
+	data, err := df.ReadFile(DEFAULT_CONFIG)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := parseLayer(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", DEFAULT_CONFIG, err)
+	}
+	layers := []Layer{{Source: DefaultSource, Data: defaults}}
+
+	files, err := c.userFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, cfgFile := range files {
+		data, err := ioutil.ReadFile(cfgFile)
+		if err != nil {
+			return nil, err
+		}
+		values, err := parseLayer(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cfgFile, err)
+		}
+		layers = append(layers, Layer{Source: cfgFile, Data: values})
+	}
+
+	projectFiles, err := c.projectFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, cfgFile := range projectFiles {
+		merged, _ := Merge(layers)
+		values, err := readProjectFile(cfgFile, mergedGuardrails(merged))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, Layer{Source: cfgFile, Data: values})
+	}
+
+	overrides, err := EnvOverrides(c.Environ)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		layers = append(layers, Layer{Source: "$" + o.Name, Data: o.Map()})
+	}
+	return layers, nil
+}
+
+// mergedGuardrails returns ekeKubectlConfig.guardrails of the merged
+// configuration
+func mergedGuardrails(merged map[string]interface{}) []interface{} {
+	for name, value := range merged {
+		kubectl, ok := value.(map[string]interface{})
+		if !ok || !strings.EqualFold(name, "ekeKubectlConfig") {
+			continue
+		}
+		for key, value := range kubectl {
+			if guardrails, ok := value.([]interface{}); ok && strings.EqualFold(key, "guardrails") {
+				return guardrails
+			}
+		}
+	}
+	return nil
+}
+
+func parseLayer(data []byte) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Merge merges the layers like the ConfigLoader does: mappings are merged
+// with case insensitive keys, other values are replaced. It returns the
+// merged configuration along with the origin of its values, sorted by key
+func Merge(layers []Layer) (map[string]interface{}, []Setting) {
+	merged := map[string]interface{}{}
+	sources := map[string]string{}
+	for _, l := range layers {
+		mergeInto(merged, l.Data, "", l.Source, sources)
+	}
+
+	var settings []Setting
+	collectSettings(merged, "", sources, &settings)
+	sort.Slice(settings, func(i, j int) bool {
+		return strings.ToLower(settings[i].Key) < strings.ToLower(settings[j].Key)
+	})
+	return merged, settings
+}
+
+func mergeInto(dst, src map[string]interface{}, prefix, source string, sources map[string]string) {
+	for key, value := range src {
+		// keep the name the key has been given first
+		name := key
+		for existing := range dst {
+			if strings.EqualFold(existing, key) {
+				name = existing
+			}
+		}
+		full := strings.ToLower(joinKey(prefix, name))
+
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[name].(map[string]interface{})
+		switch {
+		case srcIsMap && dstIsMap:
+			mergeInto(dstMap, srcMap, joinKey(prefix, name), source, sources)
+		case srcIsMap:
+			// replace a value with an empty mapping to merge into
+			for k := range sources {
+				if strings.HasPrefix(k, full+".") || k == full {
+					delete(sources, k)
+				}
+			}
+			dstMap = map[string]interface{}{}
+			dst[name] = dstMap
+			mergeInto(dstMap, srcMap, joinKey(prefix, name), source, sources)
+			if len(srcMap) == 0 {
+				sources[full] = source
+			}
+		default:
+			for k := range sources {
+				if strings.HasPrefix(k, full+".") {
+					delete(sources, k)
+				}
+			}
+			dst[name] = value
+			sources[full] = source
+		}
+	}
+}
+
+func collectSettings(values map[string]interface{}, prefix string, sources map[string]string, settings *[]Setting) {
+	for key, value := range values {
+		full := joinKey(prefix, key)
+		if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+			collectSettings(m, full, sources, settings)
+			continue
+		}
+		*settings = append(*settings, Setting{Key: full, Value: value, Source: sources[strings.ToLower(full)]})
+	}
+}
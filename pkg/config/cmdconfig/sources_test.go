@@ -0,0 +1,125 @@
+package cmdconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLayers(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown(td)
+
+	if err := writeConfig(td.FakeEtc, "ekeKubectlConfig:\n  timeout: 3\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(td.FakeHome, "ekeKubectlConfig:\n  TIMEOUT: 4\n  policy:\n    allow: [\">=1.20.0\"]\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := ConfigLoader{
+		Paths:   []string{td.FakeUsrEtc, td.FakeEtc, td.FakeHome},
+		Environ: []string{"EKE_KUBECTL_POLICY_MODE=exact-minor"},
+	}
+	layers, err := loader.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sources []string
+	for _, l := range layers {
+		sources = append(sources, l.Source)
+	}
+	want := []string{DefaultSource, filepath.Join(td.FakeEtc, "eke.cmd.yaml"), filepath.Join(td.FakeHome, "eke.cmd.yaml"), "$EKE_KUBECTL_POLICY_MODE"}
+	if !reflect.DeepEqual(sources, want) {
+		t.Fatalf("got sources %v, want %v", sources, want)
+	}
+
+	_, settings := Merge(layers)
+	origin := map[string]Setting{}
+	for _, s := range settings {
+		origin[s.Key] = s
+	}
+	for key, source := range map[string]string{
+		"ekeKubectlConfig.timeout":       want[2],
+		"ekeKubectlConfig.policy.mode":   want[3],
+		"ekeKubectlConfig.policy.allow":  want[2],
+		"ekeKubectlConfig.allowDownload": DefaultSource,
+	} {
+		if origin[key].Source != source {
+			t.Errorf("%s: got source %q, want %q", key, origin[key].Source, source)
+		}
+	}
+	if origin["ekeKubectlConfig.timeout"].Value != 4 {
+		t.Errorf("got timeout %v, want 4", origin["ekeKubectlConfig.timeout"].Value)
+	}
+}
+
+func TestProjectLayers(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown(td)
+
+	if err := writeConfig(td.FakeHome, "ekeKubectlConfig:\n  guardrails:\n  - context: prod-*\n"); err != nil {
+		t.Fatal(err)
+	}
+	repo := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(repo, ProjectConfigFile), []byte("ekeKubectlConfig:\n  guardrails:\n  - context: staging-*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := ConfigLoader{Paths: []string{td.FakeHome}, ProjectDir: repo}
+	layers, err := loader.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, _ := Merge(layers)
+	want := []interface{}{
+		map[string]interface{}{"context": "prod-*"},
+		map[string]interface{}{"context": "staging-*"},
+	}
+	if got := mergedGuardrails(merged); !reflect.DeepEqual(got, want) {
+		t.Errorf("got guardrails %v, want %v", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	layers := []Layer{
+		{Source: "a", Data: map[string]interface{}{
+			"list": []interface{}{1, 2},
+			"map":  map[string]interface{}{"x": 1, "y": 2},
+			"old":  map[string]interface{}{"x": 1},
+		}},
+		{Source: "b", Data: map[string]interface{}{
+			"list": []interface{}{3},
+			"MAP":  map[string]interface{}{"Y": 3},
+			"old":  "replaced",
+		}},
+	}
+
+	merged, settings := Merge(layers)
+	want := map[string]interface{}{
+		"list": []interface{}{3},
+		"map":  map[string]interface{}{"x": 1, "y": 3},
+		"old":  "replaced",
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("got %v, want %v", merged, want)
+	}
+
+	wantSettings := []Setting{
+		{Key: "list", Value: []interface{}{3}, Source: "b"},
+		{Key: "map.x", Value: 1, Source: "a"},
+		{Key: "map.y", Value: 3, Source: "b"},
+		{Key: "old", Value: "replaced", Source: "b"},
+	}
+	if !reflect.DeepEqual(settings, wantSettings) {
+		t.Errorf("got %v, want %v", settings, wantSettings)
+	}
+}
@@ -2,11 +2,223 @@ package cmdconfig
 
 // check how cluster config build
 type EkeCmdConfig struct {
-	EkeKubectlConfig EkeKubectlConfig `mapstructure:"ekeKubectlConfig"`
+	// EkeKubectlConfig is overridden by the EKE_KUBECTL_ environment
+	// variables
+	EkeKubectlConfig EkeKubectlConfig `mapstructure:"ekeKubectlConfig" json:"ekeKubectlConfig" env:"KUBECTL"`
+	// Tools describe the cluster tools, other than kubectl, whose version
+	// has to match the one of the cluster
+	Tools []ToolDescriptor `mapstructure:"tools" json:"tools"`
+	// Aliases map names to the kubectl arguments they stand for, see
+	// eke kubectl aliases
+	Aliases map[string]string `mapstructure:"aliases" json:"aliases"`
+	// Profile is the name of the selected profile, none when empty
+	Profile string `mapstructure:"profile" json:"profile"`
+	// Profiles hold the settings of the EWS instances, by name
+	Profiles map[string]Profile `mapstructure:"profiles" json:"profiles"`
+	// Logging configures the messages of the commands, the --log- flags
+	// take precedence
+	Logging Logging `mapstructure:"logging" json:"logging"`
+	// Tracing exports the steps of the commands as OpenTelemetry traces
+	Tracing Tracing `mapstructure:"tracing" json:"tracing"`
+	// Releases locates the builds of eke, for eke version --check and eke
+	// self-update
+	Releases Releases `mapstructure:"releases" json:"releases"`
 }
 
 type EkeKubectlConfig struct {
-	AllowDownload bool   `mapstructure:"allowDownload"`
-	SystemPath    string `mapstructure:"systemPath"`
-	Timeout       int    `mapstructure:"timeout"`
+	AllowDownload bool            `mapstructure:"allowDownload" json:"allowDownload"`
+	SystemPath    string          `mapstructure:"systemPath" json:"systemPath"`
+	Timeout       int             `mapstructure:"timeout" json:"timeout"`
+	Mirrors       []KubectlMirror `mapstructure:"mirrors" json:"mirrors"`
+	// ServerVersionCacheTTL is the number of seconds the last seen version
+	// of an API server is used without asking the server again
+	ServerVersionCacheTTL int `mapstructure:"serverVersionCacheTTL" json:"serverVersionCacheTTL"`
+	// Pins force a kubectl version for some contexts or servers, the
+	// first matching pin wins
+	Pins []KubectlPin `mapstructure:"pins" json:"pins"`
+	// Policy drives which kubectl binaries are compatible with a server
+	Policy KubectlPolicy `mapstructure:"policy" json:"policy"`
+	// SearchPaths are directories searched for kubectl binaries, on top
+	// of SystemPath
+	SearchPaths []string `mapstructure:"searchPaths" json:"searchPaths"`
+	// SearchPATH enables the search of kubectl binaries in the directories
+	// listed by the PATH environment variable
+	SearchPATH bool `mapstructure:"searchPATH" json:"searchPATH"`
+	// Verification configures the checks of the downloaded binaries
+	Verification KubectlVerification `mapstructure:"verification" json:"verification"`
+	// Guardrails protect some clusters from destructive commands, the
+	// first matching guardrail applies
+	Guardrails []KubectlGuardrail `mapstructure:"guardrails" json:"guardrails"`
+	// Audit configures the log of the commands run through the wrapper
+	Audit KubectlAudit `mapstructure:"audit" json:"audit"`
+}
+
+// KubectlMirror describes a location kubectl binaries can be downloaded from.
+// URL, ChecksumURL and StableURL are go templates which are rendered with
+// {{.Version}}, {{.OS}}, {{.Arch}} and {{.Ext}}; ChecksumURL can also refer
+// to the rendered binary location with {{.URL}}. Supported schemes are
+// http, https and file.
+// ManifestURL points to a checksum manifest signed with one of the keys of
+// KubectlVerification, its signature is read from SignatureURL, which
+// defaults to the manifest location followed by ".sig". Both are templates
+// as well, SignatureURL can refer to the manifest location with {{.URL}}.
+// The signed manifest takes precedence over ChecksumURL.
+type KubectlMirror struct {
+	URL          string `mapstructure:"url" json:"url"`
+	ChecksumURL  string `mapstructure:"checksumURL" json:"checksumURL"`
+	StableURL    string `mapstructure:"stableURL" json:"stableURL"`
+	ManifestURL  string `mapstructure:"manifestURL" json:"manifestURL"`
+	SignatureURL string `mapstructure:"signatureURL" json:"signatureURL"`
+	Username     string `mapstructure:"username" json:"username"`
+	Password     string `mapstructure:"password" json:"password"`
+	Token        string `mapstructure:"token" json:"token"`
+}
+
+// KubectlPin forces the kubectl version used against the clusters matching
+// Context and Server, which are glob patterns matched against the context
+// name and the API server URL. Server patterns without a scheme are matched
+// against the host of the URL, with or without its port, and * matches / in
+// the other ones. When both are set both must match. Version is
+// either an exact version (1.21.14) or a semver range (">=1.21.0 <1.22.0",
+// "1.21.x").
+type KubectlPin struct {
+	Context string `mapstructure:"context" json:"context"`
+	Server  string `mapstructure:"server" json:"server"`
+	Version string `mapstructure:"version" json:"version"`
+}
+
+// KubectlPolicy selects the kubectl binaries compatible with a server.
+// Mode is one of skew, exact-minor, exact-patch, newest-compatible or
+// oldest-compatible. Allow and Deny hold exact versions or semver ranges,
+// a binary must match one of the Allow entries, when any, and none of the
+// Deny ones.
+type KubectlPolicy struct {
+	Mode  string   `mapstructure:"mode" json:"mode"`
+	Allow []string `mapstructure:"allow" json:"allow"`
+	Deny  []string `mapstructure:"deny" json:"deny"`
+}
+
+// KubectlVerification holds the public keys trusted to sign the checksum
+// manifests of the mirrors. PublicKeys are PEM encoded ed25519 or ECDSA
+// keys, or base64 encoded raw ed25519 keys. When RequireSignature is set
+// the binaries of mirrors without a signed manifest are refused.
+type KubectlVerification struct {
+	PublicKeys       []string `mapstructure:"publicKeys" json:"publicKeys"`
+	RequireSignature bool     `mapstructure:"requireSignature" json:"requireSignature"`
+}
+
+// ToolDescriptor describes how to find, download and pick the binaries of a
+// cluster tool like helm or kustomize. Binary is the name of the executable,
+// it defaults to Name. NamingScheme names the downloaded binaries after
+// their major, minor and patch versions, it defaults to Binary followed by
+// "%d.%d.%d". Mirrors work like the kubectl ones; when ArchivePath is set
+// they point to tar.gz archives and ArchivePath, a template rendered like
+// the mirror urls, locates the binary inside of them. VersionArgs make the
+// binary print its version, the first semantic version printed is used.
+// ContextFlag is the flag of the tool selecting the kubeconfig context,
+// when it differs from kubectl's --context. Compatibility maps the versions
+// of the API server to the versions of the tool, the first matching rule
+// wins; without rules any version of the tool is compatible.
+type ToolDescriptor struct {
+	Name          string                  `mapstructure:"name" json:"name"`
+	Binary        string                  `mapstructure:"binary" json:"binary"`
+	NamingScheme  string                  `mapstructure:"namingScheme" json:"namingScheme"`
+	Mirrors       []KubectlMirror         `mapstructure:"mirrors" json:"mirrors"`
+	ArchivePath   string                  `mapstructure:"archivePath" json:"archivePath"`
+	VersionArgs   []string                `mapstructure:"versionArgs" json:"versionArgs"`
+	ContextFlag   string                  `mapstructure:"contextFlag" json:"contextFlag"`
+	Compatibility []ToolCompatibilityRule `mapstructure:"compatibility" json:"compatibility"`
+}
+
+// ToolCompatibilityRule states that the tool versions matching Version
+// work with the API servers matching Server, both are exact versions or
+// semver ranges. Download is the version fetched when no available binary
+// matches, the rule never downloads anything without it.
+type ToolCompatibilityRule struct {
+	Server   string `mapstructure:"server" json:"server"`
+	Version  string `mapstructure:"version" json:"version"`
+	Download string `mapstructure:"download" json:"download"`
+}
+
+// KubectlGuardrail protects the clusters matching Context, Server and
+// Namespace, which are glob patterns matched against the context name, the
+// API server URL and the namespace the command runs in. Empty patterns
+// match everything. Verbs are the kubectl commands to protect, optionally
+// followed by the flags making them destructive, e.g. "scale --replicas=0"
+// or "apply --prune"; delete, drain, "scale --replicas=0" and
+// "apply --prune" are protected when empty. Action is one of confirm, the
+// default, which asks to type the context name, or "confirm" when the
+// context and the server have no name, block, or flag, which requires the
+// --i-know flag.
+type KubectlGuardrail struct {
+	Context   string   `mapstructure:"context" json:"context"`
+	Server    string   `mapstructure:"server" json:"server"`
+	Namespace string   `mapstructure:"namespace" json:"namespace"`
+	Verbs     []string `mapstructure:"verbs" json:"verbs"`
+	Action    string   `mapstructure:"action" json:"action"`
+}
+
+// KubectlAudit configures the local log of the kubectl commands, which is
+// kept unless Disabled is set. MaxSize is the size, in MiB, above which the
+// log is rotated and MaxFiles the number of rotated files kept, both have
+// defaults when zero. The values of the flags matching one of the Redact
+// glob patterns, e.g. "--token" or "--*-password", are removed on top of
+// the built in ones.
+type KubectlAudit struct {
+	Disabled bool     `mapstructure:"disabled" json:"disabled"`
+	MaxSize  int      `mapstructure:"maxSize" json:"maxSize"`
+	MaxFiles int      `mapstructure:"maxFiles" json:"maxFiles"`
+	Redact   []string `mapstructure:"redact" json:"redact"`
+}
+
+// Profile bundles the settings of an EWS instance. EwsURL is the base URL of
+// its API and CA the path to the PEM certificates trusted to reach it and
+// written in the kubeconfig files as the authority of the API servers. User
+// is the default user id, Kubeconfig the default path of the kubeconfig
+// file. Policy, when set, replaces the kubectl policy of ekeKubectlConfig.
+// The credentials and the cluster catalog of a profile are kept apart from
+// the ones of the other profiles.
+type Profile struct {
+	EwsURL     string        `mapstructure:"ewsURL" json:"ewsURL"`
+	CA         string        `mapstructure:"ca" json:"ca"`
+	User       string        `mapstructure:"user" json:"user"`
+	Kubeconfig string        `mapstructure:"kubeconfig" json:"kubeconfig"`
+	Policy     KubectlPolicy `mapstructure:"policy" json:"policy"`
+}
+
+// Logging configures the messages of the eke commands. Format is text or
+// json. File receives the messages instead of stderr, it is rotated once
+// bigger than MaxSize MiB and MaxFiles rotated files are kept. Level is the
+// level of all the subsystems, Levels the ones of the ews, kubectl and
+// config subsystems.
+type Logging struct {
+	Format   string            `mapstructure:"format" json:"format"`
+	File     string            `mapstructure:"file" json:"file"`
+	MaxSize  int               `mapstructure:"maxSize" json:"maxSize"`
+	MaxFiles int               `mapstructure:"maxFiles" json:"maxFiles"`
+	Level    string            `mapstructure:"level" json:"level"`
+	Levels   map[string]string `mapstructure:"levels" json:"levels"`
+}
+
+// Tracing exports the steps of the eke commands, the EWS requests, the
+// endpoint resolution, the server version discovery, the kubectl downloads
+// and lookups, as OpenTelemetry traces. Exporter is otlp, sending them to
+// the OTLP/HTTP collector at Endpoint, or file, appending them as JSON to
+// File. Tracing is disabled when Exporter is empty.
+type Tracing struct {
+	Exporter string            `mapstructure:"exporter" json:"exporter"`
+	Endpoint string            `mapstructure:"endpoint" json:"endpoint"`
+	Insecure bool              `mapstructure:"insecure" json:"insecure"`
+	Headers  map[string]string `mapstructure:"headers" json:"headers"`
+	File     string            `mapstructure:"file" json:"file"`
+}
+
+// Releases locates the builds of eke. IndexURL points to the release index,
+// a JSON document listing the versions of eke along with the URLs of their
+// builds and of their checksum manifest. PublicKeys are trusted to sign the
+// manifests, like the ones of KubectlVerification; eke self-update refuses
+// the builds whose manifest is not signed with one of them.
+type Releases struct {
+	IndexURL   string   `mapstructure:"indexURL" json:"indexURL"`
+	PublicKeys []string `mapstructure:"publicKeys" json:"publicKeys"`
 }